@@ -0,0 +1,224 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/weegigs/wee-events-go/migrate"
+	"github.com/weegigs/wee-events-go/stores/file"
+	"github.com/weegigs/wee-events-go/we"
+)
+
+func openStore(t *testing.T) *file.FileEventStore {
+	store, err := file.NewEventStore(t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = store.Close() })
+
+	return store
+}
+
+// listingStore lists the aggregates it was given
+type listingStore struct {
+	*file.FileEventStore
+	ids []we.AggregateId
+}
+
+func (s listingStore) ListAggregates(ctx context.Context, _ we.Checkpoint, handler we.AggregateListHandler) error {
+	for _, id := range s.ids {
+		if err := handler(ctx, id, we.NoCheckpoint); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// typeListingStore lists the aggregates of a type without listing every aggregate
+type typeListingStore struct {
+	listingStore
+}
+
+func (s typeListingStore) ListAggregates(context.Context, we.Checkpoint, we.AggregateListHandler) error {
+	return errors.New("every aggregate was listed")
+}
+
+func (s typeListingStore) ListAggregatesOfType(ctx context.Context, aggregateType string, _ we.Checkpoint, handler we.AggregateListHandler) error {
+	for _, id := range s.ids {
+		if id.Type != aggregateType {
+			continue
+		}
+
+		if err := handler(ctx, id, we.NoCheckpoint); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadOnly hides the store's ability to append recorded events
+type loadOnly struct {
+	we.EventStore
+}
+
+type Placed struct {
+	Value string `json:"value"`
+}
+
+func (Placed) EventType() we.EventType {
+	return "test:placed"
+}
+
+func recordedEvents(t *testing.T, count int) []we.RecordedEvent {
+	events := make([]we.RecordedEvent, count)
+	for i := range events {
+		data, err := we.MarshalToData(Placed{Value: ulid.Make().String()})
+		require.NoError(t, err)
+
+		events[i] = we.RecordedEvent{
+			EventID:   we.EventID(ulid.Make().String()),
+			EventType: we.EventTypeOf(Placed{}),
+			Timestamp: we.TimestampFromTime(time.Now().AddDate(-1, 0, i)),
+			Metadata:  we.RecordedEventMetadata{CorrelationId: we.CorrelationID(ulid.Make().String())},
+			Data:      data,
+		}
+	}
+
+	return events
+}
+
+func seed(t *testing.T, store *file.FileEventStore, aggregateType string, count int) []we.AggregateId {
+	ids := make([]we.AggregateId, count)
+	for i := range ids {
+		ids[i] = we.AggregateId{Type: aggregateType, Key: ulid.Make().String()}
+		_, err := store.Append(context.Background(), ids[i], we.InitialRevision, recordedEvents(t, i+1))
+		require.NoError(t, err)
+	}
+
+	return ids
+}
+
+func assertImported(t *testing.T, source we.EventStore, target we.EventStore, ids []we.AggregateId) {
+	for _, id := range ids {
+		from, err := source.Load(context.Background(), id)
+		require.NoError(t, err)
+		to, err := target.Load(context.Background(), id)
+		require.NoError(t, err)
+
+		assert.Equal(t, migrate.Hash(from.Events), migrate.Hash(to.Events), id.Encode())
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	source := openStore(t)
+	ids := seed(t, source, "order", 3)
+
+	for name, options := range map[string][]WriterOption{"plain": nil, "compressed": {WithCompression()}} {
+		t.Run(name, func(t *testing.T) {
+			var archive bytes.Buffer
+			manifest, err := Export(ctx, source, &archive, ids, options...)
+			require.NoError(t, err)
+			assert.Equal(t, 3, manifest.Aggregates)
+			assert.Equal(t, 6, manifest.Events)
+
+			target := openStore(t)
+			report, err := Import(ctx, target, bytes.NewReader(archive.Bytes()))
+			require.NoError(t, err)
+			assert.Equal(t, Report{Aggregates: 3, Events: 6, Appended: 6}, report)
+			assertImported(t, source, target, ids)
+
+			report, err = Import(ctx, target, bytes.NewReader(archive.Bytes()))
+			require.NoError(t, err)
+			assert.Equal(t, 0, report.Appended, "imports are idempotent")
+		})
+	}
+}
+
+func TestExportType(t *testing.T) {
+	ctx := context.Background()
+	source := openStore(t)
+	orders := seed(t, source, "order", 2)
+	customers := seed(t, source, "customer", 2)
+
+	var archive bytes.Buffer
+	manifest, err := ExportType(ctx, listingStore{source, append(orders, customers...)}, &archive, "order")
+	require.NoError(t, err)
+	assert.Equal(t, 2, manifest.Aggregates)
+
+	target := openStore(t)
+	_, err = Import(ctx, target, &archive)
+	require.NoError(t, err)
+	assertImported(t, source, target, orders)
+
+	aggregate, err := target.Load(ctx, customers[0])
+	require.NoError(t, err)
+	assert.Empty(t, aggregate.Events)
+
+	_, err = ExportType(ctx, loadOnly{source}, io.Discard, "order")
+	assert.Error(t, err, "stores must be able to list aggregates")
+
+	manifest, err = ExportType(ctx, typeListingStore{listingStore{source, append(orders, customers...)}}, io.Discard, "customer")
+	require.NoError(t, err, "stores that list by type only list the type")
+	assert.Equal(t, 2, manifest.Aggregates)
+}
+
+func TestVerification(t *testing.T) {
+	ctx := context.Background()
+	source := openStore(t)
+	ids := seed(t, source, "order", 2)
+
+	var archive bytes.Buffer
+	_, err := Export(ctx, source, &archive, ids)
+	require.NoError(t, err)
+	lines := strings.SplitAfter(archive.String(), "\n")
+
+	t.Run("intact archives verify", func(t *testing.T) {
+		manifest, err := Verify(strings.NewReader(archive.String()))
+		require.NoError(t, err)
+		assert.Equal(t, 3, manifest.Events)
+	})
+
+	t.Run("detects changed events", func(t *testing.T) {
+		changed := strings.Replace(archive.String(), ids[0].Key, ulid.Make().String(), 1)
+		_, err := Verify(strings.NewReader(changed))
+		assert.Error(t, err)
+	})
+
+	t.Run("detects changed data", func(t *testing.T) {
+		changed := append([]string(nil), lines...)
+		changed[1] = strings.Replace(changed[1], `"encoding"`, ` "encoding"`, 1)
+		_, err := Verify(strings.NewReader(strings.Join(changed, "")))
+		assert.True(t, errors.Is(err, ChecksumMismatch), err)
+	})
+
+	t.Run("detects truncation", func(t *testing.T) {
+		_, err := Verify(strings.NewReader(strings.Join(lines[:len(lines)-2], "")))
+		assert.True(t, errors.Is(err, Truncated), err)
+	})
+
+	t.Run("rejects newer versions", func(t *testing.T) {
+		newer := strings.Replace(archive.String(), `"version":1`, `"version":2`, 1)
+		_, err := Verify(strings.NewReader(newer))
+		assert.True(t, errors.Is(err, UnsupportedVersion), err)
+	})
+
+	t.Run("requires a header", func(t *testing.T) {
+		_, err := Verify(strings.NewReader(strings.Join(lines[1:], "")))
+		assert.True(t, errors.Is(err, Malformed), err)
+	})
+
+	t.Run("requires stores that append", func(t *testing.T) {
+		_, err := Import(ctx, loadOnly{openStore(t)}, strings.NewReader(archive.String()))
+		assert.True(t, errors.Is(err, AppendUnsupported), err)
+	})
+}
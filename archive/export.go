@@ -0,0 +1,75 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/weegigs/wee-events-go/we"
+)
+
+// Export writes an archive of the aggregates, returning its manifest. Aggregates without events are skipped.
+func Export(ctx context.Context, store we.EventStore, out io.Writer, ids []we.AggregateId, options ...WriterOption) (Manifest, error) {
+	writer, err := NewWriter(out, options...)
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	for _, id := range ids {
+		if err := ExportAggregate(ctx, store, writer, id); err != nil {
+			return Manifest{}, err
+		}
+	}
+
+	return writer.Close()
+}
+
+// ExportType writes an archive of every aggregate of the type, or every aggregate when the type is empty, returning
+// its manifest. The store must be able to list its aggregates. Stores that are a we.TypeLister only list the
+// aggregates of the type. Hard deleted aggregates are skipped.
+func ExportType(ctx context.Context, store we.EventStore, out io.Writer, aggregateType string, options ...WriterOption) (Manifest, error) {
+	lister, ok := store.(we.AggregateLister)
+	if !ok {
+		return Manifest{}, errors.New("the store can't list aggregates")
+	}
+
+	writer, err := NewWriter(out, options...)
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	export := func(ctx context.Context, id we.AggregateId, _ we.Checkpoint) error {
+		if aggregateType != "" && id.Type != aggregateType {
+			return nil
+		}
+
+		err := ExportAggregate(ctx, store, writer, id)
+		if errors.Is(err, we.AggregateDeleted) {
+			return nil
+		}
+
+		return err
+	}
+
+	if typed, ok := lister.(we.TypeLister); ok && aggregateType != "" {
+		err = typed.ListAggregatesOfType(ctx, aggregateType, we.NoCheckpoint, export)
+	} else {
+		err = lister.ListAggregates(ctx, we.NoCheckpoint, export)
+	}
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	return writer.Close()
+}
+
+// ExportAggregate loads the aggregate and writes it to the archive.
+func ExportAggregate(ctx context.Context, store we.EventStore, writer *Writer, id we.AggregateId) error {
+	aggregate, err := store.Load(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %w", id.Encode(), err)
+	}
+
+	return writer.WriteAggregate(aggregate)
+}
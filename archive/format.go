@@ -0,0 +1,72 @@
+// Package archive reads and writes portable, store independent archives of aggregates.
+//
+// An archive is newline delimited JSON, optionally gzip compressed. The first line is the header, followed by the
+// events of each aggregate as we.RecordedEvent, each aggregate's events closed by a summary line, and finally the
+// manifest:
+//
+//	{"archive":{"format":"we-archive","version":1,"created":"..."}}
+//	{"aggregate":{"type":"order","key":"1"},"revision":"...","id":"...","type":"order:placed",...}
+//	{"summary":{"aggregate":{"type":"order","key":"1"},"events":1,"revision":"...","checksum":"sha256:..."}}
+//	{"manifest":{"aggregates":1,"events":1,"checksum":"sha256:..."}}
+//
+// A summary's checksum covers the lines of its aggregate's events and the manifest's checksum covers every line before
+// it, so an archive can be verified as it is read and a truncated archive is detected.
+package archive
+
+import (
+	"encoding/hex"
+	"errors"
+	"hash"
+
+	"github.com/weegigs/wee-events-go/we"
+)
+
+const (
+	Format  = "we-archive"
+	Version = 1
+)
+
+var (
+	// Malformed is returned when an archive isn't laid out as described by the format.
+	Malformed = errors.New("malformed-archive")
+	// UnsupportedVersion is returned when an archive was written by a newer version of the format.
+	UnsupportedVersion = errors.New("unsupported-archive-version")
+	// ChecksumMismatch is returned when an aggregate's events or the archive don't match their recorded checksums.
+	ChecksumMismatch = errors.New("archive-checksum-mismatch")
+	// Truncated is returned when an archive ends before its manifest.
+	Truncated = errors.New("archive-truncated")
+)
+
+// Header identifies the format of an archive and when it was created.
+type Header struct {
+	Format  string       `json:"format"`
+	Version int          `json:"version"`
+	Created we.Timestamp `json:"created"`
+}
+
+// Summary closes the events of an aggregate.
+type Summary struct {
+	Aggregate we.AggregateId `json:"aggregate"`
+	Events    int            `json:"events"`
+	// Revision is the revision of the aggregate in the store it was exported from
+	Revision we.Revision `json:"revision"`
+	Checksum string      `json:"checksum"`
+}
+
+// Manifest closes the archive.
+type Manifest struct {
+	Aggregates int    `json:"aggregates"`
+	Events     int    `json:"events"`
+	Checksum   string `json:"checksum"`
+}
+
+// line holds the lines that aren't events, which have none of these fields.
+type line struct {
+	Archive  *Header   `json:"archive,omitempty"`
+	Summary  *Summary  `json:"summary,omitempty"`
+	Manifest *Manifest `json:"manifest,omitempty"`
+}
+
+func checksum(digest hash.Hash) string {
+	return "sha256:" + hex.EncodeToString(digest.Sum(nil))
+}
@@ -0,0 +1,87 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/weegigs/wee-events-go/migrate"
+	"github.com/weegigs/wee-events-go/we"
+)
+
+// AppendUnsupported is returned when importing into a store that can't append recorded events.
+var AppendUnsupported = errors.New("store-append-unsupported")
+
+// Report summarises an import.
+type Report struct {
+	Aggregates int
+	Events     int
+	// Appended counts the events that weren't already in the store
+	Appended int
+}
+
+func (r Report) String() string {
+	return fmt.Sprintf("%d aggregates, %d events, %d appended", r.Aggregates, r.Events, r.Appended)
+}
+
+// Import appends the aggregates in the archive to the store, keeping their event ids, types, timestamps and metadata.
+// Each aggregate is verified before it is appended. Events already in the store are skipped, so an interrupted import
+// can be repeated, but an aggregate whose events in the store aren't a prefix of the archived events fails with
+// migrate.Diverged.
+func Import(ctx context.Context, store we.EventStore, in io.Reader) (Report, error) {
+	var report Report
+
+	appender, ok := store.(we.EventAppender)
+	if !ok {
+		return report, AppendUnsupported
+	}
+
+	target := struct {
+		we.EventStore
+		we.EventAppender
+	}{store, appender}
+
+	reader, err := NewReader(in)
+	if err != nil {
+		return report, err
+	}
+
+	for {
+		aggregate, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return report, nil
+		}
+		if err != nil {
+			return report, err
+		}
+
+		appended, err := migrate.AppendMissing(ctx, target, aggregate.Id, aggregate.Events)
+		if err != nil {
+			return report, fmt.Errorf("failed to import %s: %w", aggregate.Id.Encode(), err)
+		}
+
+		report.Aggregates++
+		report.Events += len(aggregate.Events)
+		report.Appended += appended
+	}
+}
+
+// Verify reads the archive, checking it against its summaries and manifest, and returns the manifest.
+func Verify(in io.Reader) (Manifest, error) {
+	reader, err := NewReader(in)
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	for {
+		_, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			manifest, _ := reader.Manifest()
+			return manifest, nil
+		}
+		if err != nil {
+			return Manifest{}, err
+		}
+	}
+}
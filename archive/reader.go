@@ -0,0 +1,177 @@
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+
+	"github.com/weegigs/wee-events-go/we"
+)
+
+// Reader reads an archive one aggregate at a time, verifying each aggregate against its summary before returning it.
+// Compressed archives are detected and decompressed.
+type Reader struct {
+	in       *bufio.Reader
+	header   Header
+	digest   hash.Hash
+	totals   Manifest
+	manifest *Manifest
+}
+
+// NewReader creates a reader and reads the archive's header.
+func NewReader(r io.Reader) (*Reader, error) {
+	in := bufio.NewReader(r)
+
+	magic, err := in.Peek(2)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	if bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(in)
+		if err != nil {
+			return nil, err
+		}
+		in = bufio.NewReader(gz)
+	}
+
+	reader := &Reader{in: in, digest: sha256.New()}
+
+	data, err := reader.line()
+	if err != nil {
+		return nil, err
+	}
+
+	var header line
+	if err := json.Unmarshal(data, &header); err != nil || header.Archive == nil || header.Archive.Format != Format {
+		return nil, fmt.Errorf("%w: missing header", Malformed)
+	}
+
+	if header.Archive.Version > Version {
+		return nil, fmt.Errorf("%w: %d", UnsupportedVersion, header.Archive.Version)
+	}
+
+	reader.header = *header.Archive
+	reader.digest.Write(data)
+
+	return reader, nil
+}
+
+// line reads the next line, including its newline.
+func (r *Reader) line() ([]byte, error) {
+	data, err := r.in.ReadBytes('\n')
+	if errors.Is(err, io.EOF) {
+		return nil, Truncated
+	}
+
+	return data, err
+}
+
+func (r *Reader) Header() Header {
+	return r.header
+}
+
+// Manifest returns the archive's manifest once every aggregate has been read.
+func (r *Reader) Manifest() (Manifest, bool) {
+	if r.manifest == nil {
+		return Manifest{}, false
+	}
+
+	return *r.manifest, true
+}
+
+// Next reads the next aggregate, returning io.EOF after the manifest has been read and verified. The aggregate's
+// revision is its revision in the store it was exported from.
+func (r *Reader) Next() (we.Aggregate, error) {
+	if r.manifest != nil {
+		return we.Aggregate{}, io.EOF
+	}
+
+	var events []we.RecordedEvent
+	digest := sha256.New()
+
+	for {
+		data, err := r.line()
+		if err != nil {
+			return we.Aggregate{}, err
+		}
+
+		var l line
+		if err := json.Unmarshal(data, &l); err != nil {
+			return we.Aggregate{}, fmt.Errorf("%w: %v", Malformed, err)
+		}
+
+		switch {
+		case l.Summary != nil:
+			r.digest.Write(data)
+			return r.aggregate(*l.Summary, events, digest)
+
+		case l.Manifest != nil:
+			if len(events) > 0 {
+				return we.Aggregate{}, fmt.Errorf("%w: events of %s aren't summarised", Malformed, events[0].AggregateId.Encode())
+			}
+			if err := r.verify(*l.Manifest); err != nil {
+				return we.Aggregate{}, err
+			}
+			return we.Aggregate{}, io.EOF
+
+		case l.Archive != nil:
+			return we.Aggregate{}, fmt.Errorf("%w: unexpected header", Malformed)
+		}
+
+		var event we.RecordedEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			return we.Aggregate{}, fmt.Errorf("%w: %v", Malformed, err)
+		}
+
+		if len(events) > 0 && event.AggregateId != events[0].AggregateId {
+			return we.Aggregate{}, fmt.Errorf("%w: events of %s aren't summarised", Malformed, events[0].AggregateId.Encode())
+		}
+
+		digest.Write(data)
+		r.digest.Write(data)
+		events = append(events, event)
+	}
+}
+
+func (r *Reader) aggregate(summary Summary, events []we.RecordedEvent, digest hash.Hash) (we.Aggregate, error) {
+	if len(events) == 0 {
+		return we.Aggregate{}, fmt.Errorf("%w: %s is summarised without events", Malformed, summary.Aggregate.Encode())
+	}
+
+	if len(events) != summary.Events {
+		return we.Aggregate{}, fmt.Errorf("%w: %s has %d events, its summary records %d", Malformed, summary.Aggregate.Encode(), len(events), summary.Events)
+	}
+
+	if events[0].AggregateId != summary.Aggregate {
+		return we.Aggregate{}, fmt.Errorf("%w: events of %s are summarised as %s", Malformed, events[0].AggregateId.Encode(), summary.Aggregate.Encode())
+	}
+
+	if checksum(digest) != summary.Checksum {
+		return we.Aggregate{}, fmt.Errorf("%w: %s", ChecksumMismatch, summary.Aggregate.Encode())
+	}
+
+	r.totals.Aggregates++
+	r.totals.Events += len(events)
+
+	return we.Aggregate{Id: summary.Aggregate, Events: events, Revision: summary.Revision}, nil
+}
+
+func (r *Reader) verify(manifest Manifest) error {
+	if manifest.Aggregates != r.totals.Aggregates || manifest.Events != r.totals.Events {
+		return fmt.Errorf("%w: read %d aggregates and %d events, the manifest records %d and %d", Malformed, r.totals.Aggregates, r.totals.Events, manifest.Aggregates, manifest.Events)
+	}
+
+	if checksum(r.digest) != manifest.Checksum {
+		return fmt.Errorf("%w: archive", ChecksumMismatch)
+	}
+
+	r.manifest = &manifest
+	return nil
+}
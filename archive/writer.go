@@ -0,0 +1,131 @@
+package archive
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"hash"
+	"io"
+	"time"
+
+	"github.com/weegigs/wee-events-go/we"
+)
+
+type WriterOption func(*Writer)
+
+// WithCompression gzip compresses the archive.
+func WithCompression() WriterOption {
+	return func(w *Writer) {
+		w.compress = true
+	}
+}
+
+// Writer writes an archive, one aggregate at a time. The archive isn't complete until the writer is closed.
+type Writer struct {
+	compress bool
+
+	gz       *gzip.Writer
+	out      *bufio.Writer
+	digest   hash.Hash
+	manifest Manifest
+	closed   bool
+}
+
+// NewWriter creates a writer and writes the archive's header.
+func NewWriter(w io.Writer, options ...WriterOption) (*Writer, error) {
+	writer := &Writer{digest: sha256.New()}
+
+	for _, option := range options {
+		option(writer)
+	}
+
+	if writer.compress {
+		writer.gz = gzip.NewWriter(w)
+		w = writer.gz
+	}
+	writer.out = bufio.NewWriter(w)
+
+	header := Header{Format: Format, Version: Version, Created: we.TimestampFromTime(time.Now())}
+	if err := writer.write(line{Archive: &header}, writer.digest); err != nil {
+		return nil, err
+	}
+
+	return writer, nil
+}
+
+// write marshals the value as a line, adding it to the digests.
+func (w *Writer) write(value any, digests ...hash.Hash) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	for _, digest := range digests {
+		digest.Write(data)
+	}
+
+	_, err = w.out.Write(data)
+	return err
+}
+
+// WriteAggregate writes the aggregate's events followed by their summary. Aggregates without events are skipped.
+func (w *Writer) WriteAggregate(aggregate we.Aggregate) error {
+	if w.closed {
+		return errors.New("archive writer is closed")
+	}
+
+	if len(aggregate.Events) == 0 {
+		return nil
+	}
+
+	digest := sha256.New()
+	for _, event := range aggregate.Events {
+		event.AggregateId = aggregate.Id
+		if err := w.write(event, digest, w.digest); err != nil {
+			return err
+		}
+	}
+
+	summary := Summary{
+		Aggregate: aggregate.Id,
+		Events:    len(aggregate.Events),
+		Revision:  aggregate.Revision,
+		Checksum:  checksum(digest),
+	}
+	if err := w.write(line{Summary: &summary}, w.digest); err != nil {
+		return err
+	}
+
+	w.manifest.Aggregates++
+	w.manifest.Events += len(aggregate.Events)
+
+	return nil
+}
+
+// Close writes the manifest and flushes the archive, returning the manifest. The underlying writer isn't closed.
+func (w *Writer) Close() (Manifest, error) {
+	if w.closed {
+		return w.manifest, nil
+	}
+	w.closed = true
+
+	w.manifest.Checksum = checksum(w.digest)
+	if err := w.write(line{Manifest: &w.manifest}); err != nil {
+		return Manifest{}, err
+	}
+
+	if err := w.out.Flush(); err != nil {
+		return Manifest{}, err
+	}
+
+	if w.gz != nil {
+		if err := w.gz.Close(); err != nil {
+			return Manifest{}, err
+		}
+	}
+
+	return w.manifest, nil
+}
@@ -0,0 +1,199 @@
+// we-archive exports aggregates from an event store to a portable archive, imports archives into a store and verifies
+// archives against their checksums.
+//
+// Usage:
+//
+//	we-archive export -store url [-out path] [-gzip] [-type type | -all | id ...]
+//	we-archive import -store url [-in path]
+//	we-archive verify [-in path]
+//
+// Aggregate ids are given in their encoded form, type.key. Exporting a type, or every aggregate, requires a store that
+// can list its aggregates and importing requires a store that can append recorded events. See the connect package for
+// the supported store URLs. Archives are written to stdout and read from stdin unless a path is given; compressed
+// archives are detected when read.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	"github.com/weegigs/wee-events-go/archive"
+	"github.com/weegigs/wee-events-go/stores/connect"
+	"github.com/weegigs/wee-events-go/we"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var err error
+	switch os.Args[1] {
+	case "export":
+		err = export(ctx, os.Args[2:])
+	case "import":
+		err = load(ctx, os.Args[2:])
+	case "verify":
+		err = verify(os.Args[2:])
+	default:
+		usage()
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: we-archive export|import|verify [flags]")
+	os.Exit(2)
+}
+
+func export(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	storeURL := flags.String("store", "", "store url")
+	out := flags.String("out", "-", "archive path")
+	compress := flags.Bool("gzip", false, "compress the archive")
+	aggregateType := flags.String("type", "", "export every aggregate of the type")
+	all := flags.Bool("all", false, "export every aggregate")
+	_ = flags.Parse(args)
+
+	if *storeURL == "" {
+		return fmt.Errorf("-store is required")
+	}
+
+	ids := make([]we.AggregateId, flags.NArg())
+	for i, arg := range flags.Args() {
+		id, err := we.EncodedAggregateId(arg).Decode()
+		if err != nil {
+			return fmt.Errorf("invalid aggregate id %q: %w", arg, err)
+		}
+		ids[i] = *id
+	}
+
+	listed := *all || *aggregateType != ""
+	if listed == (len(ids) > 0) {
+		return fmt.Errorf("export either aggregate ids, a -type or -all")
+	}
+
+	store, err := connect.Open(ctx, *storeURL)
+	if err != nil {
+		return fmt.Errorf("failed to open store: %w", err)
+	}
+	defer store.Close()
+
+	var options []archive.WriterOption
+	if *compress {
+		options = append(options, archive.WithCompression())
+	}
+
+	writer, done, err := create(*out)
+	if err != nil {
+		return err
+	}
+
+	var manifest archive.Manifest
+	if listed {
+		manifest, err = archive.ExportType(ctx, store.EventStore, writer, *aggregateType, options...)
+	} else {
+		manifest, err = archive.Export(ctx, store.EventStore, writer, ids, options...)
+	}
+	if err := done(err); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "exported %d aggregates, %d events\n", manifest.Aggregates, manifest.Events)
+	return nil
+}
+
+// create opens the archive for writing, returning a function that completes it, removing a partially written file.
+func create(path string) (io.Writer, func(error) error, error) {
+	if path == "-" {
+		return os.Stdout, func(err error) error { return err }, nil
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return file, func(err error) error {
+		if err == nil {
+			err = file.Sync()
+		}
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			_ = os.Remove(path)
+		}
+		return err
+	}, nil
+}
+
+func open(path string) (io.ReadCloser, error) {
+	if path == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+
+	return os.Open(path)
+}
+
+func load(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	storeURL := flags.String("store", "", "store url")
+	in := flags.String("in", "-", "archive path")
+	_ = flags.Parse(args)
+
+	if *storeURL == "" {
+		return fmt.Errorf("-store is required")
+	}
+
+	store, err := connect.Open(ctx, *storeURL)
+	if err != nil {
+		return fmt.Errorf("failed to open store: %w", err)
+	}
+	defer store.Close()
+
+	reader, err := open(*in)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	report, err := archive.Import(ctx, store.EventStore, reader)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "imported %s\n", report)
+	return nil
+}
+
+func verify(args []string) error {
+	flags := flag.NewFlagSet("verify", flag.ExitOnError)
+	in := flags.String("in", "-", "archive path")
+	_ = flags.Parse(args)
+
+	reader, err := open(*in)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	manifest, err := archive.Verify(reader)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "verified %d aggregates, %d events\n", manifest.Aggregates, manifest.Events)
+	return nil
+}
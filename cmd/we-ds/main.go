@@ -0,0 +1,96 @@
+// we-ds creates or validates the DynamoDB events table used by the ds event store.
+//
+// Usage:
+//
+//	we-ds [-table name] [-endpoint url] [-create]
+//
+// The table name defaults to EVENTS_DYNAMODB_TABLE_NAME. Use -endpoint to target dynamodb-local, e.g.
+// -endpoint http://localhost:8000. The command exits with a non-zero status when the table is missing or its schema
+// has drifted from the one the event store expects.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+
+	"github.com/weegigs/wee-events-go/stores/ds"
+)
+
+func main() {
+	table := flag.String("table", os.Getenv("EVENTS_DYNAMODB_TABLE_NAME"), "events table name")
+	endpoint := flag.String("endpoint", "", "DynamoDB endpoint, e.g. http://localhost:8000 for dynamodb-local")
+	create := flag.Bool("create", false, "create the table if it does not exist")
+	flag.Parse()
+
+	if *table == "" {
+		fmt.Fprintln(os.Stderr, "a table name is required, set -table or EVENTS_DYNAMODB_TABLE_NAME")
+		os.Exit(2)
+	}
+
+	ok, err := run(context.Background(), *table, *endpoint, *create)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if !ok {
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, table string, endpoint string, create bool) (bool, error) {
+	db, err := client(ctx, endpoint)
+	if err != nil {
+		return false, err
+	}
+
+	schema := ds.ExpectedSchema(ds.EventStoreTableName(table))
+
+	var created bool
+	var drift []ds.Drift
+	if create {
+		created, drift, err = ds.Provision(ctx, db, schema)
+	} else {
+		drift, err = ds.Validate(ctx, db, schema)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if created {
+		fmt.Printf("created table %s\n", table)
+	}
+
+	if len(drift) == 0 {
+		fmt.Printf("table %s matches the expected schema\n", table)
+		return true, nil
+	}
+
+	fmt.Printf("table %s has drifted from the expected schema\n", table)
+	for _, d := range drift {
+		fmt.Printf("  %s\n", d)
+	}
+
+	return false, nil
+}
+
+func client(ctx context.Context, endpoint string) (*dynamodb.Client, error) {
+	if endpoint != "" {
+		cfg, err := ds.EndpointAWSConfig(ctx, endpoint)
+		if err != nil {
+			return nil, err
+		}
+		return dynamodb.NewFromConfig(cfg), nil
+	}
+
+	cfg, err := ds.DefaultAWSConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return ds.Client(cfg), nil
+}
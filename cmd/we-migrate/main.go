@@ -0,0 +1,116 @@
+// we-migrate copies every aggregate from one event store to another, preserving event ids, types, timestamps and
+// metadata, then verifies the event counts and payload hashes of each aggregate.
+//
+// Usage:
+//
+//	we-migrate -source url -target url [-checkpoint path] [-name name] [-verify-only]
+//
+// The source must be able to list its aggregates and the target must be able to append recorded events. See the
+// connect package for the supported store URLs. With -checkpoint the migration records its progress in the file and
+// resumes from it when run again. The command exits with a non-zero status when verification finds a mismatch.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/weegigs/wee-events-go/migrate"
+	"github.com/weegigs/wee-events-go/stores/connect"
+	"github.com/weegigs/wee-events-go/we"
+)
+
+func main() {
+	source := flag.String("source", "", "source store url")
+	target := flag.String("target", "", "target store url")
+	checkpoint := flag.String("checkpoint", "", "file recording the migration's progress")
+	name := flag.String("name", "we-migrate", "checkpoint name")
+	verifyOnly := flag.Bool("verify-only", false, "verify the target without copying")
+	flag.Parse()
+
+	if *source == "" || *target == "" {
+		fmt.Fprintln(os.Stderr, "-source and -target are required")
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	ok, err := run(ctx, *source, *target, *checkpoint, *name, *verifyOnly)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if !ok {
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, sourceURL string, targetURL string, checkpoint string, name string, verifyOnly bool) (bool, error) {
+	source, err := connect.Open(ctx, sourceURL)
+	if err != nil {
+		return false, fmt.Errorf("failed to open source: %w", err)
+	}
+	defer source.Close()
+
+	target, err := connect.Open(ctx, targetURL)
+	if err != nil {
+		return false, fmt.Errorf("failed to open target: %w", err)
+	}
+	defer target.Close()
+
+	lister, ok := source.EventStore.(we.AggregateLister)
+	if !ok {
+		return false, fmt.Errorf("the source store can't list aggregates")
+	}
+
+	appender, ok := target.EventStore.(we.EventAppender)
+	if !ok {
+		return false, fmt.Errorf("the target store can't append recorded events")
+	}
+
+	var options []migrate.MigratorOption
+	if checkpoint != "" {
+		options = append(options, migrate.WithCheckpoints(migrate.NewFileCheckpointStore(checkpoint), name))
+	}
+
+	migrator := migrate.NewMigrator(
+		struct {
+			we.EventStore
+			we.AggregateLister
+		}{source, lister},
+		struct {
+			we.EventStore
+			we.EventAppender
+		}{target, appender},
+		options...,
+	)
+
+	if !verifyOnly {
+		report, err := migrator.Run(ctx)
+		if err != nil {
+			return false, err
+		}
+		fmt.Printf("copied %s\n", report)
+	}
+
+	report, mismatches, err := migrator.Verify(ctx)
+	if err != nil {
+		return false, err
+	}
+	fmt.Printf("verified %s\n", report)
+
+	if len(mismatches) == 0 {
+		return true, nil
+	}
+
+	fmt.Printf("%d aggregates don't match\n", len(mismatches))
+	for _, mismatch := range mismatches {
+		fmt.Printf("  %s\n", mismatch)
+	}
+
+	return false, nil
+}
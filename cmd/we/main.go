@@ -0,0 +1,16 @@
+// we inspects the aggregates in an event store.
+//
+// Usage:
+//
+//	we [store flags] events [-json] [-payloads] type.key
+//	we [store flags] tail [-json] [-payloads] [-all] type.key
+//
+// See the wecli package for the store flags and their environment variables. This binary has no entity services
+// registered, so the entity and exec commands need a binary built with wecli.Main and the application's services.
+package main
+
+import "github.com/weegigs/wee-events-go/connectors/wecli"
+
+func main() {
+	wecli.Main()
+}
@@ -0,0 +1,234 @@
+// Package wecli implements the we command line tool for inspecting and operating on aggregates.
+//
+// The store is chosen with a URL, see the connect package, or with store specific flags, each of which defaults to
+// an environment variable:
+//
+//	-store url                 EVENTS_STORE_URL
+//	-esdb connection-string    EVENTS_ESDB_CONNECTION_STRING
+//	-nats url -stream name     NATS_URL, EVENTS_JETSTREAM_STREAM
+//	-dynamodb-table name       EVENTS_DYNAMODB_TABLE_NAME, with -dynamodb-endpoint or EVENTS_DYNAMODB_ENDPOINT
+//
+// The events published by exec record the actor given with -actor, or EVENTS_ACTOR.
+//
+// Entity services aren't known to the tool, so running commands requires a binary that registers them:
+//
+//	func main() {
+//		wecli.Main(wecli.WithService("counter", NewCounterService))
+//	}
+package wecli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/signal"
+	"sort"
+
+	"github.com/weegigs/wee-events-go/stores/connect"
+	"github.com/weegigs/wee-events-go/we"
+)
+
+// UsageError is returned when the tool is invoked with invalid arguments.
+var UsageError = errors.New("usage")
+
+type Option func(*CLI)
+
+// WithService registers the entity service for the aggregate type, created with the store when a command runs.
+func WithService[T any](aggregateType string, factory func(store we.EventStore) we.EntityService[T]) Option {
+	return func(cli *CLI) {
+		cli.services[aggregateType] = func(store we.EventStore) service {
+			return entityService[T]{factory(store)}
+		}
+	}
+}
+
+// WithOutput sets where results and diagnostics are written.
+func WithOutput(out io.Writer, diagnostics io.Writer) Option {
+	return func(cli *CLI) {
+		cli.out = out
+		cli.diagnostics = diagnostics
+	}
+}
+
+// WithEnvironment sets the function used to look up the environment variables flags default to.
+func WithEnvironment(getenv func(string) string) Option {
+	return func(cli *CLI) {
+		cli.getenv = getenv
+	}
+}
+
+type CLI struct {
+	services    map[string]func(store we.EventStore) service
+	out         io.Writer
+	diagnostics io.Writer
+	getenv      func(string) string
+}
+
+func New(options ...Option) *CLI {
+	cli := &CLI{
+		services:    map[string]func(store we.EventStore) service{},
+		out:         os.Stdout,
+		diagnostics: os.Stderr,
+		getenv:      os.Getenv,
+	}
+
+	for _, option := range options {
+		option(cli)
+	}
+
+	return cli
+}
+
+// Main runs the tool with the process arguments, exiting with a non-zero status on failure.
+func Main(options ...Option) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	err := New(options...).Run(ctx, os.Args[1:])
+	stop()
+
+	if errors.Is(err, UsageError) {
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+const usage = `usage: we [flags] command [command flags] [arguments]
+
+commands:
+  events [-json] [-payloads] type.key           print the aggregate's events
+  tail [-json] [-payloads] [-all] type.key      print the aggregate's events as they are published
+  entity type.key                               print the entity rendered by its service
+  exec type.key command [payload | -]           execute a command, the payload is JSON and - reads it from stdin
+  services                                      list the aggregate types with a registered service
+`
+
+func (c *CLI) usage(flags *flag.FlagSet) func() {
+	return func() {
+		fmt.Fprint(c.diagnostics, usage)
+		fmt.Fprintln(c.diagnostics, "\nflags:")
+		flags.PrintDefaults()
+	}
+}
+
+// Run runs the tool with the arguments, excluding the program name.
+func (c *CLI) Run(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("we", flag.ContinueOnError)
+	flags.SetOutput(c.diagnostics)
+	flags.Usage = c.usage(flags)
+
+	storeURL := flags.String("store", c.getenv("EVENTS_STORE_URL"), "store url")
+	esdb := flags.String("esdb", c.getenv("EVENTS_ESDB_CONNECTION_STRING"), "EventStoreDB connection string")
+	natsURL := flags.String("nats", c.getenv("NATS_URL"), "NATS server url")
+	stream := flags.String("stream", c.getenv("EVENTS_JETSTREAM_STREAM"), "JetStream stream name")
+	table := flags.String("dynamodb-table", c.getenv("EVENTS_DYNAMODB_TABLE_NAME"), "DynamoDB events table name")
+	endpoint := flags.String("dynamodb-endpoint", c.getenv("EVENTS_DYNAMODB_ENDPOINT"), "DynamoDB endpoint, e.g. http://localhost:8000")
+	actor := flags.String("actor", c.getenv("EVENTS_ACTOR"), "actor recorded with the events published by exec")
+
+	if err := flags.Parse(args); err != nil {
+		return UsageError
+	}
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return UsageError
+	}
+
+	command, args := flags.Arg(0), flags.Args()[1:]
+	if command == "services" {
+		return c.listServices()
+	}
+
+	run, ok := map[string]func(ctx context.Context, store we.EventStore, args []string) error{
+		"events": c.events,
+		"tail":   c.tail,
+		"entity": c.entity,
+		"exec":   c.exec,
+	}[command]
+	if !ok {
+		fmt.Fprintf(c.diagnostics, "unknown command %q\n", command)
+		flags.Usage()
+		return UsageError
+	}
+
+	location, err := storeLocation(*storeURL, *esdb, *natsURL, *stream, *table, *endpoint)
+	if err != nil {
+		fmt.Fprintln(c.diagnostics, err)
+		return UsageError
+	}
+
+	if *actor != "" {
+		ctx = we.WithMetadata(ctx, we.RecordedEventMetadata{Actor: we.Actor(*actor)})
+	}
+
+	store, err := connect.Open(ctx, location)
+	if err != nil {
+		return fmt.Errorf("failed to open store: %w", err)
+	}
+	defer store.Close()
+
+	return run(ctx, store.EventStore, args)
+}
+
+// storeLocation returns the url of the store, preferring an explicit url over the store specific settings.
+func storeLocation(storeURL string, esdb string, natsURL string, stream string, table string, endpoint string) (string, error) {
+	switch {
+	case storeURL != "":
+		return storeURL, nil
+
+	case esdb != "":
+		return esdb, nil
+
+	case natsURL != "":
+		if stream == "" {
+			return "", errors.New("a JetStream stream name is required, set -stream or EVENTS_JETSTREAM_STREAM")
+		}
+
+		u, err := url.Parse(natsURL)
+		if err != nil {
+			return "", fmt.Errorf("invalid NATS url: %w", err)
+		}
+		u.Path = "/" + stream
+		return u.String(), nil
+
+	case table != "":
+		u := url.URL{Scheme: "dynamodb", Host: table}
+		if endpoint != "" {
+			u.RawQuery = url.Values{"endpoint": {endpoint}}.Encode()
+		}
+		return u.String(), nil
+	}
+
+	return "", errors.New("a store is required, set -store or EVENTS_STORE_URL")
+}
+
+func (c *CLI) listServices() error {
+	types := make([]string, 0, len(c.services))
+	for aggregateType := range c.services {
+		types = append(types, aggregateType)
+	}
+	sort.Strings(types)
+
+	for _, aggregateType := range types {
+		fmt.Fprintln(c.out, aggregateType)
+	}
+
+	return nil
+}
+
+// parseId decodes an aggregate id given as type.key.
+func parseId(arg string) (we.AggregateId, error) {
+	id, err := we.EncodedAggregateId(arg).Decode()
+	if err != nil {
+		return we.AggregateId{}, fmt.Errorf("invalid aggregate id %q: %w", arg, err)
+	}
+
+	return *id, nil
+}
@@ -0,0 +1,230 @@
+package wecli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/weegigs/wee-events-go/samples/counter"
+	"github.com/weegigs/wee-events-go/stores/file"
+	"github.com/weegigs/wee-events-go/we"
+)
+
+func counterService(store we.EventStore) we.EntityService[counter.Counter] {
+	dispatcher := we.RoutedDispatcher[counter.Counter]{Handlers: counter.CommandHandlers(counter.PseudoRandomizer()), Publish: store.Publish}
+	return we.NewEntityService(counter.Loader(store), &dispatcher)
+}
+
+type harness struct {
+	cli         *CLI
+	out         bytes.Buffer
+	diagnostics bytes.Buffer
+}
+
+func newHarness(dir string) *harness {
+	h := &harness{}
+	h.cli = New(
+		WithService("counter", counterService),
+		WithOutput(&h.out, &h.diagnostics),
+		WithEnvironment(func(name string) string {
+			if name == "EVENTS_STORE_URL" {
+				return "file://" + dir
+			}
+			return ""
+		}),
+	)
+
+	return h
+}
+
+func (h *harness) run(t *testing.T, args ...string) string {
+	h.out.Reset()
+	require.NoError(t, h.cli.Run(context.Background(), args), h.diagnostics.String())
+
+	return h.out.String()
+}
+
+func seed(t *testing.T, dir string, id we.AggregateId, events ...we.DomainEvent) {
+	store, err := file.NewEventStore(dir)
+	require.NoError(t, err)
+	defer store.Close()
+
+	options := we.Options(we.WithCorrelationId("correlation"), we.WithActor("user/seed"))
+	require.NoError(t, store.Publish(context.Background(), id, options, events...))
+}
+
+func TestEvents(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "log")
+	id := we.AggregateId{Type: "counter", Key: "one"}
+	seed(t, dir, id, counter.Incremented{Amount: 3}, counter.Decremented{Amount: 1})
+	h := newHarness(dir)
+
+	t.Run("prints a table", func(t *testing.T) {
+		lines := strings.Split(strings.TrimSpace(h.run(t, "events", "counter.one")), "\n")
+		require.Len(t, lines, 3)
+		assert.True(t, strings.HasPrefix(lines[0], "TIMESTAMP"))
+		assert.Contains(t, lines[1], we.EventTypeOf(counter.Incremented{}).String())
+		assert.NotContains(t, lines[1], "correlation")
+	})
+
+	t.Run("prints payloads and metadata", func(t *testing.T) {
+		lines := strings.Split(strings.TrimSpace(h.run(t, "events", "-payloads", "counter.one")), "\n")
+		require.Len(t, lines, 3)
+		assert.Contains(t, lines[1], "correlation")
+		assert.Contains(t, lines[1], "user/seed")
+		assert.Contains(t, lines[1], `{"amount":3}`)
+	})
+
+	t.Run("prints json", func(t *testing.T) {
+		lines := strings.Split(strings.TrimSpace(h.run(t, "events", "-json", "counter.one")), "\n")
+		require.Len(t, lines, 2)
+
+		var event we.RecordedEvent
+		require.NoError(t, json.Unmarshal([]byte(lines[1]), &event))
+		assert.Equal(t, id, event.AggregateId)
+		assert.Equal(t, we.EventTypeOf(counter.Decremented{}), event.EventType)
+		assert.Equal(t, we.CorrelationID("correlation"), event.Metadata.CorrelationId)
+	})
+
+	t.Run("rejects invalid ids", func(t *testing.T) {
+		assert.Error(t, h.cli.Run(context.Background(), []string{"events", "counter"}))
+	})
+}
+
+func TestCommands(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "log")
+	h := newHarness(dir)
+
+	t.Run("executes commands", func(t *testing.T) {
+		var resource map[string]any
+		output := h.run(t, "exec", "counter.two", string(we.CommandNameOf(counter.Increment{})), `{"Amount": 5}`)
+		require.NoError(t, json.Unmarshal([]byte(output), &resource))
+		assert.Equal(t, 5.0, resource["current"])
+		assert.Equal(t, "counter.two", resource["$id"])
+	})
+
+	t.Run("records the actor", func(t *testing.T) {
+		h.run(t, "-actor", "user/cli", "exec", "counter.two", string(we.CommandNameOf(counter.Increment{})), `{"Amount": 1}`)
+
+		lines := strings.Split(strings.TrimSpace(h.run(t, "events", "-json", "counter.two")), "\n")
+		require.Len(t, lines, 2)
+
+		var event we.RecordedEvent
+		require.NoError(t, json.Unmarshal([]byte(lines[1]), &event))
+		assert.Equal(t, we.Actor("user/cli"), event.Metadata.Actor)
+	})
+
+	t.Run("loads entities", func(t *testing.T) {
+		var resource map[string]any
+		require.NoError(t, json.Unmarshal([]byte(h.run(t, "entity", "counter.two")), &resource))
+		assert.Equal(t, 6.0, resource["current"])
+	})
+
+	t.Run("lists services", func(t *testing.T) {
+		assert.Equal(t, "counter\n", h.run(t, "services"))
+	})
+
+	t.Run("requires a registered service", func(t *testing.T) {
+		err := h.cli.Run(context.Background(), []string{"entity", "order.one"})
+		assert.ErrorContains(t, err, "no service")
+	})
+
+	t.Run("rejects unknown commands", func(t *testing.T) {
+		assert.ErrorIs(t, h.cli.Run(context.Background(), []string{"nope"}), UsageError)
+	})
+}
+
+func TestTail(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "log")
+	id := we.AggregateId{Type: "counter", Key: "three"}
+	seed(t, dir, id, counter.Incremented{Amount: 1})
+	h := newHarness(dir)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	require.NoError(t, h.cli.Run(ctx, []string{"tail", "-all", "-json", "-interval", "10ms", "counter.three"}))
+	assert.Equal(t, 1, strings.Count(h.out.String(), "\n"), "existing events are printed once")
+}
+
+// scriptedStore loads the aggregates of a script in turn, repeating the last.
+type scriptedStore struct {
+	we.EventStore
+	loads []we.Aggregate
+}
+
+func (s *scriptedStore) Load(context.Context, we.AggregateId) (we.Aggregate, error) {
+	aggregate := s.loads[0]
+	if len(s.loads) > 1 {
+		s.loads = s.loads[1:]
+	}
+
+	return aggregate, nil
+}
+
+func TestTailRevisions(t *testing.T) {
+	id := we.AggregateId{Type: "counter", Key: "four"}
+	event := func(revision we.Revision) we.RecordedEvent {
+		return we.RecordedEvent{AggregateId: id, EventID: we.EventID(revision), Revision: revision}
+	}
+	aggregate := func(events ...we.RecordedEvent) we.Aggregate {
+		return we.Aggregate{Id: id, Events: events, Revision: events[len(events)-1].Revision}
+	}
+
+	first := event("01GV8Y3VQ7S3W1J8D4B5E4Y0K1")
+	second := event("01GV8Y3VQ7S3W1J8D4B5E4Y0K2")
+	third := event("01GV8Y3VQ7S3W1J8D4B5E4Y0K3")
+
+	tail := func(t *testing.T, loads ...we.Aggregate) []we.EventID {
+		h := newHarness(t.TempDir())
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		require.NoError(t, h.cli.tail(ctx, &scriptedStore{loads: loads}, []string{"-json", "-interval", "5ms", "counter.four"}))
+
+		var printed []we.EventID
+		decoder := json.NewDecoder(&h.out)
+		for decoder.More() {
+			var recorded we.RecordedEvent
+			require.NoError(t, decoder.Decode(&recorded))
+			printed = append(printed, recorded.EventID)
+		}
+
+		return printed
+	}
+
+	t.Run("prints events published after a truncation", func(t *testing.T) {
+		// the first event is truncated and the third published between polls, leaving the count unchanged
+		printed := tail(t, aggregate(first, second), aggregate(second, third))
+		assert.Equal(t, []we.EventID{third.EventID}, printed)
+	})
+
+	t.Run("prints the events of a removed aggregate that was published to again", func(t *testing.T) {
+		printed := tail(t, aggregate(second, third), aggregate(first))
+		assert.Equal(t, []we.EventID{first.EventID}, printed)
+	})
+}
+
+func TestStoreLocation(t *testing.T) {
+	location, err := storeLocation("", "", "nats://localhost:4222", "events", "", "")
+	require.NoError(t, err)
+	assert.Equal(t, "nats://localhost:4222/events", location)
+
+	location, err = storeLocation("", "", "", "", "events", "http://localhost:8000")
+	require.NoError(t, err)
+	assert.Equal(t, "dynamodb://events?endpoint=http%3A%2F%2Flocalhost%3A8000", location)
+
+	location, err = storeLocation("sqlite:///tmp/events.db", "esdb://localhost:2113", "", "", "events", "")
+	require.NoError(t, err)
+	assert.Equal(t, "sqlite:///tmp/events.db", location, "urls take precedence")
+
+	_, err = storeLocation("", "", "nats://localhost:4222", "", "", "")
+	assert.Error(t, err, "streams are required")
+}
@@ -0,0 +1,187 @@
+package wecli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/weegigs/wee-events-go/we"
+)
+
+// printer writes events as an aligned table, or as a JSON object per line.
+type printer struct {
+	out      io.Writer
+	json     bool
+	payloads bool
+	header   bool
+}
+
+func (p *printer) print(events []we.RecordedEvent) error {
+	if p.json {
+		encoder := json.NewEncoder(p.out)
+		for _, event := range events {
+			if err := encoder.Encode(printable(event)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	table := tabwriter.NewWriter(p.out, 0, 4, 2, ' ', 0)
+	if !p.header {
+		p.header = true
+		columns := []string{"TIMESTAMP", "TYPE", "ID", "REVISION"}
+		if p.payloads {
+			columns = append(columns, "CORRELATION", "CAUSATION", "ACTOR", "DATA")
+		}
+		fmt.Fprintln(table, strings.Join(columns, "\t"))
+	}
+
+	for _, event := range events {
+		columns := []string{event.Timestamp.String(), event.EventType.String(), event.EventID.String(), event.Revision.String()}
+		if p.payloads {
+			columns = append(columns,
+				dash(event.Metadata.CorrelationId.String()),
+				dash(event.Metadata.CausationId.String()),
+				dash(event.Metadata.Actor.String()),
+				summary(event.Data),
+			)
+		}
+		fmt.Fprintln(table, strings.Join(columns, "\t"))
+	}
+
+	return table.Flush()
+}
+
+func dash(value string) string {
+	if value == "" {
+		return "-"
+	}
+
+	return value
+}
+
+func isJSON(data we.Data) bool {
+	return strings.HasPrefix(data.Encoding, "application/json") && json.Valid(data.Data)
+}
+
+// summary returns the data as compacted JSON, or its size and encoding when it isn't JSON.
+func summary(data we.Data) string {
+	if !isJSON(data) {
+		return fmt.Sprintf("<%d bytes %s>", len(data.Data), data.Encoding)
+	}
+
+	var compacted bytes.Buffer
+	_ = json.Compact(&compacted, data.Data)
+	return compacted.String()
+}
+
+// printable returns the event with data that isn't JSON encoded as a base64 string, so it can be marshalled.
+func printable(event we.RecordedEvent) we.RecordedEvent {
+	if isJSON(event.Data) {
+		return event
+	}
+
+	encoded, _ := json.Marshal([]byte(event.Data.Data))
+	event.Data.Data = encoded
+	return event
+}
+
+// eventFlags parses the output flags shared by events and tail, returning the aggregate id argument.
+func (c *CLI) eventFlags(flags *flag.FlagSet, args []string) (*printer, we.AggregateId, error) {
+	p := &printer{out: c.out}
+	flags.SetOutput(c.diagnostics)
+	flags.BoolVar(&p.json, "json", false, "print the events as JSON, one per line")
+	flags.BoolVar(&p.payloads, "payloads", false, "include the metadata and data of the events in the table")
+
+	if err := flags.Parse(args); err != nil {
+		return nil, we.AggregateId{}, UsageError
+	}
+
+	if flags.NArg() != 1 {
+		fmt.Fprintf(c.diagnostics, "usage: we %s [flags] type.key\n", flags.Name())
+		return nil, we.AggregateId{}, UsageError
+	}
+
+	id, err := parseId(flags.Arg(0))
+	return p, id, err
+}
+
+func (c *CLI) events(ctx context.Context, store we.EventStore, args []string) error {
+	p, id, err := c.eventFlags(flag.NewFlagSet("events", flag.ContinueOnError), args)
+	if err != nil {
+		return err
+	}
+
+	aggregate, err := store.Load(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return p.print(aggregate.Events)
+}
+
+// tail polls the aggregate, printing events recorded after the last event printed until the context is cancelled.
+func (c *CLI) tail(ctx context.Context, store we.EventStore, args []string) error {
+	flags := flag.NewFlagSet("tail", flag.ContinueOnError)
+	interval := flags.Duration("interval", time.Second, "time between polls of the aggregate")
+	all := flags.Bool("all", false, "print the existing events first")
+
+	p, id, err := c.eventFlags(flags, args)
+	if err != nil {
+		return err
+	}
+
+	// the revision of the last event printed, or of the aggregate when tailing began
+	var last we.Revision
+	if *all {
+		last = we.InitialRevision
+	}
+
+	for {
+		aggregate, err := store.Load(ctx, id)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case last == "":
+			last = aggregate.Revision
+		case aggregate.Revision.Before(last):
+			// revisions only go back when the aggregate has been removed, so every event is new
+			fmt.Fprintf(c.diagnostics, "%s was removed\n", id.Encode())
+			if err := p.print(aggregate.Events); err != nil {
+				return err
+			}
+			last = aggregate.Revision
+		default:
+			var recorded []we.RecordedEvent
+			for _, event := range aggregate.Events {
+				if event.Revision.After(last) {
+					recorded = append(recorded, event)
+				}
+			}
+
+			if err := p.print(recorded); err != nil {
+				return err
+			}
+			last = aggregate.Revision
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(*interval):
+		}
+	}
+}
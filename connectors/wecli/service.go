@@ -0,0 +1,154 @@
+package wecli
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/weegigs/wee-events-go/we"
+)
+
+// service is an entity service with its state type erased, rendering entities as resources.
+type service interface {
+	load(ctx context.Context, id we.AggregateId) (map[string]any, error)
+	execute(ctx context.Context, id we.AggregateId, command we.RemoteCommand) (map[string]any, error)
+}
+
+type entityService[T any] struct {
+	service we.EntityService[T]
+}
+
+func (s entityService[T]) load(ctx context.Context, id we.AggregateId) (map[string]any, error) {
+	entity, err := s.service.Load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return resource(entity)
+}
+
+func (s entityService[T]) execute(ctx context.Context, id we.AggregateId, command we.RemoteCommand) (map[string]any, error) {
+	entity, err := s.service.Execute(ctx, id, command)
+	if err != nil {
+		return nil, err
+	}
+
+	return resource(entity)
+}
+
+// resource renders the entity as the http connector does, its state along with its id, type and revision.
+func resource[T any](entity we.Entity[T]) (map[string]any, error) {
+	if !entity.Initialized() {
+		return nil, fmt.Errorf("%s not found", entity.Aggregate.Encode())
+	}
+
+	resource, err := we.StateSerializer(entity)
+	if err != nil {
+		return nil, err
+	}
+
+	resource["$id"] = entity.Aggregate.Encode()
+	resource["$type"] = entity.Type
+	resource["$revision"] = entity.Revision
+
+	return resource, nil
+}
+
+func (c *CLI) service(store we.EventStore, id we.AggregateId) (service, error) {
+	factory, ok := c.services[id.Type]
+	if !ok {
+		return nil, fmt.Errorf("no service is registered for %s aggregates", id.Type)
+	}
+
+	return factory(store), nil
+}
+
+func (c *CLI) print(resource map[string]any) error {
+	encoder := json.NewEncoder(c.out)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(resource)
+}
+
+func (c *CLI) entity(ctx context.Context, store we.EventStore, args []string) error {
+	flags := flag.NewFlagSet("entity", flag.ContinueOnError)
+	flags.SetOutput(c.diagnostics)
+	if err := flags.Parse(args); err != nil {
+		return UsageError
+	}
+
+	if flags.NArg() != 1 {
+		fmt.Fprintln(c.diagnostics, "usage: we entity type.key")
+		return UsageError
+	}
+
+	id, err := parseId(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	service, err := c.service(store, id)
+	if err != nil {
+		return err
+	}
+
+	resource, err := service.load(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return c.print(resource)
+}
+
+func (c *CLI) exec(ctx context.Context, store we.EventStore, args []string) error {
+	flags := flag.NewFlagSet("exec", flag.ContinueOnError)
+	flags.SetOutput(c.diagnostics)
+	if err := flags.Parse(args); err != nil {
+		return UsageError
+	}
+
+	if flags.NArg() < 2 || flags.NArg() > 3 {
+		fmt.Fprintln(c.diagnostics, "usage: we exec type.key command [payload | -]")
+		return UsageError
+	}
+
+	id, err := parseId(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	payload := []byte("{}")
+	switch flags.Arg(2) {
+	case "":
+	case "-":
+		if payload, err = io.ReadAll(os.Stdin); err != nil {
+			return err
+		}
+	default:
+		payload = []byte(flags.Arg(2))
+	}
+
+	if !json.Valid(payload) {
+		return fmt.Errorf("the payload isn't valid JSON")
+	}
+
+	service, err := c.service(store, id)
+	if err != nil {
+		return err
+	}
+
+	command := we.RemoteCommand{
+		CommandName: we.CommandName(flags.Arg(1)),
+		Payload:     we.Data{Encoding: "application/json", Data: payload},
+	}
+
+	resource, err := service.execute(ctx, id, command)
+	if err != nil {
+		return err
+	}
+
+	return c.print(resource)
+}
@@ -24,6 +24,13 @@ func Logger[T any](log *zerolog.Logger) HandlerOption[T] {
   }
 }
 
+// Actor sets how the actor recorded with the events published by commands is identified from their requests.
+func Actor[T any](resolver ActorResolver) HandlerOption[T] {
+  return func(service *httpService[T]) {
+    service.actor = resolver
+  }
+}
+
 func NewHandler[T any](entityService we.EntityService[T], options ...HandlerOption[T]) http.Handler {
   service := &httpService[T]{controller: entityService, encoder: we.NewResourceEncoder[T]()}
   for _, option := range options {
@@ -36,6 +43,7 @@ func NewHandler[T any](entityService we.EntityService[T], options ...HandlerOpti
   r := chi.NewRouter()
 
   r.Use(render.SetContentType(render.ContentTypeJSON))
+  r.Use(RequestMetadata(service.actor))
 
   r.Method("GET", "/{type}/{key}", service.getResource())
   r.Method("POST", "/{type}/{key}", service.executeCommand())
@@ -45,6 +53,7 @@ func NewHandler[T any](entityService we.EntityService[T], options ...HandlerOpti
 
 type httpService[T any] struct {
   log        *zerolog.Logger
+  actor      ActorResolver
   controller we.EntityService[T]
   encoder    we.EntityEncoder[T]
 }
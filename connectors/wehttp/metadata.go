@@ -0,0 +1,36 @@
+package wehttp
+
+import (
+  "net/http"
+
+  "go.opentelemetry.io/otel/trace"
+
+  "github.com/weegigs/wee-events-go/we"
+)
+
+// ActorResolver identifies who issued a request, returning an empty actor when it is anonymous.
+type ActorResolver func(r *http.Request) we.Actor
+
+// RequestMetadata is middleware adding the request's actor, remote address and, when it isn't traced by the server,
+// the caller's traceparent to the context, so they are recorded with the events its commands publish.
+func RequestMetadata(actor ActorResolver) func(http.Handler) http.Handler {
+  return func(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+      ctx := r.Context()
+
+      metadata := we.RecordedEventMetadata{
+        Extensions: we.Extensions{we.RemoteAddressExtension: r.RemoteAddr},
+      }
+
+      if actor != nil {
+        metadata.Actor = actor(r)
+      }
+
+      if !trace.SpanContextFromContext(ctx).IsValid() {
+        metadata.TraceParent = we.TraceParent(r.Header.Get("traceparent"))
+      }
+
+      next.ServeHTTP(w, r.WithContext(we.WithMetadata(ctx, metadata)))
+    })
+  }
+}
@@ -1,38 +1,18 @@
 package internal
 
 import (
-	"encoding/binary"
-	"github.com/oklog/ulid/v2"
+	"time"
+
 	"github.com/weegigs/wee-events-go/we"
 )
 
+// EncodeRevision encodes the sequence and index in a revision recorded at the millisecond timestamp.
 func EncodeRevision(timestamp uint64, sequence uint64, index uint16) (we.Revision, error) {
-	r := &ulid.ULID{}
-	err := r.SetTime(timestamp)
-
-	if err != nil {
-		return "", err
-	}
-
-	entropy := make([]byte, 10)
-	binary.BigEndian.PutUint64(entropy[:8], sequence)
-	binary.BigEndian.PutUint16(entropy[8:], index)
-
-	err = r.SetEntropy(entropy)
-	if err != nil {
-		return "", err
-	}
-
-	return we.Revision(r.String()), nil
+	return we.SequencedRevision(time.UnixMilli(int64(timestamp)), sequence, index)
 }
 
+// DecodeSequenceNumber returns the sequence encoded in a revision by EncodeRevision.
 func DecodeSequenceNumber(revision we.Revision) (uint64, error) {
-	parsed, err := ulid.Parse(revision.String())
-	if err != nil {
-		return 0, err
-	}
-
-	sequence := binary.BigEndian.Uint64(parsed.Entropy()[:8])
-
-	return sequence, nil
+	sequence, _, err := revision.Sequence()
+	return sequence, err
 }
@@ -0,0 +1,92 @@
+package migrate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/weegigs/wee-events-go/we"
+)
+
+// FileCheckpointStore keeps checkpoints in a JSON file, replacing the file atomically on each save, so a migration
+// run from the command line can be resumed.
+type FileCheckpointStore struct {
+	path string
+
+	lk sync.Mutex
+}
+
+func NewFileCheckpointStore(path string) *FileCheckpointStore {
+	return &FileCheckpointStore{path: path}
+}
+
+func (s *FileCheckpointStore) read() (map[string]we.Checkpoint, error) {
+	checkpoints := map[string]we.Checkpoint{}
+
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return checkpoints, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &checkpoints); err != nil {
+		return nil, err
+	}
+
+	return checkpoints, nil
+}
+
+func (s *FileCheckpointStore) LoadCheckpoint(_ context.Context, name string) (we.Checkpoint, error) {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	checkpoints, err := s.read()
+	if err != nil {
+		return we.NoCheckpoint, err
+	}
+
+	return checkpoints[name], nil
+}
+
+func (s *FileCheckpointStore) SaveCheckpoint(_ context.Context, name string, checkpoint we.Checkpoint) error {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	checkpoints, err := s.read()
+	if err != nil {
+		return err
+	}
+	checkpoints[name] = checkpoint
+
+	data, err := json.MarshalIndent(checkpoints, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+
+	if _, err := temp.Write(data); err != nil {
+		_ = temp.Close()
+		return err
+	}
+
+	if err := temp.Sync(); err != nil {
+		_ = temp.Close()
+		return err
+	}
+
+	if err := temp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(temp.Name(), s.path)
+}
@@ -0,0 +1,60 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/weegigs/wee-events-go/we"
+)
+
+// Target is a store events can be copied to.
+type Target interface {
+	we.EventStore
+	we.EventAppender
+}
+
+// Diverged is returned when a target aggregate holds events that aren't a prefix of the source aggregate's events,
+// so they can't be brought into line by appending.
+var Diverged = errors.New("aggregate-diverged")
+
+// Copy appends the events of the source aggregate missing from the target aggregate, returning the number copied.
+// Copying is idempotent, so an interrupted copy can be repeated.
+func Copy(ctx context.Context, source we.EventStore, target Target, id we.AggregateId) (int, error) {
+	from, err := source.Load(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+
+	return AppendMissing(ctx, target, id, from.Events)
+}
+
+// AppendMissing appends the events the target aggregate doesn't hold yet, returning the number appended. The target's
+// events must be a prefix of the events.
+func AppendMissing(ctx context.Context, target Target, id we.AggregateId, events []we.RecordedEvent) (int, error) {
+	to, err := target.Load(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(to.Events) > len(events) {
+		return 0, fmt.Errorf("%w: %s has %d events in the target and %d in the source", Diverged, id.Encode(), len(to.Events), len(events))
+	}
+
+	for i, event := range to.Events {
+		if event.EventID != events[i].EventID {
+			return 0, fmt.Errorf("%w: %s event %d is %s in the target and %s in the source", Diverged, id.Encode(), i, event.EventID, events[i].EventID)
+		}
+	}
+
+	missing := events[len(to.Events):]
+	if len(missing) == 0 {
+		return 0, nil
+	}
+
+	if _, err := target.Append(ctx, id, to.Revision, missing); err != nil {
+		return 0, err
+	}
+
+	return len(missing), nil
+}
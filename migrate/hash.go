@@ -0,0 +1,56 @@
+package migrate
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/weegigs/wee-events-go/we"
+)
+
+// Hash digests the parts of the events a migration preserves, their ids, types, timestamps, metadata and data, so
+// an aggregate can be compared across stores. Revisions are store specific and aren't included. JSON data is
+// compacted first as stores differ in how they keep whitespace.
+func Hash(events []we.RecordedEvent) string {
+	digest := sha256.New()
+
+	field := func(value string) {
+		digest.Write([]byte(value))
+		digest.Write([]byte{0})
+	}
+
+	for _, event := range events {
+		field(event.EventID.String())
+		field(event.EventType.String())
+		field(event.Timestamp.String())
+		field(event.Metadata.CausationId.String())
+		field(event.Metadata.CorrelationId.String())
+		field(event.Metadata.Actor.String())
+		field(event.Metadata.TraceParent.String())
+		field(strconv.Itoa(len(event.Metadata.Extensions)))
+		for _, key := range event.Metadata.Extensions.Keys() {
+			field(key.String())
+			field(event.Metadata.Extensions[key])
+		}
+		field(event.Data.Encoding)
+		field(string(canonical(event.Data)))
+	}
+
+	return hex.EncodeToString(digest.Sum(nil))
+}
+
+func canonical(data we.Data) []byte {
+	if !strings.HasPrefix(data.Encoding, "application/json") {
+		return data.Data
+	}
+
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, data.Data); err != nil {
+		return data.Data
+	}
+
+	return compacted.Bytes()
+}
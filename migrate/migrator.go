@@ -0,0 +1,161 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/weegigs/wee-events-go/we"
+)
+
+// Source is a store aggregates can be enumerated and copied from.
+type Source interface {
+	we.EventStore
+	we.AggregateLister
+}
+
+type MigratorOption func(*Migrator)
+
+// WithCheckpoints saves the listing checkpoint under the name after each aggregate is copied, and resumes from it.
+func WithCheckpoints(store we.CheckpointStore, name string) MigratorOption {
+	return func(m *Migrator) {
+		m.checkpoints = store
+		m.name = name
+	}
+}
+
+// WithProgress calls the function after each aggregate is copied.
+func WithProgress(progress func(id we.AggregateId, copied int)) MigratorOption {
+	return func(m *Migrator) {
+		m.progress = progress
+	}
+}
+
+// NewMigrator creates a migrator that copies every aggregate listed by the source to the target.
+func NewMigrator(source Source, target Target, options ...MigratorOption) *Migrator {
+	m := &Migrator{source: source, target: target}
+
+	for _, option := range options {
+		option(m)
+	}
+
+	return m
+}
+
+type Migrator struct {
+	source      Source
+	target      Target
+	checkpoints we.CheckpointStore
+	name        string
+	progress    func(id we.AggregateId, copied int)
+}
+
+// Report summarises a migration or verification run.
+type Report struct {
+	Aggregates int
+	Events     int
+	// Deleted counts the hard deleted aggregates that were skipped
+	Deleted int
+}
+
+func (r Report) String() string {
+	return fmt.Sprintf("%d aggregates, %d events, %d deleted aggregates skipped", r.Aggregates, r.Events, r.Deleted)
+}
+
+// Run copies the aggregates in the order the source lists them, resuming after the saved checkpoint.
+func (m *Migrator) Run(ctx context.Context) (Report, error) {
+	var report Report
+
+	after := we.NoCheckpoint
+	if m.checkpoints != nil {
+		checkpoint, err := m.checkpoints.LoadCheckpoint(ctx, m.name)
+		if err != nil {
+			return report, fmt.Errorf("failed to load checkpoint: %w", err)
+		}
+		after = checkpoint
+	}
+
+	err := m.source.ListAggregates(ctx, after, func(ctx context.Context, id we.AggregateId, checkpoint we.Checkpoint) error {
+		copied, err := Copy(ctx, m.source, m.target, id)
+		if errors.Is(err, we.AggregateDeleted) {
+			report.Deleted++
+		} else if err != nil {
+			return fmt.Errorf("failed to copy %s: %w", id.Encode(), err)
+		} else {
+			report.Aggregates++
+			report.Events += copied
+		}
+
+		if m.progress != nil {
+			m.progress(id, copied)
+		}
+
+		if m.checkpoints != nil {
+			if err := m.checkpoints.SaveCheckpoint(ctx, m.name, checkpoint); err != nil {
+				return fmt.Errorf("failed to save checkpoint: %w", err)
+			}
+		}
+
+		return nil
+	})
+
+	return report, err
+}
+
+// Mismatch describes an aggregate whose events differ between the source and target.
+type Mismatch struct {
+	Id          we.AggregateId
+	SourceCount int
+	TargetCount int
+	SourceHash  string
+	TargetHash  string
+}
+
+func (m Mismatch) String() string {
+	if m.SourceCount != m.TargetCount {
+		return fmt.Sprintf("%s: %d events in the source, %d in the target", m.Id.Encode(), m.SourceCount, m.TargetCount)
+	}
+
+	return fmt.Sprintf("%s: source hash %s, target hash %s", m.Id.Encode(), m.SourceHash, m.TargetHash)
+}
+
+// Verify compares the event counts and hashes of every aggregate listed by the source with the target.
+func (m *Migrator) Verify(ctx context.Context) (Report, []Mismatch, error) {
+	var report Report
+	var mismatches []Mismatch
+
+	err := m.source.ListAggregates(ctx, we.NoCheckpoint, func(ctx context.Context, id we.AggregateId, _ we.Checkpoint) error {
+		from, err := m.source.Load(ctx, id)
+		if errors.Is(err, we.AggregateDeleted) {
+			report.Deleted++
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		to, err := m.target.Load(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		report.Aggregates++
+		report.Events += len(from.Events)
+
+		mismatch := Mismatch{
+			Id:          id,
+			SourceCount: len(from.Events),
+			TargetCount: len(to.Events),
+			SourceHash:  Hash(from.Events),
+			TargetHash:  Hash(to.Events),
+		}
+
+		if mismatch.SourceCount != mismatch.TargetCount || mismatch.SourceHash != mismatch.TargetHash {
+			mismatches = append(mismatches, mismatch)
+		}
+
+		return nil
+	})
+
+	return report, mismatches, err
+}
@@ -0,0 +1,321 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/weegigs/wee-events-go/we"
+)
+
+type Tested struct {
+	Value string `json:"value"`
+}
+
+func (Tested) EventType() we.EventType {
+	return "test:tested"
+}
+
+// memoryStore lists aggregates in the order they were created, with their index as the checkpoint
+type memoryStore struct {
+	lk         sync.Mutex
+	aggregates []we.AggregateId
+	events     map[we.AggregateId][]we.RecordedEvent
+	deleted    map[we.AggregateId]bool
+	sequence   uint64
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{events: map[we.AggregateId][]we.RecordedEvent{}, deleted: map[we.AggregateId]bool{}}
+}
+
+func (s *memoryStore) Load(_ context.Context, id we.AggregateId) (we.Aggregate, error) {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	if s.deleted[id] {
+		return we.Aggregate{}, we.AggregateDeleted
+	}
+
+	events := append([]we.RecordedEvent(nil), s.events[id]...)
+	revision := we.InitialRevision
+	if len(events) > 0 {
+		revision = events[len(events)-1].Revision
+	}
+
+	return we.Aggregate{Id: id, Events: events, Revision: revision}, nil
+}
+
+func (s *memoryStore) Publish(ctx context.Context, id we.AggregateId, options we.PublishOptions, events ...we.DomainEvent) error {
+	recorded := make([]we.RecordedEvent, len(events))
+	for i, event := range events {
+		data, err := we.MarshalToData(event)
+		if err != nil {
+			return err
+		}
+
+		recorded[i] = we.RecordedEvent{
+			EventID:   we.EventID(ulid.Make().String()),
+			EventType: we.EventTypeOf(event),
+			Timestamp: we.TimestampFromTime(time.Now()),
+			Metadata:  options.RecordedEventMetadata,
+			Data:      data,
+		}
+	}
+
+	_, err := s.Append(ctx, id, options.ExpectedRevision, recorded)
+	return err
+}
+
+func (s *memoryStore) Append(_ context.Context, id we.AggregateId, expected we.Revision, events []we.RecordedEvent) (we.Revision, error) {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	existing, ok := s.events[id]
+	if !ok {
+		s.aggregates = append(s.aggregates, id)
+	}
+
+	current := we.InitialRevision
+	if len(existing) > 0 {
+		current = existing[len(existing)-1].Revision
+	}
+	if expected != "" && expected != current {
+		return "", we.RevisionConflict
+	}
+
+	for _, event := range events {
+		s.sequence++
+		event.AggregateId = id
+		event.Revision = we.Revision(fmt.Sprintf("%020d", s.sequence))
+		existing = append(existing, event)
+	}
+	s.events[id] = existing
+
+	return existing[len(existing)-1].Revision, nil
+}
+
+func (s *memoryStore) ListAggregates(ctx context.Context, after we.Checkpoint, handler we.AggregateListHandler) error {
+	s.lk.Lock()
+	aggregates := append([]we.AggregateId(nil), s.aggregates...)
+	s.lk.Unlock()
+
+	start := 0
+	if after != we.NoCheckpoint {
+		index, err := strconv.Atoi(after.String())
+		if err != nil {
+			return err
+		}
+		start = index + 1
+	}
+
+	for i := start; i < len(aggregates); i++ {
+		if err := handler(ctx, aggregates[i], we.Checkpoint(strconv.Itoa(i))); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func createId() we.AggregateId {
+	return we.AggregateId{Type: "test", Key: ulid.Make().String()}
+}
+
+func populate(t *testing.T, store *memoryStore, aggregates int) []we.AggregateId {
+	ctx := context.Background()
+
+	ids := make([]we.AggregateId, aggregates)
+	for i := range ids {
+		ids[i] = createId()
+		for j := 0; j <= i; j++ {
+			options := we.Options(we.WithCorrelationId(we.CorrelationID(fmt.Sprintf("correlation-%d", j))))
+			require.NoError(t, store.Publish(ctx, ids[i], options, Tested{fmt.Sprintf("event %d", j)}))
+		}
+	}
+
+	return ids
+}
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("copies aggregates preserving their events", func(t *testing.T) {
+		source, target := newMemoryStore(), newMemoryStore()
+		ids := populate(t, source, 5)
+
+		migrator := NewMigrator(source, target)
+		report, err := migrator.Run(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, Report{Aggregates: 5, Events: 15}, report)
+
+		for _, id := range ids {
+			from, err := source.Load(ctx, id)
+			require.NoError(t, err)
+			to, err := target.Load(ctx, id)
+			require.NoError(t, err)
+
+			require.Equal(t, len(from.Events), len(to.Events))
+			for i := range from.Events {
+				assert.Equal(t, from.Events[i].EventID, to.Events[i].EventID)
+				assert.Equal(t, from.Events[i].EventType, to.Events[i].EventType)
+				assert.Equal(t, from.Events[i].Timestamp, to.Events[i].Timestamp)
+				assert.Equal(t, from.Events[i].Metadata, to.Events[i].Metadata)
+				assert.Equal(t, from.Events[i].Data, to.Events[i].Data)
+			}
+		}
+
+		report, mismatches, err := migrator.Verify(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, mismatches)
+		assert.Equal(t, 15, report.Events)
+	})
+
+	t.Run("resumes from the checkpoint", func(t *testing.T) {
+		source, target := newMemoryStore(), newMemoryStore()
+		populate(t, source, 4)
+		checkpoints := NewFileCheckpointStore(filepath.Join(t.TempDir(), "checkpoints.json"))
+
+		interrupted := errors.New("interrupted")
+		copied := 0
+		stopping := NewMigrator(source, target, WithCheckpoints(checkpoints, "test"), WithProgress(func(we.AggregateId, int) {
+			copied++
+			if copied == 2 {
+				panic(interrupted)
+			}
+		}))
+
+		func() {
+			defer func() { assert.Equal(t, interrupted, recover()) }()
+			_, _ = stopping.Run(ctx)
+		}()
+
+		// the panic stops the second aggregate being checkpointed, so it's listed again and copied idempotently
+		checkpoint, err := checkpoints.LoadCheckpoint(ctx, "test")
+		require.NoError(t, err)
+		assert.Equal(t, we.Checkpoint("0"), checkpoint)
+
+		report, err := NewMigrator(source, target, WithCheckpoints(checkpoints, "test")).Run(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, Report{Aggregates: 3, Events: 7}, report)
+
+		_, mismatches, err := NewMigrator(source, target).Verify(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, mismatches)
+	})
+
+	t.Run("skips deleted aggregates", func(t *testing.T) {
+		source, target := newMemoryStore(), newMemoryStore()
+		ids := populate(t, source, 3)
+		source.deleted[ids[1]] = true
+
+		report, err := NewMigrator(source, target).Run(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, Report{Aggregates: 2, Events: 4, Deleted: 1}, report)
+	})
+
+	t.Run("verification reports mismatches", func(t *testing.T) {
+		source, target := newMemoryStore(), newMemoryStore()
+		ids := populate(t, source, 3)
+
+		migrator := NewMigrator(source, target)
+		_, err := migrator.Run(ctx)
+		require.NoError(t, err)
+
+		require.NoError(t, source.Publish(ctx, ids[0], we.Options(), Tested{"missing"}))
+		target.events[ids[2]][1].Data.Data = []byte(`{"value":"changed"}`)
+
+		_, mismatches, err := migrator.Verify(ctx)
+		require.NoError(t, err)
+		require.Len(t, mismatches, 2)
+		assert.Equal(t, ids[0], mismatches[0].Id)
+		assert.Equal(t, 2, mismatches[0].SourceCount)
+		assert.Equal(t, 1, mismatches[0].TargetCount)
+		assert.Equal(t, ids[2], mismatches[1].Id)
+		assert.NotEqual(t, mismatches[1].SourceHash, mismatches[1].TargetHash)
+	})
+
+	t.Run("diverged aggregates aren't copied", func(t *testing.T) {
+		source, target := newMemoryStore(), newMemoryStore()
+		id := populate(t, source, 1)[0]
+		require.NoError(t, target.Publish(ctx, id, we.Options(), Tested{"elsewhere"}))
+
+		_, err := Copy(ctx, source, target, id)
+		assert.ErrorIs(t, err, Diverged)
+	})
+}
+
+func TestHash(t *testing.T) {
+	event := we.RecordedEvent{
+		EventID:   "id",
+		EventType: "test:tested",
+		Timestamp: "2023-04-01T00:00:00Z",
+		Data:      we.Data{Encoding: "application/json", Data: []byte(`{ "value": "one" }`)},
+	}
+
+	compacted := event
+	compacted.Data.Data = []byte(`{"value":"one"}`)
+	assert.Equal(t, Hash([]we.RecordedEvent{event}), Hash([]we.RecordedEvent{compacted}))
+
+	revised := event
+	revised.Revision = "other"
+	assert.Equal(t, Hash([]we.RecordedEvent{event}), Hash([]we.RecordedEvent{revised}))
+
+	retimed := event
+	retimed.Timestamp = "2023-04-01T00:00:01Z"
+	assert.NotEqual(t, Hash([]we.RecordedEvent{event}), Hash([]we.RecordedEvent{retimed}))
+
+	extended := event
+	extended.Metadata.Extensions = we.Extensions{"example.com/key": "value"}
+	assert.NotEqual(t, Hash([]we.RecordedEvent{event}), Hash([]we.RecordedEvent{extended}))
+}
+
+func TestTee(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("copies published events to the secondary", func(t *testing.T) {
+		primary, secondary := newMemoryStore(), newMemoryStore()
+		tee := NewTee(primary, secondary)
+		id := createId()
+
+		require.NoError(t, tee.Publish(ctx, id, we.Options(), Tested{"one"}, Tested{"two"}))
+		revision, err := tee.PublishRevision(ctx, id, we.Options(), Tested{"three"})
+		require.NoError(t, err)
+
+		loaded, err := tee.Load(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, revision, loaded.Revision)
+
+		copied, err := secondary.Load(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, Hash(loaded.Events), Hash(copied.Events))
+	})
+
+	t.Run("secondary failures are reported to the handler", func(t *testing.T) {
+		primary, secondary := newMemoryStore(), newMemoryStore()
+		id := createId()
+		require.NoError(t, secondary.Publish(ctx, id, we.Options(), Tested{"elsewhere"}))
+
+		var failed error
+		tee := NewTee(primary, secondary, WithSecondaryFailures(func(ctx context.Context, id we.AggregateId, err error) error {
+			failed = err
+			return nil
+		}))
+
+		require.NoError(t, tee.Publish(ctx, id, we.Options(), Tested{"one"}))
+		assert.ErrorIs(t, failed, Diverged)
+
+		loaded, err := primary.Load(ctx, id)
+		require.NoError(t, err)
+		assert.Len(t, loaded.Events, 1)
+	})
+}
@@ -0,0 +1,69 @@
+package migrate
+
+import (
+	"context"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/weegigs/wee-events-go/we"
+)
+
+// Tee is an event store that publishes to a primary store and copies the published events to a secondary store,
+// keeping both in sync during a cutover. Loads are served by the primary.
+type Tee struct {
+	primary   we.EventStore
+	secondary Target
+	failed    func(ctx context.Context, id we.AggregateId, err error) error
+}
+
+type TeeOption func(*Tee)
+
+// WithSecondaryFailures sets the function called when copying to the secondary fails. The error it returns is
+// returned by the publish, which has already succeeded on the primary. By default failures are logged and the
+// aggregate is brought into line by its next publish or by a migration.
+func WithSecondaryFailures(failed func(ctx context.Context, id we.AggregateId, err error) error) TeeOption {
+	return func(t *Tee) {
+		t.failed = failed
+	}
+}
+
+func NewTee(primary we.EventStore, secondary Target, options ...TeeOption) *Tee {
+	tee := &Tee{primary: primary, secondary: secondary, failed: logFailure}
+
+	for _, option := range options {
+		option(tee)
+	}
+
+	return tee
+}
+
+func logFailure(_ context.Context, id we.AggregateId, err error) error {
+	log.WithError(err).WithField("aggregate", id.Encode().String()).Info("failed to copy events to the secondary store")
+	return nil
+}
+
+func (t *Tee) Load(ctx context.Context, id we.AggregateId) (we.Aggregate, error) {
+	return t.primary.Load(ctx, id)
+}
+
+func (t *Tee) Publish(ctx context.Context, id we.AggregateId, options we.PublishOptions, events ...we.DomainEvent) error {
+	_, err := t.PublishRevision(ctx, id, options, events...)
+	return err
+}
+
+// PublishRevision publishes to the primary, returning the primary's revision, then copies the aggregate's missing
+// events to the secondary.
+func (t *Tee) PublishRevision(ctx context.Context, id we.AggregateId, options we.PublishOptions, events ...we.DomainEvent) (we.Revision, error) {
+	revision, err := we.PublishRevision(ctx, t.primary, id, options, events...)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := Copy(ctx, t.primary, t.secondary, id); err != nil {
+		if failure := t.failed(ctx, id, err); failure != nil {
+			return revision, failure
+		}
+	}
+
+	return revision, nil
+}
@@ -0,0 +1,152 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/weegigs/wee-events-go/we"
+)
+
+// Slot is one of the two copies of a read model kept for blue/green rebuilds.
+type Slot string
+
+const (
+	Blue  Slot = "blue"
+	Green Slot = "green"
+)
+
+// Other returns the slot that isn't this one.
+func (s Slot) Other() Slot {
+	if s == Green {
+		return Blue
+	}
+
+	return Green
+}
+
+// ReadModel is a projection kept in two slots, so it can be rebuilt in one while the other serves reads.
+type ReadModel interface {
+	// Reset clears the slot before it is rebuilt.
+	Reset(ctx context.Context, slot Slot) error
+	// Handler returns the handler projecting events into the slot.
+	Handler(slot Slot) we.EventHandler
+}
+
+// SlotSwitch records which slot of a read model is live under the name in a checkpoint store. Blue is live until
+// another slot is activated.
+type SlotSwitch struct {
+	store we.CheckpointStore
+	name  string
+}
+
+func NewSlotSwitch(store we.CheckpointStore, name string) *SlotSwitch {
+	return &SlotSwitch{store: store, name: name}
+}
+
+func (s *SlotSwitch) Live(ctx context.Context) (Slot, error) {
+	checkpoint, err := s.store.LoadCheckpoint(ctx, s.name)
+	if err != nil {
+		return "", err
+	}
+
+	switch slot := Slot(checkpoint); slot {
+	case "":
+		return Blue, nil
+	case Blue, Green:
+		return slot, nil
+	default:
+		return "", fmt.Errorf("unknown slot %q for %s", slot, s.name)
+	}
+}
+
+func (s *SlotSwitch) Activate(ctx context.Context, slot Slot) error {
+	return s.store.SaveCheckpoint(ctx, s.name, we.Checkpoint(slot))
+}
+
+// NoCatchUp is returned when rebuilding from a source that can't stream the events published since a checkpoint.
+var NoCatchUp = errors.New("source-does-not-catch-up")
+
+type RebuildOption func(*rebuild)
+
+type rebuild struct {
+	threshold int64
+	passes    int
+}
+
+// WithCatchUpThreshold sets how many events a catch up pass may handle for the rebuild to count as caught up,
+// none by default.
+func WithCatchUpThreshold(events int64) RebuildOption {
+	return func(r *rebuild) {
+		r.threshold = events
+	}
+}
+
+// WithMaxPasses limits the number of catch up passes made after the initial replay, failing the rebuild if it
+// hasn't caught up by then.
+func WithMaxPasses(passes int) RebuildOption {
+	return func(r *rebuild) {
+		r.passes = passes
+	}
+}
+
+// Rebuild resets the read model's standby slot and replays every event into it, making further passes for the
+// events published meanwhile until a pass handles no more than the catch up threshold, then makes the standby slot
+// live. The report's checkpoint is where the live projection should resume from. A dry run replays once and leaves
+// the slots alone.
+//
+// The passes only find the events published meanwhile if the replayer's source is a CatchUpSource that catches up,
+// otherwise the rebuild fails with NoCatchUp rather than making an incomplete read model live.
+func (r *Replayer) Rebuild(ctx context.Context, model ReadModel, slots *SlotSwitch, options ...RebuildOption) (Report, error) {
+	config := rebuild{passes: 10}
+	for _, option := range options {
+		option(&config)
+	}
+
+	live, err := slots.Live(ctx)
+	if err != nil {
+		return Report{}, err
+	}
+	standby := live.Other()
+
+	if r.dryRun {
+		return r.Replay(ctx, we.NoCheckpoint, model.Handler(standby))
+	}
+
+	if !catchesUp(r.source) {
+		return Report{}, NoCatchUp
+	}
+
+	if err := model.Reset(ctx, standby); err != nil {
+		return Report{}, fmt.Errorf("failed to reset %s: %w", standby, err)
+	}
+
+	report, err := r.Replay(ctx, we.NoCheckpoint, model.Handler(standby))
+	if err != nil {
+		return report, err
+	}
+
+	for pass := 0; ; pass++ {
+		if pass == config.passes {
+			return report, fmt.Errorf("%s didn't catch up after %d passes", standby, pass)
+		}
+
+		caughtUp, err := r.Replay(ctx, report.Checkpoint, model.Handler(standby))
+		report.Events += caughtUp.Events
+		report.Elapsed += caughtUp.Elapsed
+		report.Checkpoint = caughtUp.Checkpoint
+		if err != nil {
+			return report, err
+		}
+
+		if caughtUp.Events <= config.threshold {
+			break
+		}
+	}
+
+	if err := slots.Activate(ctx, standby); err != nil {
+		return report, fmt.Errorf("failed to activate %s: %w", standby, err)
+	}
+
+	return report, nil
+}
@@ -0,0 +1,227 @@
+// Package replay streams the events of a store into projection handlers, to rebuild read models from history.
+package replay
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/weegigs/wee-events-go/we"
+)
+
+type ReplayerOption func(*Replayer)
+
+// WithParallelism handles events with the number of workers, partitioning events by aggregate so each aggregate's
+// events are still handled in order.
+func WithParallelism(workers int) ReplayerOption {
+	return func(r *Replayer) {
+		if workers > 0 {
+			r.workers = workers
+		}
+	}
+}
+
+// WithDryRun streams and counts the events without handling them.
+func WithDryRun() ReplayerOption {
+	return func(r *Replayer) {
+		r.dryRun = true
+	}
+}
+
+// WithRateLimit limits the number of events streamed per second.
+func WithRateLimit(eventsPerSecond float64) ReplayerOption {
+	return func(r *Replayer) {
+		burst := int(eventsPerSecond / 10)
+		if burst < 1 {
+			burst = 1
+		}
+
+		r.limiter = rate.NewLimiter(rate.Limit(eventsPerSecond), burst)
+	}
+}
+
+// WithProgress calls the function at the interval while replaying, and once more when the replay ends.
+func WithProgress(interval time.Duration, progress func(Progress)) ReplayerOption {
+	return func(r *Replayer) {
+		r.interval = interval
+		r.progress = progress
+	}
+}
+
+// NewReplayer creates a replayer streaming events from the source, handling them one at a time by default.
+func NewReplayer(source Source, options ...ReplayerOption) *Replayer {
+	r := &Replayer{source: source, workers: 1}
+
+	for _, option := range options {
+		option(r)
+	}
+
+	return r
+}
+
+type Replayer struct {
+	source   Source
+	workers  int
+	dryRun   bool
+	limiter  *rate.Limiter
+	interval time.Duration
+	progress func(Progress)
+}
+
+// Progress reports how far a replay has got.
+type Progress struct {
+	Streamed int64
+	Handled  int64
+	Elapsed  time.Duration
+}
+
+// Rate returns the number of events handled per second.
+func (p Progress) Rate() float64 {
+	if p.Elapsed <= 0 {
+		return 0
+	}
+
+	return float64(p.Handled) / p.Elapsed.Seconds()
+}
+
+func (p Progress) String() string {
+	return fmt.Sprintf("%d events streamed, %d handled in %s (%.0f/s)", p.Streamed, p.Handled, p.Elapsed.Round(time.Millisecond), p.Rate())
+}
+
+// Report summarises a replay. The checkpoint is the source checkpoint of the last event handled, or the checkpoint
+// the replay started after when there were no events.
+type Report struct {
+	Events     int64
+	Checkpoint we.Checkpoint
+	Elapsed    time.Duration
+}
+
+func (r Report) String() string {
+	return fmt.Sprintf("%d events in %s", r.Events, r.Elapsed.Round(time.Millisecond))
+}
+
+// Replay streams the events after the checkpoint into the handler, returning when every event has been handled or
+// the handler fails. The report's checkpoint is only returned once every event before it has been handled.
+func (r *Replayer) Replay(ctx context.Context, after we.Checkpoint, handler we.EventHandler) (Report, error) {
+	started := time.Now()
+	var streamed, handled atomic.Int64
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var failure error
+	var once sync.Once
+	fail := func(err error) {
+		once.Do(func() {
+			failure = err
+			cancel()
+		})
+	}
+
+	var workers sync.WaitGroup
+	queues := make([]chan we.RecordedEvent, r.workers)
+	for i := range queues {
+		queues[i] = make(chan we.RecordedEvent, 64)
+		workers.Add(1)
+		go func(queue chan we.RecordedEvent) {
+			defer workers.Done()
+			for event := range queue {
+				if ctx.Err() != nil {
+					continue
+				}
+
+				if err := handler(ctx, event); err != nil {
+					fail(fmt.Errorf("failed to handle %s event %s: %w", event.AggregateId.Encode(), event.EventID, err))
+					continue
+				}
+				handled.Add(1)
+			}
+		}(queues[i])
+	}
+
+	progress := func() Progress {
+		return Progress{Streamed: streamed.Load(), Handled: handled.Load(), Elapsed: time.Since(started)}
+	}
+
+	var reporter sync.WaitGroup
+	stop := make(chan struct{})
+	if r.progress != nil && r.interval > 0 {
+		reporter.Add(1)
+		go func() {
+			defer reporter.Done()
+			ticker := time.NewTicker(r.interval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-stop:
+					return
+				case <-ticker.C:
+					r.progress(progress())
+				}
+			}
+		}()
+	}
+
+	checkpoint := after
+	err := r.source.Stream(ctx, after, func(ctx context.Context, event we.RecordedEvent, next we.Checkpoint) error {
+		if r.limiter != nil {
+			if err := r.limiter.Wait(ctx); err != nil {
+				return err
+			}
+		}
+
+		streamed.Add(1)
+		checkpoint = next
+
+		if r.dryRun {
+			handled.Add(1)
+			return nil
+		}
+
+		select {
+		case queues[partition(event.AggregateId, r.workers)] <- event:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+
+	for _, queue := range queues {
+		close(queue)
+	}
+	workers.Wait()
+
+	close(stop)
+	reporter.Wait()
+
+	if r.progress != nil {
+		r.progress(progress())
+	}
+
+	if failure != nil {
+		err = failure
+	}
+
+	if err != nil {
+		return Report{Events: handled.Load(), Checkpoint: after, Elapsed: time.Since(started)}, err
+	}
+
+	return Report{Events: handled.Load(), Checkpoint: checkpoint, Elapsed: time.Since(started)}, nil
+}
+
+// partition assigns an aggregate to one of the workers.
+func partition(id we.AggregateId, workers int) int {
+	if workers == 1 {
+		return 0
+	}
+
+	hash := fnv.New32a()
+	hash.Write([]byte(id.Encode()))
+	return int(hash.Sum32() % uint32(workers))
+}
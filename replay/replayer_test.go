@@ -0,0 +1,294 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/weegigs/wee-events-go/stores/file"
+	"github.com/weegigs/wee-events-go/we"
+)
+
+type Counted struct {
+	Count int `json:"count"`
+}
+
+func (Counted) EventType() we.EventType {
+	return "test:counted"
+}
+
+func openStore(t *testing.T) *file.FileEventStore {
+	store, err := file.NewEventStore(t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = store.Close() })
+
+	return store
+}
+
+// publish adds count events to each aggregate, interleaving the aggregates
+func publish(t *testing.T, store we.EventStore, ids []we.AggregateId, count int) {
+	for i := 0; i < count; i++ {
+		for _, id := range ids {
+			require.NoError(t, store.Publish(context.Background(), id, we.Options(), Counted{Count: i}))
+		}
+	}
+}
+
+func aggregates(count int) []we.AggregateId {
+	ids := make([]we.AggregateId, count)
+	for i := range ids {
+		ids[i] = we.AggregateId{Type: "counter", Key: ulid.Make().String()}
+	}
+
+	return ids
+}
+
+// projection records the order events were handled in for each aggregate
+type projection struct {
+	lk     sync.Mutex
+	events map[we.AggregateId][]we.EventID
+}
+
+func newProjection() *projection {
+	return &projection{events: map[we.AggregateId][]we.EventID{}}
+}
+
+func (p *projection) handle(_ context.Context, event we.RecordedEvent) error {
+	p.lk.Lock()
+	defer p.lk.Unlock()
+
+	p.events[event.AggregateId] = append(p.events[event.AggregateId], event.EventID)
+	return nil
+}
+
+func (p *projection) count() int {
+	p.lk.Lock()
+	defer p.lk.Unlock()
+
+	count := 0
+	for _, events := range p.events {
+		count += len(events)
+	}
+
+	return count
+}
+
+func assertProjected(t *testing.T, store we.EventStore, p *projection, ids []we.AggregateId) {
+	for _, id := range ids {
+		aggregate, err := store.Load(context.Background(), id)
+		require.NoError(t, err)
+
+		expected := make([]we.EventID, len(aggregate.Events))
+		for i, event := range aggregate.Events {
+			expected[i] = event.EventID
+		}
+
+		assert.Equal(t, expected, p.events[id], id.Encode())
+	}
+}
+
+func TestReplay(t *testing.T) {
+	ctx := context.Background()
+	store := openStore(t)
+	ids := aggregates(8)
+	publish(t, store, ids, 5)
+	source := PositionSource(store.ReadAll)
+
+	t.Run("keeps each aggregate's events in order across workers", func(t *testing.T) {
+		p := newProjection()
+		report, err := NewReplayer(source, WithParallelism(4)).Replay(ctx, we.NoCheckpoint, p.handle)
+		require.NoError(t, err)
+
+		assert.Equal(t, int64(40), report.Events)
+		assertProjected(t, store, p, ids)
+	})
+
+	t.Run("resumes after the checkpoint", func(t *testing.T) {
+		replayer := NewReplayer(source)
+		report, err := replayer.Replay(ctx, we.NoCheckpoint, newProjection().handle)
+		require.NoError(t, err)
+
+		publish(t, store, ids[:2], 1)
+
+		p := newProjection()
+		resumed, err := replayer.Replay(ctx, report.Checkpoint, p.handle)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), resumed.Events)
+		assert.Len(t, p.events, 2)
+
+		again, err := replayer.Replay(ctx, resumed.Checkpoint, p.handle)
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), again.Events)
+		assert.Equal(t, resumed.Checkpoint, again.Checkpoint)
+	})
+
+	t.Run("dry runs don't handle events", func(t *testing.T) {
+		p := newProjection()
+		report, err := NewReplayer(source, WithDryRun()).Replay(ctx, we.NoCheckpoint, p.handle)
+		require.NoError(t, err)
+
+		assert.Equal(t, int64(42), report.Events)
+		assert.Zero(t, p.count())
+	})
+
+	t.Run("stops when the handler fails", func(t *testing.T) {
+		failure := errors.New("failed")
+		handler := func(context.Context, we.RecordedEvent) error { return failure }
+
+		report, err := NewReplayer(source, WithParallelism(4)).Replay(ctx, we.SequencePosition(3, 0).Checkpoint(), handler)
+		assert.ErrorIs(t, err, failure)
+		assert.Equal(t, we.SequencePosition(3, 0).Checkpoint(), report.Checkpoint, "the checkpoint doesn't move")
+	})
+
+	t.Run("reports progress", func(t *testing.T) {
+		var reports []Progress
+		replayer := NewReplayer(source, WithProgress(time.Hour, func(progress Progress) {
+			reports = append(reports, progress)
+		}))
+
+		_, err := replayer.Replay(ctx, we.NoCheckpoint, newProjection().handle)
+		require.NoError(t, err)
+
+		require.Len(t, reports, 1, "progress is reported when the replay ends")
+		assert.Equal(t, int64(42), reports[0].Streamed)
+		assert.Equal(t, int64(42), reports[0].Handled)
+	})
+
+	t.Run("limits the rate", func(t *testing.T) {
+		started := time.Now()
+		_, err := NewReplayer(source, WithRateLimit(50)).Replay(ctx, we.SequencePosition(32, 0).Checkpoint(), newProjection().handle)
+		require.NoError(t, err)
+
+		assert.GreaterOrEqual(t, time.Since(started), 80*time.Millisecond, "10 events at 50/s after a burst of 5")
+	})
+}
+
+// listingStore lists the aggregates it was given
+type listingStore struct {
+	*file.FileEventStore
+	ids []we.AggregateId
+}
+
+func (s *listingStore) ListAggregates(ctx context.Context, after we.Checkpoint, handler we.AggregateListHandler) error {
+	start := 0
+	if after != we.NoCheckpoint {
+		index, err := strconv.Atoi(after.String())
+		if err != nil {
+			return err
+		}
+		start = index + 1
+	}
+
+	for i := start; i < len(s.ids); i++ {
+		if err := handler(ctx, s.ids[i], we.Checkpoint(strconv.Itoa(i))); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func TestAggregateSource(t *testing.T) {
+	ctx := context.Background()
+	store := &listingStore{FileEventStore: openStore(t), ids: aggregates(3)}
+	publish(t, store, store.ids, 2)
+
+	source := AggregateSource(store)
+	p := newProjection()
+	report, err := NewReplayer(source, WithParallelism(2)).Replay(ctx, we.NoCheckpoint, p.handle)
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), report.Events)
+	assertProjected(t, store, p, store.ids)
+
+	publish(t, store, store.ids[2:], 1)
+
+	resumed, err := NewReplayer(source).Replay(ctx, "1", p.handle)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), resumed.Events, "only events published since are streamed")
+	assertProjected(t, store, p, store.ids)
+}
+
+// slotted keeps a projection per slot, publishing an event the first time the green slot handles one so the rebuild
+// has to catch up
+type slotted struct {
+	store   we.EventStore
+	id      we.AggregateId
+	publish sync.Once
+	slots   map[Slot]*projection
+}
+
+func (m *slotted) Reset(_ context.Context, slot Slot) error {
+	m.slots[slot] = newProjection()
+	return nil
+}
+
+func (m *slotted) Handler(slot Slot) we.EventHandler {
+	return func(ctx context.Context, event we.RecordedEvent) error {
+		var err error
+		if slot == Green {
+			m.publish.Do(func() {
+				err = m.store.Publish(ctx, m.id, we.Options(), Counted{Count: 99})
+			})
+		}
+		if err != nil {
+			return err
+		}
+
+		return m.slots[slot].handle(ctx, event)
+	}
+}
+
+func TestRebuild(t *testing.T) {
+	ctx := context.Background()
+	store := openStore(t)
+	ids := aggregates(4)
+	publish(t, store, ids, 3)
+
+	blue := newProjection()
+	model := &slotted{store: store, id: ids[0], slots: map[Slot]*projection{Blue: blue}}
+	slots := NewSlotSwitch(we.NewMemoryCheckpointStore(), "counters")
+	replayer := NewReplayer(PositionSource(store.ReadAll), WithParallelism(2))
+
+	t.Run("dry runs leave the slots alone", func(t *testing.T) {
+		dryRun := NewReplayer(PositionSource(store.ReadAll), WithDryRun())
+		report, err := dryRun.Rebuild(ctx, model, slots)
+		require.NoError(t, err)
+		assert.Equal(t, int64(12), report.Events)
+
+		live, err := slots.Live(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, Blue, live)
+		assert.NotContains(t, model.slots, Green)
+	})
+
+	t.Run("refuses sources that can't catch up", func(t *testing.T) {
+		listing := &listingStore{FileEventStore: store, ids: ids}
+		_, err := NewReplayer(AggregateSource(listing)).Rebuild(ctx, model, slots)
+		assert.ErrorIs(t, err, NoCatchUp)
+
+		live, err := slots.Live(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, Blue, live)
+		assert.NotContains(t, model.slots, Green)
+	})
+
+	t.Run("switches once caught up", func(t *testing.T) {
+		report, err := replayer.Rebuild(ctx, model, slots)
+		require.NoError(t, err)
+		assert.Equal(t, int64(13), report.Events, "includes the event published during the rebuild")
+
+		live, err := slots.Live(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, Green, live)
+
+		assertProjected(t, store, model.slots[Green], ids)
+		assert.Zero(t, blue.count(), "the live slot isn't touched")
+	})
+}
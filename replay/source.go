@@ -0,0 +1,130 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/weegigs/wee-events-go/we"
+)
+
+// SourceHandler receives the events streamed by a source along with the checkpoint to resume after the event.
+type SourceHandler func(ctx context.Context, event we.RecordedEvent, checkpoint we.Checkpoint) error
+
+// Source streams the events of a store, keeping each aggregate's events in order, resuming after a checkpoint.
+type Source interface {
+	Stream(ctx context.Context, after we.Checkpoint, handler SourceHandler) error
+}
+
+// CatchUpSource is implemented by sources that can report whether streaming after a checkpoint delivers every event
+// published since the checkpoint was taken, which Rebuild relies on to catch a read model up.
+type CatchUpSource interface {
+	Source
+	CatchesUp() bool
+}
+
+// catchesUp reports whether the source is a CatchUpSource that catches up.
+func catchesUp(source Source) bool {
+	s, ok := source.(CatchUpSource)
+	return ok && s.CatchesUp()
+}
+
+type SourceFunc func(ctx context.Context, after we.Checkpoint, handler SourceHandler) error
+
+func (f SourceFunc) Stream(ctx context.Context, after we.Checkpoint, handler SourceHandler) error {
+	return f(ctx, after, handler)
+}
+
+// PositionSource streams the events of a store that reads every event in the order of their sequence positions, like
+// the file store, checkpointing the events' positions:
+//
+//	replay.PositionSource(store.ReadAll)
+//
+// Events published after a checkpoint follow it, so the source catches up.
+func PositionSource(readAll func(ctx context.Context, after uint64, handler we.EventHandler) error) Source {
+	return positionSource(readAll)
+}
+
+type positionSource func(ctx context.Context, after uint64, handler we.EventHandler) error
+
+func (readAll positionSource) Stream(ctx context.Context, after we.Checkpoint, handler SourceHandler) error {
+	var sequence uint64
+	if after != we.NoCheckpoint {
+		s, _, err := we.PositionOf(after).Sequence()
+		if err != nil {
+			return err
+		}
+		sequence = s
+	}
+
+	return readAll(ctx, sequence, func(ctx context.Context, event we.RecordedEvent) error {
+		return handler(ctx, event, event.Position.Checkpoint())
+	})
+}
+
+func (positionSource) CatchesUp() bool {
+	return true
+}
+
+// ListingStore is a store that can enumerate its aggregates.
+type ListingStore interface {
+	we.EventStore
+	we.AggregateLister
+}
+
+// AggregateSource streams the events of a store one aggregate at a time, in the order the store lists them. Hard
+// deleted aggregates are skipped.
+//
+// The source remembers how many events of each aggregate it has streamed, so streaming again after a checkpoint only
+// delivers events published since. It only catches up if the store is a we.ChangeLister that lists the aggregates
+// changed after the checkpoint, as the esbs store does. The listing checkpoints of other stores, like ds, resume an
+// interrupted replay but miss changes to aggregates listed before the checkpoint.
+func AggregateSource(store ListingStore) Source {
+	return &aggregateSource{store: store, streamed: map[we.AggregateId]int{}}
+}
+
+type aggregateSource struct {
+	store ListingStore
+
+	lk       sync.Mutex
+	streamed map[we.AggregateId]int
+}
+
+func (s *aggregateSource) CatchesUp() bool {
+	lister, ok := s.store.(we.ChangeLister)
+	return ok && lister.ListsChanges()
+}
+
+func (s *aggregateSource) Stream(ctx context.Context, after we.Checkpoint, handler SourceHandler) error {
+	return s.store.ListAggregates(ctx, after, func(ctx context.Context, id we.AggregateId, checkpoint we.Checkpoint) error {
+		aggregate, err := s.store.Load(ctx, id)
+		if errors.Is(err, we.AggregateDeleted) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to load %s: %w", id.Encode(), err)
+		}
+
+		s.lk.Lock()
+		streamed := s.streamed[id]
+		s.lk.Unlock()
+
+		// truncated aggregates have fewer events than were streamed
+		if streamed > len(aggregate.Events) {
+			streamed = len(aggregate.Events)
+		}
+
+		for _, event := range aggregate.Events[streamed:] {
+			if err := handler(ctx, event, checkpoint); err != nil {
+				return err
+			}
+		}
+
+		s.lk.Lock()
+		s.streamed[id] = len(aggregate.Events)
+		s.lk.Unlock()
+
+		return nil
+	})
+}
@@ -25,9 +25,10 @@ func live(ctx context.Context) (we.EntityService[counter.Counter], func(), error
 	if err != nil {
 		return nil, nil, err
 	}
-	dynamoEventStore := ds.NewEventStore(client, eventStoreTableName)
-	v := counter.PseudoRandomizer()
-	entityService := NewCounterService(dynamoEventStore, v)
+	v := ds.DefaultEventStoreOptions()
+	dynamoEventStore := ds.NewEventStore(client, eventStoreTableName, v...)
+	v2 := counter.PseudoRandomizer()
+	entityService := NewCounterService(dynamoEventStore, v2)
 	return entityService, func() {
 	}, nil
 }
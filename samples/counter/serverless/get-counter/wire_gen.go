@@ -25,9 +25,10 @@ func live(ctx context.Context) (func(ctx context.Context, event events.APIGatewa
 	if err != nil {
 		return nil, nil, err
 	}
-	dynamoEventStore := ds.NewEventStore(client, eventStoreTableName)
+	v := ds.DefaultEventStoreOptions()
+	dynamoEventStore := ds.NewEventStore(client, eventStoreTableName, v...)
 	entityLoader := counter.Loader(dynamoEventStore)
-	v := createHandler(entityLoader)
-	return v, func() {
+	v2 := createHandler(entityLoader)
+	return v2, func() {
 	}, nil
 }
@@ -0,0 +1,56 @@
+package connect
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/weegigs/wee-events-go/stores/file"
+	rediss "github.com/weegigs/wee-events-go/stores/redis"
+	sqls "github.com/weegigs/wee-events-go/stores/sql"
+)
+
+func TestOpen(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	t.Run("opens sqlite databases", func(t *testing.T) {
+		store, err := Open(ctx, "sqlite://"+filepath.Join(dir, "events.db"))
+		require.NoError(t, err)
+		defer store.Close()
+
+		assert.IsType(t, &sqls.SQLEventStore{}, store.EventStore)
+	})
+
+	t.Run("opens file logs", func(t *testing.T) {
+		store, err := Open(ctx, "file://"+filepath.Join(dir, "log"))
+		require.NoError(t, err)
+		defer store.Close()
+
+		assert.IsType(t, &file.FileEventStore{}, store.EventStore)
+	})
+
+	t.Run("opens redis", func(t *testing.T) {
+		server := miniredis.RunT(t)
+
+		store, err := Open(ctx, "redis://"+server.Addr()+"/0?prefix=events:")
+		require.NoError(t, err)
+		defer store.Close()
+
+		assert.IsType(t, &rediss.RedisEventStore{}, store.EventStore)
+	})
+
+	t.Run("rejects unknown schemes", func(t *testing.T) {
+		_, err := Open(ctx, "mysql://localhost/events")
+		assert.Error(t, err)
+	})
+
+	t.Run("requires a dynamodb table", func(t *testing.T) {
+		_, err := Open(ctx, "dynamodb://")
+		assert.Error(t, err)
+	})
+}
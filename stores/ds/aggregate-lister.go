@@ -0,0 +1,168 @@
+package ds
+
+import (
+  "context"
+  "strings"
+
+  "github.com/aws/aws-sdk-go-v2/aws"
+  "github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+  "github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+  "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+  "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+  "github.com/pkg/errors"
+
+  "github.com/weegigs/wee-events-go/we"
+)
+
+// ListAggregates scans the aggregate type index, which only holds the latest revision records, for the aggregates of
+// the tenant resolved from the context, or of aggregates without a tenant when there isn't one. Indexed aggregate
+// types are prefixed like partition keys and neither tenants nor aggregate types contain the separator, so the prefix
+// identifies the tenant. Each checkpoint is the aggregate's partition key, which the scan resumes after.
+func (ds *DynamoEventStore) ListAggregates(ctx context.Context, after we.Checkpoint, handler we.AggregateListHandler) error {
+  tenant, err := ds.tenant(ctx)
+  if err != nil {
+    return err
+  }
+
+  indexed := expression.Name(aggregateTypeAttribute)
+  var filter expression.ConditionBuilder
+  if tenant == we.NoTenant {
+    filter = expression.Not(expression.Contains(indexed, tenantSeparator))
+  } else {
+    filter = expression.BeginsWith(indexed, tenanted(tenant, ""))
+  }
+
+  expr, err := expression.NewBuilder().WithFilter(filter).Build()
+  if err != nil {
+    return err
+  }
+
+  return ds.listIndex(ctx, tenant, after, handler, func(start map[string]types.AttributeValue) (*page, error) {
+    out, err := ds.db.Scan(ctx, &dynamodb.ScanInput{
+      TableName:                 aws.String(ds.table),
+      IndexName:                 aws.String(AggregateTypeIndex),
+      ExclusiveStartKey:         start,
+      FilterExpression:          expr.Filter(),
+      ExpressionAttributeNames:  expr.Names(),
+      ExpressionAttributeValues: expr.Values(),
+    })
+    if err != nil {
+      return nil, errors.Wrap(err, "failed to scan aggregates")
+    }
+
+    return &page{items: out.Items, last: out.LastEvaluatedKey}, nil
+  })
+}
+
+// ListAggregatesOfType queries the aggregate type index for the aggregates of the type within the tenant resolved
+// from the context.
+func (ds *DynamoEventStore) ListAggregatesOfType(ctx context.Context, aggregateType string, after we.Checkpoint, handler we.AggregateListHandler) error {
+  tenant, err := ds.tenant(ctx)
+  if err != nil {
+    return err
+  }
+
+  if strings.Contains(aggregateType, tenantSeparator) {
+    return InvalidAggregateType
+  }
+
+  expr, err := expression.NewBuilder().
+    WithKeyCondition(expression.Key(aggregateTypeAttribute).Equal(expression.Value(tenanted(tenant, aggregateType)))).
+    Build()
+  if err != nil {
+    return err
+  }
+
+  return ds.listIndex(ctx, tenant, after, handler, func(start map[string]types.AttributeValue) (*page, error) {
+    out, err := ds.db.Query(ctx, &dynamodb.QueryInput{
+      TableName:                 aws.String(ds.table),
+      IndexName:                 aws.String(AggregateTypeIndex),
+      ExclusiveStartKey:         start,
+      KeyConditionExpression:    expr.KeyCondition(),
+      ExpressionAttributeNames:  expr.Names(),
+      ExpressionAttributeValues: expr.Values(),
+    })
+    if err != nil {
+      return nil, errors.Wrap(err, "failed to query aggregates")
+    }
+
+    return &page{items: out.Items, last: out.LastEvaluatedKey}, nil
+  })
+}
+
+type page struct {
+  items []map[string]types.AttributeValue
+  last  map[string]types.AttributeValue
+}
+
+// listIndex reads pages of the aggregate type index, starting after the checkpoint, until the last page.
+func (ds *DynamoEventStore) listIndex(ctx context.Context, tenant we.Tenant, after we.Checkpoint, handler we.AggregateListHandler, read func(start map[string]types.AttributeValue) (*page, error)) error {
+  prefix := tenanted(tenant, "")
+
+  var start map[string]types.AttributeValue
+  if after != we.NoCheckpoint {
+    key, err := indexKey(prefix, after.String())
+    if err != nil {
+      return err
+    }
+    start = key
+  }
+
+  for {
+    page, err := read(start)
+    if err != nil {
+      return err
+    }
+
+    var items []struct {
+      PartitionKey string `dynamodbav:"pk"`
+    }
+    if err := attributevalue.UnmarshalListOfMaps(page.items, &items); err != nil {
+      return err
+    }
+
+    for _, item := range items {
+      id, err := aggregateOf(prefix, item.PartitionKey)
+      if err != nil {
+        return err
+      }
+
+      if err := handler(ctx, id, we.Checkpoint(item.PartitionKey)); err != nil {
+        return err
+      }
+    }
+
+    start = page.last
+    if start == nil {
+      return nil
+    }
+  }
+}
+
+// aggregateOf decodes the id of the aggregate from its partition key within the tenant's prefix.
+func aggregateOf(prefix string, pk string) (we.AggregateId, error) {
+  if !strings.HasPrefix(pk, prefix) {
+    return we.AggregateId{}, errors.Errorf("partition key %q is not prefixed with %q", pk, prefix)
+  }
+
+  id, err := we.EncodedAggregateId(strings.TrimPrefix(pk, prefix)).Decode()
+  if err != nil {
+    return we.AggregateId{}, err
+  }
+
+  return *id, nil
+}
+
+// indexKey is the aggregate type index key of the latest revision record with the partition key.
+func indexKey(prefix string, pk string) (map[string]types.AttributeValue, error) {
+  id, err := aggregateOf(prefix, pk)
+  if err != nil {
+    return nil, err
+  }
+
+  return attributevalue.MarshalMap(struct {
+    AggregateType string `dynamodbav:"aggregate-type"`
+    PartitionKey  string `dynamodbav:"pk"`
+    SortKey       string `dynamodbav:"sk"`
+  }{prefix + id.Type, pk, latestSortKey})
+}
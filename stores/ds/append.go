@@ -0,0 +1,26 @@
+package ds
+
+import (
+  "context"
+
+  "github.com/pkg/errors"
+
+  "github.com/weegigs/wee-events-go/we"
+)
+
+// Append imports the events as a single change set within the tenant resolved from the context, keeping their ids,
+// types, timestamps and metadata.
+func (ds *DynamoEventStore) Append(ctx context.Context, id we.AggregateId, expected we.Revision, events []we.RecordedEvent) (we.Revision, error) {
+  if len(events) == 0 {
+    return "", errors.New("attempted to append empty list of events")
+  }
+
+  tenant, err := ds.tenant(ctx)
+  if err != nil {
+    return "", err
+  }
+
+  return ds.write(ctx, id, expected, func() (ChangeSet, error) {
+    return ds.importChangeSet(tenant, id, expected, events)
+  })
+}
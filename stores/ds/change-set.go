@@ -2,6 +2,7 @@ package ds
 
 import (
   "encoding/json"
+  "strings"
 
   "github.com/pkg/errors"
 
@@ -11,16 +12,19 @@ import (
 type ChangeSet struct {
   PartitionKey string       `dynamodbav:"pk"`
   SortKey      string       `dynamodbav:"sk"`
+  Tenant       string       `dynamodbav:"tenant,omitempty"`
   Events       string       `dynamodbav:"events"`
   Revision     we.Revision  `dynamodbav:"revision"`
   Timestamp    we.Timestamp `dynamodbav:"timestamp"`
 }
 
 type LatestRecord struct {
-  PartitionKey string       `dynamodbav:"pk"`
-  SortKey      string       `dynamodbav:"sk"`
-  Revision     we.Revision  `dynamodbav:"revision"`
-  Timestamp    we.Timestamp `dynamodbav:"timestamp"`
+  PartitionKey  string       `dynamodbav:"pk"`
+  SortKey       string       `dynamodbav:"sk"`
+  AggregateType string       `dynamodbav:"aggregate-type"`
+  Revision      we.Revision  `dynamodbav:"revision"`
+  Timestamp     we.Timestamp `dynamodbav:"timestamp"`
+  Deleted       bool         `dynamodbav:"deleted,omitempty"`
 }
 
 func (cs *ChangeSet) RecordedEvents() ([]we.RecordedEvent, error) {
@@ -33,5 +37,6 @@ func (cs *ChangeSet) RecordedEvents() ([]we.RecordedEvent, error) {
 }
 
 func (cs *ChangeSet) AggregateId() (*we.AggregateId, error) {
-  return we.EncodedAggregateId(cs.PartitionKey).Decode()
+  encoded := strings.TrimPrefix(cs.PartitionKey, tenanted(we.Tenant(cs.Tenant), ""))
+  return we.EncodedAggregateId(encoded).Decode()
 }
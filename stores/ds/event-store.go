@@ -4,7 +4,6 @@ import (
   "context"
   "encoding/json"
   "strings"
-  "time"
 
   "github.com/avast/retry-go"
   "github.com/aws/aws-sdk-go-v2/aws"
@@ -23,6 +22,33 @@ type DynamoEventStore struct {
   db       *dynamodb.Client
   table    string
   revision *we.RevisionGenerator
+  tenants  we.TenantResolver
+  clock    we.Clock
+  ids      we.IDGenerator
+}
+
+type EventStoreOption func(*DynamoEventStore)
+
+// WithTenantResolver partitions the store by tenant, prefixing partition keys with the tenant resolved from the
+// operation context.
+func WithTenantResolver(resolver we.TenantResolver) EventStoreOption {
+  return func(store *DynamoEventStore) {
+    store.tenants = resolver
+  }
+}
+
+// WithClock sets the clock that change sets are recorded at, which also seeds the entropy of their revisions.
+func WithClock(clock we.Clock) EventStoreOption {
+  return func(store *DynamoEventStore) {
+    store.clock = clock
+  }
+}
+
+// WithIdGenerator sets the generator of the ids of published events, which otherwise take the event's revision.
+func WithIdGenerator(generator we.IDGenerator) EventStoreOption {
+  return func(store *DynamoEventStore) {
+    store.ids = generator
+  }
 }
 
 type EventStoreTableName string
@@ -31,12 +57,51 @@ func (name EventStoreTableName) String() string {
   return string(name)
 }
 
-func NewEventStore(db *dynamodb.Client, table EventStoreTableName) *DynamoEventStore {
-  return &DynamoEventStore{db: db, table: string(table), revision: we.NewRevisionGenerator()}
+func NewEventStore(db *dynamodb.Client, table EventStoreTableName, options ...EventStoreOption) *DynamoEventStore {
+  store := &DynamoEventStore{db: db, table: string(table), clock: we.SystemClock}
+
+  for _, option := range options {
+    option(store)
+  }
+
+  store.revision = we.NewRevisionGenerator(we.WithRevisionClock(store.clock))
+
+  if store.tenants == nil {
+    store.tenants = we.StaticTenantResolver(we.NoTenant)
+  }
+
+  return store
+}
+
+// Partition keys are the encoded aggregate id, prefixed with the tenant and a separator in tenanted stores. The
+// separator can't appear in tenants or aggregate types and tenants can't contain the encoded aggregate id's delimiter,
+// so the partition keys of tenanted aggregates are distinct from those of aggregates without a tenant.
+const tenantSeparator = "#"
+
+var InvalidTenant = errors.New("tenant must not contain " + tenantSeparator + " or .")
+
+var InvalidAggregateType = errors.New("aggregate type must not contain " + tenantSeparator)
+
+func (ds *DynamoEventStore) tenant(ctx context.Context) (we.Tenant, error) {
+  tenant, err := ds.tenants.Tenant(ctx)
+  if err != nil {
+    return we.NoTenant, err
+  }
+
+  if strings.ContainsAny(tenant.String(), tenantSeparator+".") {
+    return we.NoTenant, InvalidTenant
+  }
+
+  return tenant, nil
 }
 
 func (ds *DynamoEventStore) Load(ctx context.Context, id we.AggregateId) (we.Aggregate, error) {
-  events, err := ds.read(ctx, id)
+  tenant, err := ds.tenant(ctx)
+  if err != nil {
+    return we.Aggregate{}, err
+  }
+
+  events, err := ds.read(ctx, tenant, id)
   if err != nil {
     return we.Aggregate{}, err
   }
@@ -51,37 +116,72 @@ func (ds *DynamoEventStore) Load(ctx context.Context, id we.AggregateId) (we.Agg
 }
 
 func (ds *DynamoEventStore) Publish(ctx context.Context, aggregateId we.AggregateId, options we.PublishOptions, events ...we.DomainEvent) error {
-  return ds.publish(ctx, aggregateId, options, events)
+  tenant, err := ds.tenant(ctx)
+  if err != nil {
+    return err
+  }
+
+  _, err = ds.publish(ctx, tenant, aggregateId, options, events)
+  return err
+}
+
+// PublishRevision publishes the events and returns the revision of the change set that was written.
+func (ds *DynamoEventStore) PublishRevision(ctx context.Context, aggregateId we.AggregateId, options we.PublishOptions, events ...we.DomainEvent) (we.Revision, error) {
+  tenant, err := ds.tenant(ctx)
+  if err != nil {
+    return "", err
+  }
+
+  return ds.publish(ctx, tenant, aggregateId, options, events)
 }
 
+// Remove deletes every record for the aggregate within the tenant resolved from the context.
 func (ds *DynamoEventStore) Remove(ctx context.Context, aggregateId we.AggregateId) (int, error) {
-  return ds.remove(ctx, aggregateId)
+  tenant, err := ds.tenant(ctx)
+  if err != nil {
+    return 0, err
+  }
+
+  return ds.remove(ctx, tenant, aggregateId)
+}
+
+func tenanted(tenant we.Tenant, value string) string {
+  if tenant == we.NoTenant {
+    return value
+  }
+
+  return strings.Join([]string{tenant.String(), tenantSeparator, value}, "")
 }
 
-func partitionKey(id we.AggregateId) string {
-  return id.Encode().String()
+func partitionKey(tenant we.Tenant, id we.AggregateId) string {
+  return tenanted(tenant, id.Encode().String())
 }
 
+const (
+  changeSetPrefix = "change-set#"
+  latestSortKey   = "latest-revision"
+)
+
 func sortKey(revision we.Revision) string {
-  return strings.Join([]string{`change-set#`, revision.String()}, "")
+  return strings.Join([]string{changeSetPrefix, revision.String()}, "")
 }
 
-func latestFor(record ChangeSet) LatestRecord {
+func latestFor(aggregateId we.AggregateId, record ChangeSet) LatestRecord {
   return LatestRecord{
-    PartitionKey: record.PartitionKey,
-    SortKey:      "latest-revision",
-    Revision:     record.Revision,
-    Timestamp:    record.Timestamp,
+    PartitionKey:  record.PartitionKey,
+    SortKey:       latestSortKey,
+    AggregateType: tenanted(we.Tenant(record.Tenant), aggregateId.Type),
+    Revision:      record.Revision,
+    Timestamp:     record.Timestamp,
   }
 }
 
 // KAO: Some of this could be done in parallel
-func (ds *DynamoEventStore) read(ctx context.Context, id we.AggregateId) ([]we.RecordedEvent, error) {
-  query := expression.Key("pk").Equal(expression.Value(partitionKey(id))).And(
-    expression.Key("sk").BeginsWith("change-set#"),
-  )
+func (ds *DynamoEventStore) read(ctx context.Context, tenant we.Tenant, id we.AggregateId) ([]we.RecordedEvent, error) {
+  // the whole partition is read so the latest revision record can report a hard deleted aggregate
+  query := expression.Key("pk").Equal(expression.Value(partitionKey(tenant, id)))
 
-  projection := expression.NamesList(expression.Name("events"))
+  projection := expression.NamesList(expression.Name("sk"), expression.Name("events"), expression.Name("deleted"))
 
   builder := expression.NewBuilder().WithKeyCondition(query).WithProjection(projection)
   expr, err := builder.Build()
@@ -89,6 +189,12 @@ func (ds *DynamoEventStore) read(ctx context.Context, id we.AggregateId) ([]we.R
     return nil, err
   }
 
+  type record struct {
+    SortKey string `dynamodbav:"sk"`
+    Events  string `dynamodbav:"events"`
+    Deleted bool   `dynamodbav:"deleted"`
+  }
+
   var events []we.RecordedEvent
   var start map[string]types.AttributeValue
   for {
@@ -106,7 +212,7 @@ func (ds *DynamoEventStore) read(ctx context.Context, id we.AggregateId) ([]we.R
       return nil, err
     }
 
-    var items []ChangeSet
+    var items []record
     err = attributevalue.UnmarshalListOfMaps(out.Items, &items)
     if err != nil {
       return nil, err
@@ -114,6 +220,14 @@ func (ds *DynamoEventStore) read(ctx context.Context, id we.AggregateId) ([]we.R
 
     // KAO: this could be done in parallel
     for _, record := range items {
+      if record.Deleted {
+        return nil, we.AggregateDeleted
+      }
+
+      if !strings.HasPrefix(record.SortKey, changeSetPrefix) {
+        continue
+      }
+
       var evts []we.RecordedEvent
       if err := json.Unmarshal([]byte(record.Events), &evts); err != nil {
         return nil, errors.Wrap(err, "failed to unmarshal events")
@@ -131,6 +245,10 @@ func (ds *DynamoEventStore) read(ctx context.Context, id we.AggregateId) ([]we.R
 }
 
 func latestCondition(revision we.Revision, expectedRevision we.Revision) expression.ConditionBuilder {
+  return revisionCondition(revision, expectedRevision).And(expression.AttributeNotExists(expression.Name("deleted")))
+}
+
+func revisionCondition(revision we.Revision, expectedRevision we.Revision) expression.ConditionBuilder {
   if len(expectedRevision) == 0 {
     return expression.Name("revision").LessThan(expression.Value(revision)).Or(
       expression.AttributeNotExists(expression.Name("revision")),
@@ -176,22 +294,31 @@ func (ds *DynamoEventStore) encodeEvent(event we.DomainEvent) (we.Data, error) {
   return we.MarshalToData(event)
 }
 
-func (ds *DynamoEventStore) makeChangeSet(aggregateId we.AggregateId, options we.PublishOptions, events []we.DomainEvent) (ChangeSet, error) {
-  now := time.Now()
+// makeChangeSet records the events with revisions after the aggregate's expected revision, so they increase even if
+// the clock is behind the one that recorded it.
+func (ds *DynamoEventStore) makeChangeSet(tenant we.Tenant, aggregateId we.AggregateId, options we.PublishOptions, events []we.DomainEvent) (ChangeSet, error) {
+  after := options.ExpectedRevision
+  now := ds.clock.Now()
   timestamp := we.Timestamp(now.UTC().Format(we.RFC3339Milli))
 
   recorded := make([]we.RecordedEvent, len(events))
 
   for index, event := range events {
 
-    revision := ds.revision.NewRevision(now)
+    revision := ds.revision.NewRevisionAfter(now, after)
+    after = revision
     data, err := ds.encodeEvent(event)
     if err != nil {
       return ChangeSet{}, err
     }
 
+    id := we.EventID(revision)
+    if ds.ids != nil {
+      id = ds.ids.Create()
+    }
+
     recorded[index] = we.RecordedEvent{
-      EventID:     we.EventID(revision),
+      EventID:     id,
       EventType:   we.EventTypeOf(event),
       AggregateId: aggregateId,
       Data:        data,
@@ -201,7 +328,28 @@ func (ds *DynamoEventStore) makeChangeSet(aggregateId we.AggregateId, options we
     }
   }
 
-  last := recorded[len(events)-1].Revision
+  return changeSetOf(tenant, aggregateId, timestamp, recorded)
+}
+
+// importChangeSet keeps the events' ids, timestamps and metadata, giving them new revisions so they sort after the
+// aggregate's existing change sets.
+func (ds *DynamoEventStore) importChangeSet(tenant we.Tenant, aggregateId we.AggregateId, expected we.Revision, events []we.RecordedEvent) (ChangeSet, error) {
+  now := ds.clock.Now()
+
+  after := expected
+  recorded := make([]we.RecordedEvent, len(events))
+  for index, event := range events {
+    event.AggregateId = aggregateId
+    event.Revision = ds.revision.NewRevisionAfter(now, after)
+    after = event.Revision
+    recorded[index] = event
+  }
+
+  return changeSetOf(tenant, aggregateId, we.TimestampFromTime(now), recorded)
+}
+
+func changeSetOf(tenant we.Tenant, aggregateId we.AggregateId, timestamp we.Timestamp, recorded []we.RecordedEvent) (ChangeSet, error) {
+  last := recorded[len(recorded)-1].Revision
 
   evts, err := json.Marshal(recorded)
   if err != nil {
@@ -209,28 +357,42 @@ func (ds *DynamoEventStore) makeChangeSet(aggregateId we.AggregateId, options we
   }
 
   return ChangeSet{
-    PartitionKey: partitionKey(aggregateId),
+    PartitionKey: partitionKey(tenant, aggregateId),
     SortKey:      sortKey(last),
+    Tenant:       tenant.String(),
     Events:       string(evts),
     Timestamp:    timestamp,
     Revision:     last,
   }, nil
-
 }
 
-func (ds *DynamoEventStore) publish(ctx context.Context, aggregateId we.AggregateId, options we.PublishOptions, events []we.DomainEvent) error {
+func (ds *DynamoEventStore) publish(ctx context.Context, tenant we.Tenant, aggregateId we.AggregateId, options we.PublishOptions, events []we.DomainEvent) (we.Revision, error) {
   if len(events) == 0 {
-    return errors.New("attempted to publish empty list of events")
+    return "", errors.New("attempted to publish empty list of events")
   }
 
+  return ds.write(ctx, aggregateId, options.ExpectedRevision, func() (ChangeSet, error) {
+    return ds.makeChangeSet(tenant, aggregateId, options, events)
+  })
+}
+
+// write puts the change set made by the function along with the latest revision record, on the condition that the
+// aggregate is at the expected revision. The change set is remade for each attempt so its revision is current.
+func (ds *DynamoEventStore) write(ctx context.Context, aggregateId we.AggregateId, expected we.Revision, makeChangeSet func() (ChangeSet, error)) (we.Revision, error) {
+  if strings.Contains(aggregateId.Type, tenantSeparator) {
+    return "", InvalidAggregateType
+  }
+
+  var revision we.Revision
   err := retry.Do(
     func() error {
-      changes, err := ds.makeChangeSet(aggregateId, options, events)
+      changes, err := makeChangeSet()
       if err != nil {
         return err
       }
+      revision = changes.Revision
 
-      latest, err := attributevalue.MarshalMap(latestFor(changes))
+      latest, err := attributevalue.MarshalMap(latestFor(aggregateId, changes))
       if err != nil {
         return err
       }
@@ -243,7 +405,7 @@ func (ds *DynamoEventStore) publish(ctx context.Context, aggregateId we.Aggregat
       condition, err := expression.NewBuilder().WithCondition(
         latestCondition(
           changes.Revision,
-          options.ExpectedRevision,
+          expected,
         ),
       ).Build()
       if err != nil {
@@ -272,21 +434,35 @@ func (ds *DynamoEventStore) publish(ctx context.Context, aggregateId we.Aggregat
       }
 
       _, err = ds.db.TransactWriteItems(ctx, write)
-      return maybeRevisionConflict(err)
+      err = maybeRevisionConflict(err)
+      if isRevisionConflict(err) {
+        deleted, derr := ds.deleted(ctx, changes.PartitionKey)
+        if derr != nil {
+          return derr
+        }
+        if deleted {
+          return we.AggregateDeleted
+        }
+      }
+
+      return err
     }, retry.RetryIf(
       func(err error) bool {
         // todo: KAO ... check for retryable errors
-        return isRevisionConflict(err) && len(options.ExpectedRevision) == 0
+        return isRevisionConflict(err) && len(expected) == 0
       },
     ),
     retry.LastErrorOnly(true),
   )
 
-  if err != nil && !isRevisionConflict(err) {
-    return errors.Wrap(err, "failed to publish events")
+  if err != nil {
+    if isRevisionConflict(err) || err == we.AggregateDeleted {
+      return "", err
+    }
+    return "", errors.Wrap(err, "failed to publish events")
   }
 
-  return err
+  return revision, nil
 }
 
 func revisionFrom(events []we.RecordedEvent) we.Revision {
@@ -298,13 +474,17 @@ func revisionFrom(events []we.RecordedEvent) we.Revision {
   return events[count-1].Revision
 }
 
-func (ds *DynamoEventStore) remove(ctx context.Context, id we.AggregateId) (int, error) {
+func (ds *DynamoEventStore) remove(ctx context.Context, tenant we.Tenant, id we.AggregateId) (int, error) {
+  return ds.removeWhere(ctx, expression.Key("pk").Equal(expression.Value(partitionKey(tenant, id))))
+}
+
+// removeWhere deletes the records matching the key condition, 25 at a time.
+func (ds *DynamoEventStore) removeWhere(ctx context.Context, query expression.KeyConditionBuilder) (int, error) {
   type record struct {
     PartitionKey string `dynamodbav:"pk"`
     SortKey      string `dynamodbav:"sk"`
   }
 
-  query := expression.Key("pk").Equal(expression.Value(partitionKey(id)))
   projection := expression.NamesList(expression.Name("pk"), expression.Name("sk"))
 
   builder := expression.NewBuilder().WithKeyCondition(query).WithProjection(projection)
@@ -31,6 +31,24 @@ func (Tested) EventType() we.EventType {
 	return TestedEvent
 }
 
+func TestPartitionKeys(t *testing.T) {
+	ctx := context.Background()
+	store := NewEventStore(nil, "keys", WithTenantResolver(we.ContextTenantResolver()))
+	untenanted := NewEventStore(nil, "keys")
+
+	t.Run("rejects tenants containing the encoded aggregate id delimiter", func(t *testing.T) {
+		// "red.counter#abc.1" would also be the partition key of {Type: "red", Key: "counter#abc.1"}
+		err := store.Publish(we.WithTenant(ctx, "red.counter"), we.AggregateId{Type: "abc", Key: "1"}, we.Options(), Tested{})
+		assert.Equal(t, InvalidTenant, err)
+	})
+
+	t.Run("rejects aggregate types containing the tenant separator", func(t *testing.T) {
+		// "red#counter.abc" would also be the partition key of {Type: "counter", Key: "abc"} in the red tenant
+		err := untenanted.Publish(ctx, we.AggregateId{Type: "red#counter", Key: "abc"}, we.Options(), Tested{})
+		assert.Equal(t, InvalidAggregateType, err)
+	})
+}
+
 func TestDynamoDBStore(t *testing.T) {
 	ctx := context.Background()
 	store, tearDown, err := DynamoTestStore(ctx)
@@ -68,4 +86,132 @@ func TestDynamoDBStore(t *testing.T) {
 		loaded, err := store.Load(ctx, aggregateId)
 		assert.Equal(t, we.InitialRevision, loaded.Revision)
 	})
+
+	t.Run("isolates tenants", func(t *testing.T) {
+		tenanted := NewEventStore(store.db, EventStoreTableName(store.table), WithTenantResolver(we.ContextTenantResolver()))
+		red := we.WithTenant(ctx, "red")
+		blue := we.WithTenant(ctx, "blue")
+		aggregateId := createId()
+
+		err := tenanted.Publish(red, aggregateId, we.Options(), Tested{TestStringValue: "red", TestIntValue: 1})
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		err = tenanted.Publish(blue, aggregateId, we.Options(), Tested{TestStringValue: "blue", TestIntValue: 2})
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		loaded, err := tenanted.Load(red, aggregateId)
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, 1, len(loaded.Events))
+
+		_, err = tenanted.Load(ctx, aggregateId)
+		assert.Equal(t, we.MissingTenant, err)
+
+		count, err := tenanted.Remove(blue, aggregateId)
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, 2, count)
+
+		loaded, err = tenanted.Load(blue, aggregateId)
+		assert.Nil(t, err)
+		assert.Equal(t, we.InitialRevision, loaded.Revision)
+
+		loaded, err = tenanted.Load(red, aggregateId)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(loaded.Events))
+	})
+
+	t.Run("lists the aggregates of a tenant", func(t *testing.T) {
+		tenanted := NewEventStore(store.db, EventStoreTableName(store.table), WithTenantResolver(we.ContextTenantResolver()))
+		green := we.WithTenant(ctx, we.Tenant("green-"+createId().Key))
+		aggregateId := createId()
+
+		err := tenanted.Publish(green, aggregateId, we.Options(), Tested{TestStringValue: "green", TestIntValue: 3})
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		var listed []we.AggregateId
+		err = tenanted.ListAggregates(green, we.NoCheckpoint, func(ctx context.Context, id we.AggregateId, _ we.Checkpoint) error {
+			listed = append(listed, id)
+			return nil
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, []we.AggregateId{aggregateId}, listed)
+	})
+
+	t.Run("lists aggregates without a tenant whose keys contain the tenant separator", func(t *testing.T) {
+		tenanted := NewEventStore(store.db, EventStoreTableName(store.table), WithTenantResolver(we.ContextTenantResolver()))
+		untenanted := createId()
+		untenanted.Key = "separated#" + untenanted.Key
+		tenantedId := createId()
+
+		err := store.Publish(ctx, untenanted, we.Options(), Tested{TestStringValue: "untenanted", TestIntValue: 4})
+		if !assert.Nil(t, err) {
+			return
+		}
+		err = tenanted.Publish(we.WithTenant(ctx, "yellow"), tenantedId, we.Options(), Tested{TestStringValue: "yellow", TestIntValue: 5})
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		var listed []we.AggregateId
+		err = store.ListAggregates(ctx, we.NoCheckpoint, func(ctx context.Context, id we.AggregateId, _ we.Checkpoint) error {
+			listed = append(listed, id)
+			return nil
+		})
+		assert.Nil(t, err)
+		assert.Contains(t, listed, untenanted)
+		assert.NotContains(t, listed, tenantedId)
+	})
+
+	t.Run("lists the aggregates of a type", func(t *testing.T) {
+		tenanted := NewEventStore(store.db, EventStoreTableName(store.table), WithTenantResolver(we.ContextTenantResolver()))
+		purple := we.WithTenant(ctx, we.Tenant("purple-"+createId().Key))
+		aggregateType := "typed-" + createId().Key
+
+		var ids []we.AggregateId
+		for i := 0; i < 3; i++ {
+			id := createId()
+			id.Type = aggregateType
+			ids = append(ids, id)
+
+			err := tenanted.Publish(purple, id, we.Options(), Tested{TestStringValue: "purple", TestIntValue: i})
+			if !assert.Nil(t, err) {
+				return
+			}
+		}
+		err := tenanted.Publish(purple, createId(), we.Options(), Tested{TestStringValue: "other", TestIntValue: 6})
+		if !assert.Nil(t, err) {
+			return
+		}
+		err = store.Publish(ctx, ids[0], we.Options(), Tested{TestStringValue: "untenanted", TestIntValue: 7})
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		var listed []we.AggregateId
+		var checkpoints []we.Checkpoint
+		err = tenanted.ListAggregatesOfType(purple, aggregateType, we.NoCheckpoint, func(ctx context.Context, id we.AggregateId, checkpoint we.Checkpoint) error {
+			listed = append(listed, id)
+			checkpoints = append(checkpoints, checkpoint)
+			return nil
+		})
+		assert.Nil(t, err)
+		assert.ElementsMatch(t, ids, listed)
+
+		var resumed []we.AggregateId
+		err = tenanted.ListAggregatesOfType(purple, aggregateType, checkpoints[0], func(ctx context.Context, id we.AggregateId, _ we.Checkpoint) error {
+			resumed = append(resumed, id)
+			return nil
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, listed[1:], resumed)
+	})
 }
@@ -15,7 +15,7 @@ import (
 )
 
 func LocalDynamoStore(ctx context.Context) (*DynamoEventStore, error) {
-	const tableName = "wee-events"
+	tableName := LocalEventsTableName()
 
 	cfg, err := localConfig(ctx)
 	if err != nil {
@@ -24,29 +24,32 @@ func LocalDynamoStore(ctx context.Context) (*DynamoEventStore, error) {
 
 	client := dynamodb.NewFromConfig(cfg)
 
-	exists, err := tableExists(ctx, client, tableName)
+	_, drift, err := Provision(ctx, client, ExpectedSchema(tableName))
 	if err != nil {
 		return nil, err
 	}
 
-	if !exists {
-		createTable(ctx, client, tableName)
+	for _, d := range drift {
+		log.WithField("table", tableName).Info("events table schema drift: " + d.String())
 	}
 
-	store := NewEventStore(
-		client,
-		EventStoreTableName("wee-events"),
-	)
+	store := NewEventStore(client, tableName)
 
 	return store, nil
 }
 
 func localConfig(ctx context.Context) (aws.Config, error) {
+	return EndpointAWSConfig(ctx, "http://localhost:8000")
+}
+
+// EndpointAWSConfig creates a configuration for a DynamoDB compatible endpoint, such as dynamodb-local, using
+// dummy credentials.
+func EndpointAWSConfig(ctx context.Context, endpoint string) (aws.Config, error) {
 	config, err := config.LoadDefaultConfig(ctx,
 		config.WithRegion("us-east-1"),
 		config.WithEndpointResolver(aws.EndpointResolverFunc(
 			func(service, region string) (aws.Endpoint, error) {
-				return aws.Endpoint{URL: "http://localhost:8000"}, nil
+				return aws.Endpoint{URL: endpoint}, nil
 			})),
 		config.WithCredentialsProvider(credentials.StaticCredentialsProvider{
 			Value: aws.Credentials{
@@ -81,31 +84,6 @@ func tableExists(ctx context.Context, client *dynamodb.Client, name string) (boo
 	return true, nil
 }
 
-func createTable(ctx context.Context, client *dynamodb.Client, table string) error {
-	log.Info("creating events table")
-
-	_, err := client.CreateTable(
-		ctx, &dynamodb.CreateTableInput{
-			TableName: aws.String(table),
-			AttributeDefinitions: []types.AttributeDefinition{
-				{AttributeName: aws.String("pk"), AttributeType: types.ScalarAttributeTypeS},
-				{AttributeName: aws.String("sk"), AttributeType: types.ScalarAttributeTypeS},
-			},
-			KeySchema: []types.KeySchemaElement{
-				{AttributeName: aws.String("pk"), KeyType: types.KeyTypeHash},
-				{AttributeName: aws.String("sk"), KeyType: types.KeyTypeRange},
-			},
-			BillingMode: types.BillingModePayPerRequest,
-		},
-	)
-
-	if err != nil {
-		return err
-	}
-
-	return waitForTable(ctx, client, table)
-}
-
 func waitForTable(ctx context.Context, client *dynamodb.Client, name string) error {
 	required := &dynamodb.DescribeTableInput{TableName: aws.String(name)}
 	return dynamodb.NewTableExistsWaiter(client).Wait(ctx, required, 2*time.Minute)
@@ -0,0 +1,115 @@
+package ds
+
+import (
+  "context"
+
+  "github.com/aws/aws-sdk-go-v2/aws"
+  "github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+  "github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+  "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+  "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+  "github.com/pkg/errors"
+
+  "github.com/weegigs/wee-events-go/we"
+)
+
+// RemoveAggregate deletes or truncates the aggregate within the tenant resolved from the context. A hard deleted
+// aggregate's latest revision record is kept, marked as deleted, so it can't be published to or soft deleted again.
+func (ds *DynamoEventStore) RemoveAggregate(ctx context.Context, id we.AggregateId, removal we.Removal) error {
+  tenant, err := ds.tenant(ctx)
+  if err != nil {
+    return err
+  }
+
+  pk := partitionKey(tenant, id)
+
+  switch removal.Mode {
+  case we.SoftDelete:
+    deleted, err := ds.deleted(ctx, pk)
+    if err != nil {
+      return err
+    }
+    if deleted {
+      return we.AggregateDeleted
+    }
+
+    _, err = ds.remove(ctx, tenant, id)
+    return err
+
+  case we.HardDelete:
+    if err := ds.markDeleted(ctx, pk); err != nil {
+      return err
+    }
+
+    _, err := ds.removeWhere(ctx, expression.Key("pk").Equal(expression.Value(pk)).And(
+      expression.Key("sk").BeginsWith(changeSetPrefix),
+    ))
+    return err
+
+  case we.TruncateBefore:
+    // change sets are keyed by their last revision, so those keyed before the revision hold only earlier events
+    _, err := ds.removeWhere(ctx, expression.Key("pk").Equal(expression.Value(pk)).And(
+      expression.Key("sk").LessThan(expression.Value(sortKey(removal.Before))),
+    ))
+    return err
+
+  default:
+    return we.UnsupportedRemoval
+  }
+}
+
+func (ds *DynamoEventStore) markDeleted(ctx context.Context, pk string) error {
+  key, err := latestKey(pk)
+  if err != nil {
+    return err
+  }
+
+  update, err := expression.NewBuilder().WithUpdate(expression.Set(expression.Name("deleted"), expression.Value(true))).Build()
+  if err != nil {
+    return err
+  }
+
+  _, err = ds.db.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+    TableName:                 aws.String(ds.table),
+    Key:                       key,
+    UpdateExpression:          update.Update(),
+    ExpressionAttributeNames:  update.Names(),
+    ExpressionAttributeValues: update.Values(),
+  })
+  if err != nil {
+    return errors.Wrap(err, "failed to mark aggregate deleted")
+  }
+
+  return nil
+}
+
+// deleted reports whether the aggregate's latest revision record marks it as deleted.
+func (ds *DynamoEventStore) deleted(ctx context.Context, pk string) (bool, error) {
+  key, err := latestKey(pk)
+  if err != nil {
+    return false, err
+  }
+
+  out, err := ds.db.GetItem(ctx, &dynamodb.GetItemInput{
+    TableName:      aws.String(ds.table),
+    Key:            key,
+    ConsistentRead: aws.Bool(true),
+  })
+  if err != nil {
+    return false, err
+  }
+
+  var latest LatestRecord
+  if err := attributevalue.UnmarshalMap(out.Item, &latest); err != nil {
+    return false, err
+  }
+
+  return latest.Deleted, nil
+}
+
+func latestKey(pk string) (map[string]types.AttributeValue, error) {
+  return attributevalue.MarshalMap(struct {
+    PartitionKey string `dynamodbav:"pk"`
+    SortKey      string `dynamodbav:"sk"`
+  }{pk, latestSortKey})
+}
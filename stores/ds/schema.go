@@ -0,0 +1,268 @@
+package ds
+
+import (
+  "context"
+  "fmt"
+  "sort"
+
+  "github.com/aws/aws-sdk-go-v2/aws"
+  "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+  "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+  "github.com/pkg/errors"
+  log "github.com/sirupsen/logrus"
+)
+
+const (
+  partitionKeyAttribute  = "pk"
+  sortKeyAttribute       = "sk"
+  aggregateTypeAttribute = "aggregate-type"
+
+  // AggregateTypeIndex is the global secondary index used to enumerate aggregates by type. It is populated by the
+  // latest-revision record of each aggregate.
+  AggregateTypeIndex = "aggregate-type"
+)
+
+// TableSchema describes the events table required by DynamoEventStore.
+type TableSchema struct {
+  TableName      string
+  PartitionKey   string
+  SortKey        string
+  Indexes        []IndexSchema
+  StreamViewType types.StreamViewType
+  BillingMode    types.BillingMode
+}
+
+type IndexSchema struct {
+  Name         string
+  PartitionKey string
+  SortKey      string
+  Projection   types.ProjectionType
+}
+
+type SchemaOption func(schema *TableSchema)
+
+func WithStreamViewType(view types.StreamViewType) SchemaOption {
+  return func(schema *TableSchema) {
+    schema.StreamViewType = view
+  }
+}
+
+// ExpectedSchema returns the schema the event store expects for the named table.
+func ExpectedSchema(table EventStoreTableName, options ...SchemaOption) TableSchema {
+  schema := TableSchema{
+    TableName:    table.String(),
+    PartitionKey: partitionKeyAttribute,
+    SortKey:      sortKeyAttribute,
+    Indexes: []IndexSchema{
+      {
+        Name:         AggregateTypeIndex,
+        PartitionKey: aggregateTypeAttribute,
+        SortKey:      partitionKeyAttribute,
+        Projection:   types.ProjectionTypeKeysOnly,
+      },
+    },
+    StreamViewType: types.StreamViewTypeNewImage,
+    BillingMode:    types.BillingModePayPerRequest,
+  }
+
+  for _, option := range options {
+    option(&schema)
+  }
+
+  return schema
+}
+
+// Drift records a difference between the expected and actual table schema.
+type Drift struct {
+  Property string
+  Expected string
+  Actual   string
+}
+
+func (d Drift) String() string {
+  return fmt.Sprintf("%s: expected %q, found %q", d.Property, d.Expected, d.Actual)
+}
+
+var TableNotFound = errors.New("events table not found")
+
+// Provision creates the events table if it does not exist, and validates it if it does. The returned drift is
+// empty when the table matches the schema.
+func Provision(ctx context.Context, client *dynamodb.Client, schema TableSchema) (bool, []Drift, error) {
+  exists, err := tableExists(ctx, client, schema.TableName)
+  if err != nil {
+    return false, nil, err
+  }
+
+  if exists {
+    drift, err := Validate(ctx, client, schema)
+    return false, drift, err
+  }
+
+  if err := createTable(ctx, client, schema); err != nil {
+    return false, nil, err
+  }
+
+  drift, err := Validate(ctx, client, schema)
+  return true, drift, err
+}
+
+// Validate compares the events table with the schema, returning TableNotFound if the table does not exist.
+func Validate(ctx context.Context, client *dynamodb.Client, schema TableSchema) ([]Drift, error) {
+  description, err := client.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(schema.TableName)})
+  if err != nil {
+    var notFound *types.ResourceNotFoundException
+    if errors.As(err, &notFound) {
+      return nil, TableNotFound
+    }
+    return nil, errors.Wrap(err, "failed to describe events table")
+  }
+
+  return schema.Compare(description.Table), nil
+}
+
+// Compare reports the differences between the schema and a table description.
+func (schema TableSchema) Compare(table *types.TableDescription) []Drift {
+  var drift []Drift
+  report := func(property string, expected string, actual string) {
+    if expected != actual {
+      drift = append(drift, Drift{Property: property, Expected: expected, Actual: actual})
+    }
+  }
+
+  if table.TableStatus != types.TableStatusActive {
+    report("status", string(types.TableStatusActive), string(table.TableStatus))
+  }
+
+  hash, rng := keysOf(table.KeySchema)
+  report("partition key", schema.PartitionKey, hash)
+  report("sort key", schema.SortKey, rng)
+
+  attributes := map[string]types.ScalarAttributeType{}
+  for _, definition := range table.AttributeDefinitions {
+    attributes[aws.ToString(definition.AttributeName)] = definition.AttributeType
+  }
+  for _, attribute := range schema.keyAttributes() {
+    report(fmt.Sprintf("attribute %s type", attribute), string(types.ScalarAttributeTypeS), string(attributes[attribute]))
+  }
+
+  indexes := map[string]types.GlobalSecondaryIndexDescription{}
+  for _, index := range table.GlobalSecondaryIndexes {
+    indexes[aws.ToString(index.IndexName)] = index
+  }
+  for _, expected := range schema.Indexes {
+    index, ok := indexes[expected.Name]
+    if !ok {
+      report(fmt.Sprintf("index %s", expected.Name), "present", "missing")
+      continue
+    }
+
+    hash, rng := keysOf(index.KeySchema)
+    report(fmt.Sprintf("index %s partition key", expected.Name), expected.PartitionKey, hash)
+    report(fmt.Sprintf("index %s sort key", expected.Name), expected.SortKey, rng)
+
+    var projection types.ProjectionType
+    if index.Projection != nil {
+      projection = index.Projection.ProjectionType
+    }
+    report(fmt.Sprintf("index %s projection", expected.Name), string(expected.Projection), string(projection))
+  }
+
+  if schema.StreamViewType != "" {
+    var view types.StreamViewType
+    if table.StreamSpecification != nil && aws.ToBool(table.StreamSpecification.StreamEnabled) {
+      view = table.StreamSpecification.StreamViewType
+    }
+    report("stream view type", string(schema.StreamViewType), string(view))
+  }
+
+  return drift
+}
+
+func (schema TableSchema) keyAttributes() []string {
+  unique := map[string]bool{schema.PartitionKey: true, schema.SortKey: true}
+  for _, index := range schema.Indexes {
+    unique[index.PartitionKey] = true
+    if index.SortKey != "" {
+      unique[index.SortKey] = true
+    }
+  }
+
+  var attributes []string
+  for attribute := range unique {
+    attributes = append(attributes, attribute)
+  }
+  sort.Strings(attributes)
+
+  return attributes
+}
+
+func keysOf(elements []types.KeySchemaElement) (string, string) {
+  var hash, rng string
+  for _, element := range elements {
+    switch element.KeyType {
+    case types.KeyTypeHash:
+      hash = aws.ToString(element.AttributeName)
+    case types.KeyTypeRange:
+      rng = aws.ToString(element.AttributeName)
+    }
+  }
+
+  return hash, rng
+}
+
+func keySchema(hash string, rng string) []types.KeySchemaElement {
+  elements := []types.KeySchemaElement{
+    {AttributeName: aws.String(hash), KeyType: types.KeyTypeHash},
+  }
+  if rng != "" {
+    elements = append(elements, types.KeySchemaElement{AttributeName: aws.String(rng), KeyType: types.KeyTypeRange})
+  }
+
+  return elements
+}
+
+func (schema TableSchema) createTableInput() *dynamodb.CreateTableInput {
+  var definitions []types.AttributeDefinition
+  for _, attribute := range schema.keyAttributes() {
+    definitions = append(definitions, types.AttributeDefinition{
+      AttributeName: aws.String(attribute),
+      AttributeType: types.ScalarAttributeTypeS,
+    })
+  }
+
+  var indexes []types.GlobalSecondaryIndex
+  for _, index := range schema.Indexes {
+    indexes = append(indexes, types.GlobalSecondaryIndex{
+      IndexName:  aws.String(index.Name),
+      KeySchema:  keySchema(index.PartitionKey, index.SortKey),
+      Projection: &types.Projection{ProjectionType: index.Projection},
+    })
+  }
+
+  input := &dynamodb.CreateTableInput{
+    TableName:              aws.String(schema.TableName),
+    AttributeDefinitions:   definitions,
+    KeySchema:              keySchema(schema.PartitionKey, schema.SortKey),
+    GlobalSecondaryIndexes: indexes,
+    BillingMode:            schema.BillingMode,
+  }
+
+  if schema.StreamViewType != "" {
+    input.StreamSpecification = &types.StreamSpecification{
+      StreamEnabled:  aws.Bool(true),
+      StreamViewType: schema.StreamViewType,
+    }
+  }
+
+  return input
+}
+
+func createTable(ctx context.Context, client *dynamodb.Client, schema TableSchema) error {
+  log.WithField("table", schema.TableName).Info("creating events table")
+
+  if _, err := client.CreateTable(ctx, schema.createTableInput()); err != nil {
+    return errors.Wrap(err, "failed to create events table")
+  }
+
+  return waitForTable(ctx, client, schema.TableName)
+}
@@ -0,0 +1,53 @@
+package ds
+
+import (
+  "testing"
+
+  "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+  "github.com/stretchr/testify/assert"
+)
+
+func describe(schema TableSchema) *types.TableDescription {
+  input := schema.createTableInput()
+
+  var indexes []types.GlobalSecondaryIndexDescription
+  for _, index := range input.GlobalSecondaryIndexes {
+    indexes = append(indexes, types.GlobalSecondaryIndexDescription{
+      IndexName:  index.IndexName,
+      KeySchema:  index.KeySchema,
+      Projection: index.Projection,
+    })
+  }
+
+  return &types.TableDescription{
+    TableName:              input.TableName,
+    TableStatus:            types.TableStatusActive,
+    AttributeDefinitions:   input.AttributeDefinitions,
+    KeySchema:              input.KeySchema,
+    GlobalSecondaryIndexes: indexes,
+    StreamSpecification:    input.StreamSpecification,
+  }
+}
+
+func TestSchemaDrift(t *testing.T) {
+  schema := ExpectedSchema("events")
+
+  t.Run("matches the table it creates", func(t *testing.T) {
+    assert.Empty(t, schema.Compare(describe(schema)))
+  })
+
+  t.Run("reports a missing index", func(t *testing.T) {
+    table := describe(schema)
+    table.GlobalSecondaryIndexes = nil
+
+    assert.Equal(t, []Drift{{Property: "index aggregate-type", Expected: "present", Actual: "missing"}}, schema.Compare(table))
+  })
+
+  t.Run("reports a disabled stream", func(t *testing.T) {
+    table := describe(schema)
+    table.StreamSpecification = nil
+
+    assert.Equal(t, []Drift{{Property: "stream view type", Expected: "NEW_IMAGE", Actual: ""}}, schema.Compare(table))
+  })
+
+}
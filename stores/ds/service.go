@@ -19,6 +19,7 @@ var Live = wire.NewSet(
   EventsTableNameFromEnvironment,
   DefaultAWSConfig,
   Client,
+  DefaultEventStoreOptions,
   NewEventStore,
   wire.Bind(new(we.EventStore), new(*DynamoEventStore)),
 )
@@ -42,6 +43,11 @@ func EventsTableNameFromEnvironment() (EventStoreTableName, error) {
   return EventStoreTableName(table), nil
 }
 
+// DefaultEventStoreOptions provides the options for an untenanted store.
+func DefaultEventStoreOptions() []EventStoreOption {
+  return nil
+}
+
 func LocalEventsTableName() EventStoreTableName {
   return EventStoreTableName("wee-events")
 }
@@ -0,0 +1,43 @@
+package ds
+
+import (
+  "context"
+
+  "github.com/aws/aws-lambda-go/events"
+  log "github.com/sirupsen/logrus"
+
+  "github.com/weegigs/wee-events-go/we"
+)
+
+type LambdaStreamHandler = func(ctx context.Context, event events.DynamoDBEvent) (events.DynamoDBEventResponse, error)
+
+// NewLambdaStreamHandler adapts an event handler to a Lambda DynamoDB stream event source. Processing stops at the
+// first failing record, which is reported as a batch item failure so that Lambda retries it and the records that
+// follow it in order. The event source mapping should enable ReportBatchItemFailures.
+func NewLambdaStreamHandler(handler we.EventHandler) LambdaStreamHandler {
+  return func(ctx context.Context, event events.DynamoDBEvent) (events.DynamoDBEventResponse, error) {
+    for _, record := range event.Records {
+      if _, err := deliver(ctx, handler, lambdaImage(record.Change.NewImage)); err != nil {
+        log.WithError(err).WithField("sequence", record.Change.SequenceNumber).Info("stream record failed")
+        return events.DynamoDBEventResponse{
+          BatchItemFailures: []events.DynamoDBBatchItemFailure{
+            {ItemIdentifier: record.Change.SequenceNumber},
+          },
+        }, nil
+      }
+    }
+
+    return events.DynamoDBEventResponse{}, nil
+  }
+}
+
+func lambdaImage(image map[string]events.DynamoDBAttributeValue) map[string]string {
+  values := map[string]string{}
+  for name, value := range image {
+    if value.DataType() == events.DataTypeString {
+      values[name] = value.String()
+    }
+  }
+
+  return values
+}
@@ -0,0 +1,82 @@
+package ds
+
+import (
+  "context"
+  "encoding/json"
+  "errors"
+  "testing"
+
+  "github.com/aws/aws-lambda-go/events"
+  "github.com/stretchr/testify/assert"
+  "github.com/stretchr/testify/require"
+
+  "github.com/weegigs/wee-events-go/we"
+)
+
+func streamRecord(t *testing.T, sequence string, sk string, recorded ...we.RecordedEvent) events.DynamoDBEventRecord {
+  encoded, err := json.Marshal(recorded)
+  require.NoError(t, err)
+
+  return events.DynamoDBEventRecord{
+    EventName: "INSERT",
+    Change: events.DynamoDBStreamRecord{
+      SequenceNumber: sequence,
+      NewImage: map[string]events.DynamoDBAttributeValue{
+        "pk":     events.NewStringAttribute("test.stream"),
+        "sk":     events.NewStringAttribute(sk),
+        "events": events.NewStringAttribute(string(encoded)),
+      },
+    },
+  }
+}
+
+func TestLambdaStreamHandler(t *testing.T) {
+  ctx := context.Background()
+  id := we.AggregateId{Type: "test", Key: "stream"}
+  first := we.RecordedEvent{AggregateId: id, EventID: "1", Revision: "00000000000000000000000001"}
+  second := we.RecordedEvent{AggregateId: id, EventID: "2", Revision: "00000000000000000000000002"}
+  third := we.RecordedEvent{AggregateId: id, EventID: "3", Revision: "00000000000000000000000003"}
+
+  event := events.DynamoDBEvent{
+    Records: []events.DynamoDBEventRecord{
+      streamRecord(t, "100", "change-set#00000000000000000000000002", first, second),
+      streamRecord(t, "101", "latest-revision"),
+      streamRecord(t, "102", "change-set#00000000000000000000000003", third),
+    },
+  }
+
+  t.Run("delivers change set events in order", func(t *testing.T) {
+    var received []we.EventID
+    var positions []we.Position
+    handler := NewLambdaStreamHandler(func(ctx context.Context, event we.RecordedEvent) error {
+      received = append(received, event.EventID)
+      positions = append(positions, event.Position)
+      return nil
+    })
+
+    response, err := handler(ctx, event)
+    require.NoError(t, err)
+
+    assert.Empty(t, response.BatchItemFailures)
+    assert.Equal(t, []we.EventID{"1", "2", "3"}, received)
+    // stream sequence numbers only order the records of a shard, so they can't position events in the store
+    assert.Equal(t, []we.Position{we.NoPosition, we.NoPosition, we.NoPosition}, positions)
+  })
+
+  t.Run("reports the first failing record", func(t *testing.T) {
+    var received []we.EventID
+    handler := NewLambdaStreamHandler(func(ctx context.Context, event we.RecordedEvent) error {
+      if event.EventID == "3" {
+        return errors.New("failed")
+      }
+      received = append(received, event.EventID)
+      return nil
+    })
+
+    response, err := handler(ctx, event)
+    require.NoError(t, err)
+
+    assert.Equal(t, []events.DynamoDBBatchItemFailure{{ItemIdentifier: "102"}}, response.BatchItemFailures)
+    assert.Equal(t, []we.EventID{"1", "2"}, received)
+  })
+}
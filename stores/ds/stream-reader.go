@@ -0,0 +1,361 @@
+package ds
+
+import (
+  "context"
+  "strings"
+  "sync"
+  "time"
+
+  "github.com/aws/aws-sdk-go-v2/aws"
+  "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+  "github.com/aws/aws-sdk-go-v2/service/dynamodbstreams"
+  streams "github.com/aws/aws-sdk-go-v2/service/dynamodbstreams/types"
+  "github.com/pkg/errors"
+
+  "github.com/weegigs/wee-events-go/we"
+)
+
+type StreamReaderOption func(*StreamReader)
+
+// WithCheckpoints sets the store used to record the last sequence number processed for each shard. By default
+// checkpoints are held in memory.
+func WithCheckpoints(checkpoints we.CheckpointStore) StreamReaderOption {
+  return func(reader *StreamReader) {
+    reader.checkpoints = checkpoints
+  }
+}
+
+func WithPollInterval(interval time.Duration) StreamReaderOption {
+  return func(reader *StreamReader) {
+    reader.interval = interval
+  }
+}
+
+func WithBatchSize(size int32) StreamReaderOption {
+  return func(reader *StreamReader) {
+    reader.batch = size
+  }
+}
+
+// WithReaderName distinguishes the checkpoints of readers sharing a checkpoint store. It defaults to the table name.
+func WithReaderName(name string) StreamReaderOption {
+  return func(reader *StreamReader) {
+    reader.name = name
+  }
+}
+
+// WithEmptyPageLimit sets the number of consecutive empty pages read from an open shard before it's treated as caught
+// up for the poll. Shards can return empty pages before their latest records, so the reader continues from where it
+// stopped on the next poll rather than from its checkpoint.
+func WithEmptyPageLimit(pages int) StreamReaderOption {
+  return func(reader *StreamReader) {
+    if pages < 1 {
+      pages = 1
+    }
+
+    reader.emptyPages = pages
+  }
+}
+
+// StartAtLatest reads shards without a checkpoint from the tip of the stream rather than the oldest record.
+func StartAtLatest() StreamReaderOption {
+  return func(reader *StreamReader) {
+    reader.start = streams.ShardIteratorTypeLatest
+  }
+}
+
+// StreamReader consumes the events table's DynamoDB stream, delivering the events in each change set to a handler.
+// Events are delivered in order for each aggregate, and progress is checkpointed by shard and sequence number.
+type StreamReader struct {
+  name        string
+  db          *dynamodb.Client
+  streams     *dynamodbstreams.Client
+  table       string
+  handler     we.EventHandler
+  checkpoints we.CheckpointStore
+  interval    time.Duration
+  batch       int32
+  start       streams.ShardIteratorType
+  emptyPages  int
+
+  lk        sync.Mutex
+  iterators map[string]*string
+}
+
+func NewStreamReader(db *dynamodb.Client, client *dynamodbstreams.Client, table EventStoreTableName, handler we.EventHandler, options ...StreamReaderOption) *StreamReader {
+  reader := &StreamReader{
+    name:        table.String(),
+    db:          db,
+    streams:     client,
+    table:       table.String(),
+    handler:     handler,
+    checkpoints: we.NewMemoryCheckpointStore(),
+    interval:    time.Second,
+    batch:       100,
+    start:       streams.ShardIteratorTypeTrimHorizon,
+    emptyPages:  3,
+    iterators:   map[string]*string{},
+  }
+
+  for _, option := range options {
+    option(reader)
+  }
+
+  return reader
+}
+
+const shardFinished = we.Checkpoint("finished")
+
+// Run polls the stream until the context is cancelled or the handler fails.
+func (r *StreamReader) Run(ctx context.Context) error {
+  for {
+    if _, err := r.Poll(ctx); err != nil {
+      return err
+    }
+
+    select {
+    case <-ctx.Done():
+      return ctx.Err()
+    case <-time.After(r.interval):
+    }
+  }
+}
+
+// Poll reads the records currently available on every shard, returning the number of events delivered. Child shards
+// are only read once their parent has been read to the end.
+func (r *StreamReader) Poll(ctx context.Context) (int, error) {
+  arn, err := r.streamArn(ctx)
+  if err != nil {
+    return 0, err
+  }
+
+  shards, err := r.shards(ctx, arn)
+  if err != nil {
+    return 0, err
+  }
+
+  known := map[string]bool{}
+  for _, shard := range shards {
+    known[aws.ToString(shard.ShardId)] = true
+  }
+
+  count := 0
+  read := map[string]bool{}
+  finished := map[string]bool{}
+  for progress := true; progress; {
+    progress = false
+    for _, shard := range shards {
+      id := aws.ToString(shard.ShardId)
+      parent := aws.ToString(shard.ParentShardId)
+      if read[id] || (known[parent] && !finished[parent]) {
+        continue
+      }
+
+      n, done, err := r.readShard(ctx, arn, id)
+      count += n
+      if err != nil {
+        return count, err
+      }
+
+      read[id] = true
+      finished[id] = done
+      progress = true
+    }
+  }
+
+  return count, nil
+}
+
+func (r *StreamReader) streamArn(ctx context.Context) (string, error) {
+  description, err := r.db.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(r.table)})
+  if err != nil {
+    return "", errors.Wrap(err, "failed to describe events table")
+  }
+
+  if description.Table.LatestStreamArn == nil {
+    return "", errors.New("events table does not have a stream enabled")
+  }
+
+  return *description.Table.LatestStreamArn, nil
+}
+
+func (r *StreamReader) shards(ctx context.Context, arn string) ([]streams.Shard, error) {
+  var shards []streams.Shard
+  var start *string
+  for {
+    out, err := r.streams.DescribeStream(ctx, &dynamodbstreams.DescribeStreamInput{
+      StreamArn:             aws.String(arn),
+      ExclusiveStartShardId: start,
+    })
+    if err != nil {
+      return nil, errors.Wrap(err, "failed to describe events stream")
+    }
+
+    shards = append(shards, out.StreamDescription.Shards...)
+
+    start = out.StreamDescription.LastEvaluatedShardId
+    if start == nil {
+      break
+    }
+  }
+
+  return shards, nil
+}
+
+func (r *StreamReader) iterator(ctx context.Context, arn string, shard string, checkpoint we.Checkpoint) (*string, error) {
+  input := &dynamodbstreams.GetShardIteratorInput{
+    StreamArn:         aws.String(arn),
+    ShardId:           aws.String(shard),
+    ShardIteratorType: r.start,
+  }
+
+  if checkpoint != we.NoCheckpoint {
+    input.ShardIteratorType = streams.ShardIteratorTypeAfterSequenceNumber
+    input.SequenceNumber = aws.String(checkpoint.String())
+  }
+
+  out, err := r.streams.GetShardIterator(ctx, input)
+  if err != nil {
+    return nil, errors.Wrap(err, "failed to get shard iterator")
+  }
+
+  return out.ShardIterator, nil
+}
+
+func (r *StreamReader) readShard(ctx context.Context, arn string, shard string) (int, bool, error) {
+  name := r.name + "/" + shard
+  checkpoint, err := r.checkpoints.LoadCheckpoint(ctx, name)
+  if err != nil {
+    return 0, false, err
+  }
+
+  if checkpoint == shardFinished {
+    return 0, true, nil
+  }
+
+  // the iterator the last poll stopped at is past any empty pages it read, so reading continues from it
+  iterator := r.resume(shard)
+  if iterator == nil {
+    if iterator, err = r.iterator(ctx, arn, shard, checkpoint); err != nil {
+      return 0, false, err
+    }
+  }
+
+  count := 0
+  empty := 0
+  for iterator != nil {
+    out, err := r.streams.GetRecords(ctx, &dynamodbstreams.GetRecordsInput{
+      ShardIterator: iterator,
+      Limit:         aws.Int32(r.batch),
+    })
+    var expired *streams.ExpiredIteratorException
+    if errors.As(err, &expired) {
+      if iterator, err = r.iterator(ctx, arn, shard, checkpoint); err != nil {
+        return count, false, err
+      }
+      continue
+    }
+    if err != nil {
+      return count, false, errors.Wrap(err, "failed to get stream records")
+    }
+
+    for _, record := range out.Records {
+      if record.Dynamodb == nil {
+        continue
+      }
+
+      n, err := deliver(ctx, r.handler, streamImage(record.Dynamodb.NewImage))
+      count += n
+      if err != nil {
+        return count, false, err
+      }
+
+      checkpoint = we.Checkpoint(aws.ToString(record.Dynamodb.SequenceNumber))
+      if err := r.checkpoints.SaveCheckpoint(ctx, name, checkpoint); err != nil {
+        return count, false, err
+      }
+    }
+
+    iterator = out.NextShardIterator
+    if len(out.Records) > 0 {
+      empty = 0
+      continue
+    }
+
+    empty++
+    if iterator != nil && empty >= r.emptyPages {
+      r.suspend(shard, iterator)
+      return count, false, nil
+    }
+  }
+
+  return count, true, r.checkpoints.SaveCheckpoint(ctx, name, shardFinished)
+}
+
+// resume takes the iterator that reading the shard was suspended at, if there is one.
+func (r *StreamReader) resume(shard string) *string {
+  r.lk.Lock()
+  defer r.lk.Unlock()
+
+  iterator := r.iterators[shard]
+  delete(r.iterators, shard)
+
+  return iterator
+}
+
+func (r *StreamReader) suspend(shard string, iterator *string) {
+  r.lk.Lock()
+  defer r.lk.Unlock()
+
+  r.iterators[shard] = iterator
+}
+
+func streamImage(image map[string]streams.AttributeValue) map[string]string {
+  values := map[string]string{}
+  for name, value := range image {
+    if s, ok := value.(*streams.AttributeValueMemberS); ok {
+      values[name] = s.Value
+    }
+  }
+
+  return values
+}
+
+// changeSetFrom decodes a change set from the string attributes of a stream image, ignoring latest-revision and any
+// other records.
+func changeSetFrom(image map[string]string) (*ChangeSet, bool) {
+  if !strings.HasPrefix(image["sk"], "change-set#") {
+    return nil, false
+  }
+
+  return &ChangeSet{
+    PartitionKey: image["pk"],
+    SortKey:      image["sk"],
+    Tenant:       image["tenant"],
+    Events:       image["events"],
+    Revision:     we.Revision(image["revision"]),
+    Timestamp:    we.Timestamp(image["timestamp"]),
+  }, true
+}
+
+// deliver hands the events of the change set in a stream record to the handler. Stream sequence numbers only order
+// the records of a shard, so the events are left without a position.
+func deliver(ctx context.Context, handler we.EventHandler, image map[string]string) (int, error) {
+  changes, ok := changeSetFrom(image)
+  if !ok {
+    return 0, nil
+  }
+
+  events, err := changes.RecordedEvents()
+  if err != nil {
+    return 0, err
+  }
+
+  for i, event := range events {
+    if err := handler(ctx, event); err != nil {
+      return i, err
+    }
+  }
+
+  return len(events), nil
+}
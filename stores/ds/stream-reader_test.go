@@ -0,0 +1,142 @@
+package ds
+
+import (
+  "context"
+  "encoding/json"
+  "fmt"
+  "io"
+  "net/http"
+  "strings"
+  "testing"
+
+  "github.com/aws/aws-sdk-go-v2/aws"
+  "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+  "github.com/aws/aws-sdk-go-v2/service/dynamodbstreams"
+  "github.com/stretchr/testify/assert"
+  "github.com/stretchr/testify/require"
+
+  "github.com/weegigs/wee-events-go/we"
+)
+
+func TestStreamReader(t *testing.T) {
+  ctx := context.Background()
+  cfg, tearDown, err := DynamoTestConfig(ctx)
+  if err != nil {
+    t.Logf("failed to create test environment. %+v", err)
+    t.FailNow()
+  }
+  defer tearDown()
+
+  db := dynamodb.NewFromConfig(cfg)
+  table := EventStoreTableName("stream-events")
+  require.NoError(t, createTable(ctx, db, ExpectedSchema(table)))
+
+  store := NewEventStore(db, table)
+  aggregateId := createId()
+
+  err = store.Publish(ctx, aggregateId, we.Options(), Tested{TestStringValue: "first", TestIntValue: 1}, Tested{TestStringValue: "second", TestIntValue: 2})
+  require.NoError(t, err)
+  err = store.Publish(ctx, aggregateId, we.Options(), Tested{TestStringValue: "third", TestIntValue: 3})
+  require.NoError(t, err)
+
+  loaded, err := store.Load(ctx, aggregateId)
+  require.NoError(t, err)
+
+  var received []we.RecordedEvent
+  checkpoints := we.NewMemoryCheckpointStore()
+  reader := NewStreamReader(
+    db, dynamodbstreams.NewFromConfig(cfg), table,
+    func(ctx context.Context, event we.RecordedEvent) error {
+      received = append(received, event)
+      return nil
+    },
+    WithCheckpoints(checkpoints),
+  )
+
+  t.Run("delivers recorded events in order", func(t *testing.T) {
+    count, err := reader.Poll(ctx)
+    require.NoError(t, err)
+
+    assert.Equal(t, 3, count)
+    require.Len(t, received, 3)
+    for i, event := range received {
+      assert.Equal(t, loaded.Events[i], event)
+    }
+  })
+
+  t.Run("resumes from the checkpoint", func(t *testing.T) {
+    err := store.Publish(ctx, aggregateId, we.Options(), Tested{TestStringValue: "fourth", TestIntValue: 4})
+    require.NoError(t, err)
+
+    count, err := reader.Poll(ctx)
+    require.NoError(t, err)
+
+    assert.Equal(t, 1, count)
+    assert.Equal(t, 4, len(received))
+  })
+}
+
+// scriptedStream serves GetRecords pages from a script keyed by shard iterator, as a stream whose shard holds empty
+// pages before its latest record would. The shard is open, so iterators without a page read an empty one.
+type scriptedStream struct {
+  pages map[string]string
+}
+
+func (s scriptedStream) Do(request *http.Request) (*http.Response, error) {
+  var input struct{ ShardIterator string }
+  if err := json.NewDecoder(request.Body).Decode(&input); err != nil {
+    return nil, err
+  }
+
+  body := `{"ShardIterator":"0"}`
+  if strings.HasSuffix(request.Header.Get("X-Amz-Target"), ".GetRecords") {
+    page, ok := s.pages[input.ShardIterator]
+    if !ok {
+      page = fmt.Sprintf(`{"Records":[],"NextShardIterator":"%s-"}`, input.ShardIterator)
+    }
+    body = page
+  }
+
+  return &http.Response{
+    StatusCode: http.StatusOK,
+    Header:     http.Header{"Content-Type": []string{"application/x-amz-json-1.0"}},
+    Body:       io.NopCloser(strings.NewReader(body)),
+    Request:    request,
+  }, nil
+}
+
+func TestStreamReaderEmptyPages(t *testing.T) {
+  ctx := context.Background()
+  client := dynamodbstreams.New(dynamodbstreams.Options{
+    Region:           "us-east-1",
+    Credentials:      aws.AnonymousCredentials{},
+    EndpointResolver: dynamodbstreams.EndpointResolverFromURL("http://streams.test"),
+    HTTPClient: scriptedStream{pages: map[string]string{
+      "0": `{"Records":[],"NextShardIterator":"1"}`,
+      "1": `{"Records":[],"NextShardIterator":"2"}`,
+      "2": `{"Records":[],"NextShardIterator":"3"}`,
+      "3": `{"Records":[{"dynamodb":{"SequenceNumber":"300","NewImage":{"sk":{"S":"latest-revision"}}}}],"NextShardIterator":"4"}`,
+    }},
+  })
+
+  read := func(reader *StreamReader) we.Checkpoint {
+    _, done, err := reader.readShard(ctx, "arn", "shard")
+    require.NoError(t, err)
+    assert.False(t, done)
+
+    checkpoint, err := reader.checkpoints.LoadCheckpoint(ctx, reader.name+"/shard")
+    require.NoError(t, err)
+    return checkpoint
+  }
+
+  t.Run("reads past empty pages", func(t *testing.T) {
+    reader := NewStreamReader(nil, client, "events", nil, WithEmptyPageLimit(4))
+    assert.Equal(t, we.Checkpoint("300"), read(reader))
+  })
+
+  t.Run("continues from the empty pages read by the last poll", func(t *testing.T) {
+    reader := NewStreamReader(nil, client, "events", nil, WithEmptyPageLimit(3))
+    assert.Equal(t, we.NoCheckpoint, read(reader))
+    assert.Equal(t, we.Checkpoint("300"), read(reader))
+  })
+}
@@ -8,13 +8,32 @@ import (
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodbstreams"
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/wait"
 )
 
-func DynamoTestStore(ctx context.Context) (*DynamoEventStore, func(), error) {
+func DynamoTestStore(ctx context.Context, options ...EventStoreOption) (*DynamoEventStore, func(), error) {
+	cfg, tearDown, err := DynamoTestConfig(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	client := dynamodb.NewFromConfig(cfg)
+
+	table := EventStoreTableName("test-events")
+	if err := createTable(ctx, client, ExpectedSchema(table)); err != nil {
+		tearDown()
+		return nil, nil, err
+	}
+
+	store := NewEventStore(client, table, options...)
 
+	return store, tearDown, nil
+}
+
+// DynamoTestConfig starts dynamodb-local and returns a configuration for its DynamoDB and DynamoDB Streams endpoints.
+func DynamoTestConfig(ctx context.Context) (aws.Config, func(), error) {
 	db, err := testcontainers.GenericContainer(
 		ctx, testcontainers.GenericContainerRequest{
 			ContainerRequest: testcontainers.ContainerRequest{
@@ -26,25 +45,33 @@ func DynamoTestStore(ctx context.Context) (*DynamoEventStore, func(), error) {
 		},
 	)
 	if err != nil {
-		return nil, nil, err
+		return aws.Config{}, nil, err
+	}
+
+	tearDown := func() {
+		if err := db.Terminate(ctx); err != nil {
+			panic(err)
+		}
 	}
 
 	host, err := db.Host(ctx)
 	if err != nil {
-		return nil, nil, err
+		tearDown()
+		return aws.Config{}, nil, err
 	}
 
 	port, err := db.MappedPort(ctx, "8000")
 	if err != nil {
-		return nil, nil, err
+		tearDown()
+		return aws.Config{}, nil, err
 	}
 
 	customResolver := aws.EndpointResolverWithOptionsFunc(
 		func(service, region string, options ...interface{}) (aws.Endpoint, error) {
-			if service == dynamodb.ServiceID {
+			if service == dynamodb.ServiceID || service == dynamodbstreams.ServiceID {
 				return aws.Endpoint{
 					PartitionID:   "aws",
-					URL:           fmt.Sprintf("http://%s:%s", host, port),
+					URL:           fmt.Sprintf("http://%s:%s", host, port.Port()),
 					SigningRegion: "us-east-1",
 				}, nil
 			}
@@ -64,37 +91,9 @@ func DynamoTestStore(ctx context.Context) (*DynamoEventStore, func(), error) {
 		}),
 	)
 	if err != nil {
-		return nil, nil, err
+		tearDown()
+		return aws.Config{}, nil, err
 	}
 
-	client := dynamodb.NewFromConfig(cfg)
-
-	table, err := client.CreateTable(
-		ctx, &dynamodb.CreateTableInput{
-			TableName: aws.String("test-events"),
-			AttributeDefinitions: []types.AttributeDefinition{
-				{AttributeName: aws.String("pk"), AttributeType: types.ScalarAttributeTypeS},
-				{AttributeName: aws.String("sk"), AttributeType: types.ScalarAttributeTypeS},
-			},
-			KeySchema: []types.KeySchemaElement{
-				{AttributeName: aws.String("pk"), KeyType: types.KeyTypeHash},
-				{AttributeName: aws.String("sk"), KeyType: types.KeyTypeRange},
-			},
-			BillingMode: types.BillingModePayPerRequest,
-		},
-	)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	store := NewEventStore(
-		client,
-		EventStoreTableName(*table.TableDescription.TableName),
-	)
-
-	return store, func() {
-		if err := db.Terminate(ctx); err != nil {
-			panic(err)
-		}
-	}, nil
+	return cfg, tearDown, nil
 }
@@ -0,0 +1,96 @@
+package esdbs
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"github.com/EventStore/EventStore-Client-Go/esdb"
+	"github.com/pkg/errors"
+
+	"github.com/weegigs/wee-events-go/we"
+)
+
+// ListsChanges reports that listings after a checkpoint include the aggregates written to since, as $all is read in
+// order.
+func (es *ESDBEventStore) ListsChanges() bool {
+	return true
+}
+
+// ListAggregates lists the aggregates with events in $all, in the order they were first written to after the
+// checkpoint. Each checkpoint is the $all position of the event the aggregate was first seen at, so a resumed listing
+// can list an aggregate again if it was written to after the listing stopped.
+func (es *ESDBEventStore) ListAggregates(ctx context.Context, after we.Checkpoint, handler we.AggregateListHandler) error {
+	var from esdb.AllPosition = esdb.Start{}
+	var skip *esdb.Position
+	if after != we.NoCheckpoint {
+		position, err := ParsePosition(after)
+		if err != nil {
+			return err
+		}
+
+		// reads from a position include the event at it
+		from, skip = position, &position
+	}
+
+	seen := map[string]struct{}{}
+	for {
+		stream, err := es.db.ReadAll(ctx, esdb.ReadAllOptions{From: from}, uint64(es.pageSize))
+		if err != nil {
+			return errors.Wrap(err, "failed to read $all")
+		}
+
+		read, last, err := es.listPage(ctx, stream, skip, seen, handler)
+		stream.Close()
+		if err != nil {
+			return err
+		}
+
+		if read < es.pageSize {
+			return nil
+		}
+
+		from, skip = last, &last
+	}
+}
+
+func (es *ESDBEventStore) listPage(ctx context.Context, stream *esdb.ReadStream, skip *esdb.Position, seen map[string]struct{}, handler we.AggregateListHandler) (int, esdb.Position, error) {
+	read := 0
+	var last esdb.Position
+	for {
+		resolved, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return read, last, nil
+		}
+		if err != nil {
+			return read, last, errors.Wrap(err, "failed to read $all")
+		}
+
+		read++
+		event := resolved.OriginalEvent()
+		last = event.Position
+
+		if skip != nil && event.Position == *skip {
+			continue
+		}
+
+		if strings.HasPrefix(event.StreamID, "$") {
+			continue
+		}
+
+		if _, ok := seen[event.StreamID]; ok {
+			continue
+		}
+		seen[event.StreamID] = struct{}{}
+
+		id, err := we.EncodedAggregateId(event.StreamID).Decode()
+		if err != nil {
+			// streams that weren't written by the store aren't aggregates
+			continue
+		}
+
+		if err := handler(ctx, *id, PositionCheckpoint(event.Position)); err != nil {
+			return read, last, err
+		}
+	}
+}
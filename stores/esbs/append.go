@@ -0,0 +1,114 @@
+package esdbs
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/EventStore/EventStore-Client-Go/esdb"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/weegigs/wee-events-go/we"
+)
+
+// userMetadata is the JSON user metadata of an event. Correlation and causation use the system's $ properties. The
+// identity, time and encoding of imported events are kept too, as EventStoreDB assigns its own creation date, only
+// accepts UUID event ids and only distinguishes json from binary content.
+type userMetadata struct {
+	CorrelationId we.CorrelationID `json:"$correlationId,omitempty"`
+	CausationId   we.EventID       `json:"$causationId,omitempty"`
+	Actor         we.Actor         `json:"actor,omitempty"`
+	TraceParent   we.TraceParent   `json:"traceparent,omitempty"`
+	Extensions    we.Extensions    `json:"extensions,omitempty"`
+
+	EventId   we.EventID   `json:"eventId,omitempty"`
+	Timestamp we.Timestamp `json:"timestamp,omitempty"`
+	Encoding  string       `json:"encoding,omitempty"`
+}
+
+func userMetadataOf(metadata we.RecordedEventMetadata) userMetadata {
+	return userMetadata{
+		CorrelationId: metadata.CorrelationId,
+		CausationId:   metadata.CausationId,
+		Actor:         metadata.Actor,
+		TraceParent:   metadata.TraceParent,
+		Extensions:    metadata.Extensions,
+	}
+}
+
+func (m userMetadata) empty() bool {
+	return m.CorrelationId == "" && m.CausationId == "" && m.Actor == "" && m.TraceParent == "" &&
+		len(m.Extensions) == 0 && m.EventId == "" && m.Timestamp == "" && m.Encoding == ""
+}
+
+func (m userMetadata) recorded() we.RecordedEventMetadata {
+	return we.RecordedEventMetadata{
+		CorrelationId: m.CorrelationId,
+		CausationId:   m.CausationId,
+		Actor:         m.Actor,
+		TraceParent:   m.TraceParent,
+		Extensions:    m.Extensions,
+	}
+}
+
+// Append imports the events into the aggregate's stream, keeping their ids, types, timestamps and metadata. Event ids
+// that are UUIDs are used as the EventStoreDB event id, others are kept in the user metadata.
+func (es *ESDBEventStore) Append(ctx context.Context, id we.AggregateId, expected we.Revision, events []we.RecordedEvent) (we.Revision, error) {
+	streamId := id.Encode().String()
+
+	last, err := es.appendRecorded(ctx, streamId, expected, events)
+	if err != nil {
+		return "", err
+	}
+
+	return es.revisionAt(ctx, streamId, last)
+}
+
+// appendRecorded writes the events to the stream, returning the event number of the last event written.
+func (es *ESDBEventStore) appendRecorded(ctx context.Context, streamId string, expected we.Revision, events []we.RecordedEvent) (uint64, error) {
+	if len(events) == 0 {
+		return 0, errors.New("attempted to append empty list of events")
+	}
+
+	esevents := make([]esdb.EventData, len(events))
+	for i, event := range events {
+		metadata := userMetadataOf(event.Metadata)
+		metadata.Timestamp = event.Timestamp
+
+		if event.Data.Encoding != jsonEncoding {
+			metadata.Encoding = event.Data.Encoding
+		}
+
+		eventId, err := uuid.FromString(event.EventID.String())
+		if err != nil {
+			eventId = uuid.Must(uuid.NewV4())
+			metadata.EventId = event.EventID
+		}
+
+		md, err := json.Marshal(metadata)
+		if err != nil {
+			return 0, errors.Wrap(err, "failed to marshal metadata")
+		}
+
+		esevents[i] = esdb.EventData{
+			EventID:     eventId,
+			ContentType: contentType(event.Data.Encoding),
+			EventType:   event.EventType.String(),
+			Data:        event.Data.Data,
+			Metadata:    md,
+		}
+	}
+
+	return es.append(ctx, streamId, expected, esevents)
+}
+
+const jsonEncoding = "application/json"
+
+// contentType maps the data encoding to the closest EventStoreDB content type.
+func contentType(encoding string) esdb.ContentType {
+	if encoding == jsonEncoding {
+		return esdb.JsonContentType
+	}
+
+	return esdb.BinaryContentType
+}
@@ -0,0 +1,50 @@
+package esdbs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/EventStore/EventStore-Client-Go/esdb"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/weegigs/wee-events-go/we"
+)
+
+func TestUserMetadata(t *testing.T) {
+	id := we.AggregateId{Type: "test", Key: "metadata"}
+	created := time.Date(2023, 3, 12, 1, 2, 3, 456_000_000, time.UTC)
+
+	event := func(metadata string) *esdb.RecordedEvent {
+		return &esdb.RecordedEvent{
+			EventID:      uuid.Must(uuid.NewV4()),
+			EventType:    "test:event",
+			ContentType:  "application/json",
+			CreatedDate:  created,
+			Data:         []byte(`{}`),
+			UserMetadata: []byte(metadata),
+		}
+	}
+
+	t.Run("reads metadata written before extensions", func(t *testing.T) {
+		recorded, err := recordedEvent(id, event(`{"$correlationId":"correlation","$causationId":"cause","eventId":"imported","timestamp":"2020-01-02T03:04:05.678Z"}`))
+		require.NoError(t, err)
+
+		assert.Equal(t, we.RecordedEventMetadata{CorrelationId: "correlation", CausationId: "cause"}, recorded.Metadata)
+		assert.Equal(t, we.EventID("imported"), recorded.EventID)
+		assert.Equal(t, we.Timestamp("2020-01-02T03:04:05.678Z"), recorded.Timestamp)
+	})
+
+	t.Run("reads actor, trace parent and extensions", func(t *testing.T) {
+		recorded, err := recordedEvent(id, event(`{"actor":"user/1","traceparent":"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01","extensions":{"remote-address":"192.0.2.1:4321"}}`))
+		require.NoError(t, err)
+
+		assert.Equal(t, we.RecordedEventMetadata{
+			Actor:       "user/1",
+			TraceParent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			Extensions:  we.Extensions{we.RemoteAddressExtension: "192.0.2.1:4321"},
+		}, recorded.Metadata)
+		assert.Equal(t, we.TimestampFromTime(created), recorded.Timestamp)
+	})
+}
@@ -3,11 +3,10 @@ package esdbs
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 	"io"
-	"strconv"
 
 	"github.com/EventStore/EventStore-Client-Go/esdb"
+	"github.com/gofrs/uuid"
 	"github.com/pkg/errors"
 
 	"github.com/weegigs/wee-events-go/we"
@@ -27,6 +26,22 @@ func PageSize(size int) EventStoreOption {
 	}
 }
 
+// WithClock timestamps published events with the clock rather than the date EventStoreDB created them. Revisions are
+// still taken from the created date.
+func WithClock(clock we.Clock) EventStoreOption {
+	return func(es *ESDBEventStore) {
+		es.clock = clock
+	}
+}
+
+// WithIdGenerator sets the generator of the ids of published events rather than leaving them to the client. Ids that
+// aren't UUIDs are kept in the user metadata, as they are by Append.
+func WithIdGenerator(generator we.IDGenerator) EventStoreOption {
+	return func(es *ESDBEventStore) {
+		es.ids = generator
+	}
+}
+
 func NewEventStore(client *esdb.Client, options ...EventStoreOption) *ESDBEventStore {
 	store := &ESDBEventStore{
 		db:       client,
@@ -43,24 +58,45 @@ func NewEventStore(client *esdb.Client, options ...EventStoreOption) *ESDBEventS
 type ESDBEventStore struct {
 	db       *esdb.Client
 	pageSize int
+	clock    we.Clock
+	ids      we.IDGenerator
 }
 
 func (es *ESDBEventStore) Publish(ctx context.Context, aggregateId we.AggregateId, options we.PublishOptions, events ...we.DomainEvent) error {
+	_, err := es.publish(ctx, aggregateId.Encode().String(), options, events)
+	return err
+}
+
+// PublishRevision appends the events to the aggregate's stream and returns the revision of the last event written.
+// The revision includes the time the event was created, so the event is read back from the stream.
+func (es *ESDBEventStore) PublishRevision(ctx context.Context, aggregateId we.AggregateId, options we.PublishOptions, events ...we.DomainEvent) (we.Revision, error) {
 	streamId := aggregateId.Encode().String()
-	metadata := map[string]string{}
-	if options.RecordedEventMetadata.CorrelationId != "" {
-		metadata["$correlationId"] = options.RecordedEventMetadata.CorrelationId.String()
+
+	last, err := es.publish(ctx, streamId, options, events)
+	if err != nil {
+		return "", err
 	}
-	if options.RecordedEventMetadata.CausationId != "" {
-		metadata["$causationId"] = options.RecordedEventMetadata.CausationId.String()
+
+	return es.revisionAt(ctx, streamId, last)
+}
+
+// publish appends the events to the stream, returning the event number of the last event written.
+func (es *ESDBEventStore) publish(ctx context.Context, streamId string, options we.PublishOptions, events []we.DomainEvent) (uint64, error) {
+	if es.clock != nil || es.ids != nil {
+		recorded, err := es.recorded(options, events)
+		if err != nil {
+			return 0, err
+		}
+
+		return es.appendRecorded(ctx, streamId, options.ExpectedRevision, recorded)
 	}
 
 	var err error
 	var md []byte
-	if len(metadata) > 0 {
+	if metadata := userMetadataOf(options.RecordedEventMetadata); !metadata.empty() {
 		md, err = json.Marshal(metadata)
 		if err != nil {
-			return errors.Wrap(err, "failed to marshal metadata")
+			return 0, errors.Wrap(err, "failed to marshal metadata")
 		}
 	}
 
@@ -68,7 +104,7 @@ func (es *ESDBEventStore) Publish(ctx context.Context, aggregateId we.AggregateI
 	for i, event := range events {
 		data, err := json.Marshal(event)
 		if err != nil {
-			return errors.Wrap(err, "failed to marshal event")
+			return 0, errors.Wrap(err, "failed to marshal event")
 		}
 
 		esevents[i] = esdb.EventData{
@@ -79,36 +115,85 @@ func (es *ESDBEventStore) Publish(ctx context.Context, aggregateId we.AggregateI
 		}
 	}
 
-	var revision esdb.ExpectedRevision = esdb.Any{}
-	if options.ExpectedRevision == we.InitialRevision {
-		revision = esdb.NoStream{}
-	} else if options.ExpectedRevision != "" {
-		r, err := strconv.ParseUint(options.ExpectedRevision.String(), 10, 64)
+	return es.append(ctx, streamId, options.ExpectedRevision, esevents)
+}
+
+// recorded makes the events as they will be recorded, timestamped with the store's clock and identified by its id
+// generator, so they can be written with Append.
+func (es *ESDBEventStore) recorded(options we.PublishOptions, events []we.DomainEvent) ([]we.RecordedEvent, error) {
+	clock := es.clock
+	if clock == nil {
+		clock = we.SystemClock
+	}
+	timestamp := we.TimestampFromTime(clock.Now())
+
+	recorded := make([]we.RecordedEvent, len(events))
+	for i, event := range events {
+		data, err := we.MarshalToData(event)
 		if err != nil {
-			return errors.Wrap(err, "invalid expected revision")
+			return nil, errors.Wrap(err, "failed to marshal event")
+		}
+
+		id := we.EventID(uuid.Must(uuid.NewV4()).String())
+		if es.ids != nil {
+			id = es.ids.Create()
 		}
-		r = r - 1 // KAO - revisions are incremented by one when emitted
-		if r < 0 {
-			return errors.New("invalid expected revision")
+
+		recorded[i] = we.RecordedEvent{
+			EventID:   id,
+			EventType: we.EventTypeOf(event),
+			Timestamp: timestamp,
+			Metadata:  options.RecordedEventMetadata,
+			Data:      data,
 		}
+	}
+
+	return recorded, nil
+}
 
-		revision = esdb.Revision(r)
+// append writes the events to the stream, returning the event number of the last event written. It fails with a
+// revision conflict if the stream isn't at the expected revision.
+func (es *ESDBEventStore) append(ctx context.Context, streamId string, expected we.Revision, esevents []esdb.EventData) (uint64, error) {
+	revision, err := expectedRevision(expected)
+	if err != nil {
+		return 0, err
 	}
 
 	esdbOptions := esdb.AppendToStreamOptions{
 		ExpectedRevision: revision,
 	}
 
-	_, err = es.db.AppendToStream(ctx, streamId, esdbOptions, esevents...)
+	result, err := es.db.AppendToStream(ctx, streamId, esdbOptions, esevents...)
 	if err != nil {
-		if err == esdb.ErrWrongExpectedStreamRevision {
-			return we.RevisionConflict
+		if errors.Is(err, esdb.ErrWrongExpectedStreamRevision) {
+			if es.deleted(ctx, streamId) {
+				return 0, we.AggregateDeleted
+			}
+			return 0, we.RevisionConflict
 		}
 
-		return errors.Wrap(err, "failed to append to stream")
+		return 0, errors.Wrap(err, "failed to append to stream")
+	}
+
+	return result.NextExpectedVersion, nil
+}
+
+// revisionAt reads back the event at the event number as its revision includes the time it was created, which the
+// append result doesn't report.
+func (es *ESDBEventStore) revisionAt(ctx context.Context, streamId string, eventNumber uint64) (we.Revision, error) {
+	stream, err := es.db.ReadStream(ctx, streamId, esdb.ReadStreamOptions{From: esdb.Revision(eventNumber)}, 1)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read published event")
+	}
+	defer stream.Close()
+
+	event, err := stream.Recv()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read published event")
 	}
 
-	return nil
+	e := event.OriginalEvent()
+	return encodeRevision(e.CreatedDate, e.EventNumber)
 }
 
 func (es *ESDBEventStore) Load(ctx context.Context, id we.AggregateId) (we.Aggregate, error) {
@@ -164,6 +249,11 @@ func (es *ESDBEventStore) read(ctx context.Context, aggregate we.AggregateId, fr
 			return nil, esdb.End{}, nil
 		}
 
+		var deleted *esdb.StreamDeletedError
+		if errors.As(err, &deleted) {
+			return nil, esdb.End{}, we.AggregateDeleted
+		}
+
 		return nil, esdb.End{}, errors.Wrap(err, "failed to read stream")
 	}
 	defer stream.Close()
@@ -183,34 +273,9 @@ func (es *ESDBEventStore) read(ctx context.Context, aggregate we.AggregateId, fr
 		}
 
 		e := event.OriginalEvent()
-		// KAO - the first event in an es stream is event number 0, 0 would translate to initial revision,
-		// so I'm incrementing by one to get a usable revision.
-		// It *may* be possible to convert this to a ulid of sorts depending on the order of the CreatedDate
-		revision := we.Revision(fmt.Sprintf("%026x", e.EventNumber+1))
-
-		var userMetadata map[string]string
-		if len(e.UserMetadata) > 0 {
-			if err := json.Unmarshal(e.UserMetadata, &userMetadata); err != nil {
-				return nil, esdb.End{}, errors.Wrap(err, "failed to unmarshal metadata")
-			}
-		}
-
-		metadata := we.RecordedEventMetadata{
-			CorrelationId: we.CorrelationID(userMetadata["$correlationId"]),
-			CausationId:   we.EventID(userMetadata["$causationId"]),
-		}
-
-		recorded := we.RecordedEvent{
-			AggregateId: aggregate,
-			EventID:     we.EventID(e.EventID.String()),
-			Revision:    revision,
-			Timestamp:   we.TimestampFromTime(e.CreatedDate),
-			EventType:   we.EventType(e.EventType),
-			Data: we.Data{
-				Encoding: e.ContentType,
-				Data:     e.Data,
-			},
-			Metadata: metadata,
+		recorded, err := recordedEvent(aggregate, e)
+		if err != nil {
+			return nil, esdb.End{}, err
 		}
 
 		events = append(events, recorded)
@@ -220,3 +285,50 @@ func (es *ESDBEventStore) read(ctx context.Context, aggregate we.AggregateId, fr
 
 	return events, last, nil
 }
+
+// recordedEvent converts an esdb event, mapping the $correlationId and $causationId user metadata. Imported events
+// keep their original id, timestamp and encoding in the user metadata.
+func recordedEvent(aggregate we.AggregateId, e *esdb.RecordedEvent) (we.RecordedEvent, error) {
+	revision, err := encodeRevision(e.CreatedDate, e.EventNumber)
+	if err != nil {
+		return we.RecordedEvent{}, err
+	}
+
+	var user userMetadata
+	if len(e.UserMetadata) > 0 {
+		if err := json.Unmarshal(e.UserMetadata, &user); err != nil {
+			return we.RecordedEvent{}, errors.Wrap(err, "failed to unmarshal metadata")
+		}
+	}
+
+	metadata := user.recorded()
+
+	eventId := user.EventId
+	if eventId == "" {
+		eventId = we.EventID(e.EventID.String())
+	}
+
+	timestamp := user.Timestamp
+	if timestamp == "" {
+		timestamp = we.TimestampFromTime(e.CreatedDate)
+	}
+
+	encoding := user.Encoding
+	if encoding == "" {
+		encoding = e.ContentType
+	}
+
+	return we.RecordedEvent{
+		AggregateId: aggregate,
+		EventID:     eventId,
+		Revision:    revision,
+		Timestamp:   timestamp,
+		EventType:   we.EventType(e.EventType),
+		Data: we.Data{
+			Encoding: encoding,
+			Data:     e.Data,
+		},
+		Metadata: metadata,
+		Position: we.CommitPosition(e.Position.Commit, e.Position.Prepare),
+	}, nil
+}
@@ -6,6 +6,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/weegigs/wee-events-go/internal"
 	"github.com/weegigs/wee-events-go/we"
 )
 
@@ -43,7 +45,12 @@ func TestEventStore(t *testing.T) {
 
 		assert.NotNil(t, aggregate)
 		assert.Equal(t, 10, len(aggregate.Events))
-		assert.Equal(t, we.Revision("0000000000000000000000000a"), aggregate.Revision)
+
+		sequence, err := internal.DecodeSequenceNumber(aggregate.Revision)
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, uint64(10), sequence)
 	})
 
 }
@@ -0,0 +1,83 @@
+package esdbs
+
+import (
+	"context"
+
+	"github.com/EventStore/EventStore-Client-Go/esdb"
+	"github.com/pkg/errors"
+
+	"github.com/weegigs/wee-events-go/internal"
+	"github.com/weegigs/wee-events-go/we"
+)
+
+// RemoveAggregate soft deletes, tombstones or truncates the aggregate's stream. Truncation sets the stream's $tb
+// metadata, leaving the server to scavenge the events before it.
+func (es *ESDBEventStore) RemoveAggregate(ctx context.Context, id we.AggregateId, removal we.Removal) error {
+	streamId := id.Encode().String()
+
+	switch removal.Mode {
+	case we.SoftDelete:
+		_, err := es.db.DeleteStream(ctx, streamId, esdb.DeleteStreamOptions{})
+		return es.removed(err, "failed to delete stream")
+
+	case we.HardDelete:
+		_, err := es.db.TombstoneStream(ctx, streamId, esdb.TombstoneStreamOptions{})
+		return es.removed(err, "failed to tombstone stream")
+
+	case we.TruncateBefore:
+		if removal.Before == we.InitialRevision {
+			return nil
+		}
+
+		sequence, err := internal.DecodeSequenceNumber(removal.Before)
+		if err != nil || sequence == 0 {
+			return InvalidRevision
+		}
+
+		metadata, err := es.db.GetStreamMetadata(ctx, streamId, esdb.ReadStreamOptions{From: esdb.End{}, Direction: esdb.Backwards})
+		if errors.Is(err, esdb.ErrStreamNotFound) {
+			metadata, err = &esdb.StreamMetadata{}, nil
+		}
+		if err != nil {
+			return es.removed(err, "failed to read stream metadata")
+		}
+
+		metadata.SetTruncateBefore(sequence - 1)
+
+		return es.SetStreamMetadata(ctx, id, *metadata)
+
+	default:
+		return we.UnsupportedRemoval
+	}
+}
+
+// SetStreamMetadata replaces the metadata of the aggregate's stream, allowing $maxAge, $maxCount and $tb to be set.
+func (es *ESDBEventStore) SetStreamMetadata(ctx context.Context, id we.AggregateId, metadata esdb.StreamMetadata) error {
+	_, err := es.db.SetStreamMetadata(ctx, id.Encode().String(), esdb.AppendToStreamOptions{}, metadata)
+	return es.removed(err, "failed to set stream metadata")
+}
+
+func (es *ESDBEventStore) removed(err error, message string) error {
+	if err == nil {
+		return nil
+	}
+
+	var deleted *esdb.StreamDeletedError
+	if errors.As(err, &deleted) {
+		return we.AggregateDeleted
+	}
+
+	return errors.Wrap(err, message)
+}
+
+// deleted reports whether the stream has been tombstoned.
+func (es *ESDBEventStore) deleted(ctx context.Context, streamId string) bool {
+	stream, err := es.db.ReadStream(ctx, streamId, esdb.ReadStreamOptions{From: esdb.End{}, Direction: esdb.Backwards}, 1)
+	if err != nil {
+		var deleted *esdb.StreamDeletedError
+		return errors.As(err, &deleted)
+	}
+	stream.Close()
+
+	return false
+}
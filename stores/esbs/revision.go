@@ -0,0 +1,42 @@
+package esdbs
+
+import (
+	"time"
+
+	"github.com/EventStore/EventStore-Client-Go/esdb"
+	"github.com/oklog/ulid/v2"
+	"github.com/pkg/errors"
+
+	"github.com/weegigs/wee-events-go/internal"
+	"github.com/weegigs/wee-events-go/we"
+)
+
+var InvalidRevision = errors.New("invalid expected revision")
+
+// encodeRevision encodes an event's position in its stream as a ulid shaped revision, ordered by the time the event
+// was created. The first event in a stream is event number 0, which would collide with the initial revision, so the
+// sequence is the event number plus one.
+func encodeRevision(created time.Time, eventNumber uint64) (we.Revision, error) {
+	return internal.EncodeRevision(ulid.Timestamp(created), eventNumber+1, 0)
+}
+
+// expectedRevision decodes the revision from which an append is expected to follow.
+func expectedRevision(revision we.Revision) (esdb.ExpectedRevision, error) {
+	switch revision {
+	case "":
+		return esdb.Any{}, nil
+	case we.InitialRevision:
+		return esdb.NoStream{}, nil
+	}
+
+	sequence, err := internal.DecodeSequenceNumber(revision)
+	if err != nil {
+		return nil, errors.Wrap(InvalidRevision, err.Error())
+	}
+
+	if sequence == 0 {
+		return nil, InvalidRevision
+	}
+
+	return esdb.Revision(sequence - 1), nil
+}
@@ -0,0 +1,69 @@
+package esdbs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/EventStore/EventStore-Client-Go/esdb"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/weegigs/wee-events-go/we"
+)
+
+func TestRevisionEncoding(t *testing.T) {
+	created := time.Date(2023, 3, 12, 1, 2, 3, 456_000_000, time.UTC)
+
+	t.Run("round trips event numbers", func(t *testing.T) {
+		for _, eventNumber := range []uint64{0, 9, 10, 15, 16, 255, 1_000_000} {
+			revision, err := encodeRevision(created, eventNumber)
+			if !assert.Nil(t, err) {
+				return
+			}
+
+			expected, err := expectedRevision(revision)
+			if !assert.Nil(t, err) {
+				return
+			}
+			assert.Equal(t, esdb.Revision(eventNumber), expected)
+		}
+	})
+
+	t.Run("orders revisions by event number", func(t *testing.T) {
+		previous, err := encodeRevision(created, 0)
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		for eventNumber := uint64(1); eventNumber < 300; eventNumber++ {
+			revision, err := encodeRevision(created, eventNumber)
+			if !assert.Nil(t, err) {
+				return
+			}
+			assert.Less(t, previous.String(), revision.String())
+			previous = revision
+		}
+	})
+
+	t.Run("records the time events were created", func(t *testing.T) {
+		revision, err := encodeRevision(created, 3)
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, we.TimestampFromTime(created), revision.Timestamp())
+	})
+
+	t.Run("decodes the initial and unspecified revisions", func(t *testing.T) {
+		expected, err := expectedRevision(we.InitialRevision)
+		assert.Nil(t, err)
+		assert.Equal(t, esdb.NoStream{}, expected)
+
+		expected, err = expectedRevision("")
+		assert.Nil(t, err)
+		assert.Equal(t, esdb.Any{}, expected)
+	})
+
+	t.Run("rejects invalid revisions", func(t *testing.T) {
+		_, err := expectedRevision("not-a-revision")
+		assert.ErrorIs(t, err, InvalidRevision)
+	})
+}
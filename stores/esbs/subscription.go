@@ -0,0 +1,293 @@
+package esdbs
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/EventStore/EventStore-Client-Go/esdb"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/weegigs/wee-events-go/we"
+)
+
+type SubscriptionOption func(*subscription)
+
+type subscription struct {
+	category    string
+	checkpoints we.CheckpointStore
+	batchSize   uint32
+	nackAction  esdb.Nack_Action
+	createGroup bool
+	settings    esdb.SubscriptionSettings
+}
+
+func newSubscription(options []SubscriptionOption) subscription {
+	s := subscription{
+		nackAction: esdb.Nack_Retry,
+		settings:   esdb.SubscriptionSettingsDefault(),
+	}
+
+	for _, option := range options {
+		option(&s)
+	}
+
+	return s
+}
+
+// WithCategory limits a subscription to the aggregates of a single type.
+func WithCategory(aggregateType string) SubscriptionOption {
+	return func(s *subscription) {
+		s.category = aggregateType
+	}
+}
+
+// WithCheckpoints saves the $all position of a catch-up subscription as events are handled and resumes the
+// subscription from it. Without a checkpoint store a catch-up subscription starts from the beginning of $all.
+func WithCheckpoints(store we.CheckpointStore) SubscriptionOption {
+	return func(s *subscription) {
+		s.checkpoints = store
+	}
+}
+
+// WithBatchSize sets the number of events a persistent subscription buffers for the consumer.
+func WithBatchSize(size uint32) SubscriptionOption {
+	return func(s *subscription) {
+		s.batchSize = size
+	}
+}
+
+// WithNackAction sets what the server does with events the persistent subscription's handler fails, retrying them
+// by default.
+func WithNackAction(action esdb.Nack_Action) SubscriptionOption {
+	return func(s *subscription) {
+		s.nackAction = action
+	}
+}
+
+// CreateGroup creates the persistent subscription group, from the start of $all, if it doesn't exist.
+func CreateGroup(settings esdb.SubscriptionSettings) SubscriptionOption {
+	return func(s *subscription) {
+		s.createGroup = true
+		s.settings = settings
+	}
+}
+
+// filter excludes system streams and, for category subscriptions, streams of other aggregate types.
+func (s subscription) filter() *esdb.SubscriptionFilter {
+	if s.category != "" {
+		return &esdb.SubscriptionFilter{Type: esdb.StreamFilterType, Prefixes: []string{s.category + "."}}
+	}
+
+	return &esdb.SubscriptionFilter{Type: esdb.StreamFilterType, Regex: `^[^\$]`}
+}
+
+// NewCatchUpSubscription creates a subscription that delivers every event in $all, or in a category, to the handler
+// in order.
+func NewCatchUpSubscription(client *esdb.Client, name string, handler we.EventHandler, options ...SubscriptionOption) *CatchUpSubscription {
+	return &CatchUpSubscription{
+		db:           client,
+		name:         name,
+		handler:      handler,
+		subscription: newSubscription(options),
+	}
+}
+
+type CatchUpSubscription struct {
+	db      *esdb.Client
+	name    string
+	handler we.EventHandler
+	subscription
+}
+
+// Run delivers events until the context is cancelled, the subscription is dropped or the handler fails. Events are
+// delivered at least once, as the checkpoint is saved after the handler returns.
+func (s *CatchUpSubscription) Run(ctx context.Context) error {
+	var from esdb.AllPosition = esdb.Start{}
+	if s.checkpoints != nil {
+		checkpoint, err := s.checkpoints.LoadCheckpoint(ctx, s.name)
+		if err != nil {
+			return errors.Wrap(err, "failed to load checkpoint")
+		}
+
+		if checkpoint != we.NoCheckpoint {
+			position, err := ParsePosition(checkpoint)
+			if err != nil {
+				return err
+			}
+			from = position
+		}
+	}
+
+	sub, err := s.db.SubscribeToAll(ctx, esdb.SubscribeToAllOptions{From: from, Filter: s.filter()})
+	if err != nil {
+		return errors.Wrap(err, "failed to subscribe to $all")
+	}
+	defer sub.Close()
+
+	for {
+		event := sub.Recv()
+
+		switch {
+		case event.SubscriptionDropped != nil:
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return errors.Wrap(event.SubscriptionDropped.Error, "subscription dropped")
+
+		case event.CheckPointReached != nil:
+			// filtered subscriptions report progress through events they skip
+			if err := s.save(ctx, *event.CheckPointReached); err != nil {
+				return err
+			}
+
+		case event.EventAppeared != nil:
+			e := event.EventAppeared.OriginalEvent()
+
+			recorded, err := subscribedEvent(e)
+			if err != nil {
+				return err
+			}
+
+			if err := s.handler(ctx, recorded); err != nil {
+				return err
+			}
+
+			if err := s.save(ctx, e.Position); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *CatchUpSubscription) save(ctx context.Context, position esdb.Position) error {
+	if s.checkpoints == nil {
+		return nil
+	}
+
+	if err := s.checkpoints.SaveCheckpoint(ctx, s.name, PositionCheckpoint(position)); err != nil {
+		return errors.Wrap(err, "failed to save checkpoint")
+	}
+
+	return nil
+}
+
+// NewPersistentSubscription creates a competing consumer of a persistent subscription group over $all. The server
+// tracks the group's position, so consumers sharing a group each receive a share of the events.
+func NewPersistentSubscription(client *esdb.Client, group string, handler we.EventHandler, options ...SubscriptionOption) *PersistentSubscription {
+	return &PersistentSubscription{
+		db:           client,
+		group:        group,
+		handler:      handler,
+		subscription: newSubscription(options),
+	}
+}
+
+type PersistentSubscription struct {
+	db      *esdb.Client
+	group   string
+	handler we.EventHandler
+	subscription
+}
+
+// Run delivers events until the context is cancelled or the subscription is dropped. Events are acked once handled
+// and nacked, with the configured action, when the handler fails.
+func (s *PersistentSubscription) Run(ctx context.Context) error {
+	if s.createGroup {
+		if err := s.create(ctx); err != nil {
+			return err
+		}
+	}
+
+	sub, err := s.db.ConnectToPersistentSubscriptionToAll(ctx, s.group, esdb.ConnectToPersistentSubscriptionOptions{
+		BatchSize: s.batchSize,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to connect to persistent subscription %s", s.group)
+	}
+	defer sub.Close()
+
+	for {
+		event := sub.Recv()
+
+		if event.SubscriptionDropped != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return errors.Wrap(event.SubscriptionDropped.Error, "subscription dropped")
+		}
+
+		if event.EventAppeared == nil {
+			continue
+		}
+
+		recorded, err := subscribedEvent(event.EventAppeared.OriginalEvent())
+		if err == nil {
+			err = s.handler(ctx, recorded)
+		}
+
+		if err != nil {
+			log.WithError(err).WithField("group", s.group).Info("persistent subscription handler failed")
+
+			if err := sub.Nack(err.Error(), s.nackAction, event.EventAppeared); err != nil {
+				return errors.Wrap(err, "failed to nack event")
+			}
+			continue
+		}
+
+		if err := sub.Ack(event.EventAppeared); err != nil {
+			return errors.Wrap(err, "failed to ack event")
+		}
+	}
+}
+
+func (s *PersistentSubscription) create(ctx context.Context) error {
+	settings := s.settings
+	err := s.db.CreatePersistentSubscriptionAll(ctx, s.group, esdb.PersistentAllSubscriptionOptions{
+		Settings: &settings,
+		From:     esdb.Start{},
+		Filter:   s.filter(),
+	})
+	if err != nil && !alreadyExists(err) {
+		return errors.Wrapf(err, "failed to create persistent subscription %s", s.group)
+	}
+
+	return nil
+}
+
+func alreadyExists(err error) bool {
+	var grpc interface{ GRPCStatus() *status.Status }
+	return errors.As(err, &grpc) && grpc.GRPCStatus().Code() == codes.AlreadyExists
+}
+
+func subscribedEvent(e *esdb.RecordedEvent) (we.RecordedEvent, error) {
+	aggregate, err := we.EncodedAggregateId(e.StreamID).Decode()
+	if err != nil {
+		return we.RecordedEvent{}, errors.Wrapf(err, "unexpected stream %s", e.StreamID)
+	}
+
+	return recordedEvent(*aggregate, e)
+}
+
+// PositionCheckpoint records an $all position as a checkpoint, in the form of the events' positions.
+func PositionCheckpoint(position esdb.Position) we.Checkpoint {
+	return we.CommitPosition(position.Commit, position.Prepare).Checkpoint()
+}
+
+// ParsePosition reads the $all position recorded in a checkpoint, accepting the C:commit/P:prepare form recorded
+// before events had positions.
+func ParsePosition(checkpoint we.Checkpoint) (esdb.Position, error) {
+	var position esdb.Position
+	if _, err := fmt.Sscanf(checkpoint.String(), "C:%d/P:%d", &position.Commit, &position.Prepare); err == nil {
+		return position, nil
+	}
+
+	commit, prepare, err := we.PositionOf(checkpoint).Commit()
+	if err != nil {
+		return esdb.Position{}, errors.Wrapf(err, "invalid $all checkpoint %q", checkpoint)
+	}
+
+	return esdb.Position{Commit: commit, Prepare: prepare}, nil
+}
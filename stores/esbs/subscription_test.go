@@ -0,0 +1,162 @@
+package esdbs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/EventStore/EventStore-Client-Go/esdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/weegigs/wee-events-go/we"
+)
+
+func TestPositionCheckpoints(t *testing.T) {
+	position := esdb.Position{Commit: 1234, Prepare: 1200}
+
+	checkpoint := PositionCheckpoint(position)
+	assert.Equal(t, we.Checkpoint("00000000000000001234/00000000000000001200"), checkpoint)
+
+	parsed, err := ParsePosition(checkpoint)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, position, parsed)
+
+	legacy, err := ParsePosition("C:1234/P:1200")
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, position, legacy)
+
+	_, err = ParsePosition("shard-0001")
+	assert.NotNil(t, err)
+}
+
+// collector records events delivered to a handler until it has seen the expected number of them.
+type collector struct {
+	lk     sync.Mutex
+	events []we.RecordedEvent
+	done   chan struct{}
+	want   int
+}
+
+func newCollector(want int) *collector {
+	return &collector{done: make(chan struct{}), want: want}
+}
+
+func (c *collector) handle(_ context.Context, event we.RecordedEvent) error {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+
+	c.events = append(c.events, event)
+	if len(c.events) == c.want {
+		close(c.done)
+	}
+
+	return nil
+}
+
+func (c *collector) wait(t *testing.T) []we.RecordedEvent {
+	select {
+	case <-c.done:
+	case <-time.After(30 * time.Second):
+		t.Fatal("timed out waiting for events")
+	}
+
+	c.lk.Lock()
+	defer c.lk.Unlock()
+
+	return append([]we.RecordedEvent(nil), c.events...)
+}
+
+func TestSubscriptions(t *testing.T) {
+	ctx := context.Background()
+	store, cleanup, err := NewESDBTestStore(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	orders := we.AggregateId{Type: "order", Key: "subscriptions"}
+	customers := we.AggregateId{Type: "customer", Key: "subscriptions"}
+
+	correlation := we.Options(we.WithCausationId("correlation", "cause"))
+	require.NoError(t, store.Publish(ctx, orders, correlation, createEvents(3)...))
+	require.NoError(t, store.Publish(ctx, customers, we.Options(), createEvents(2)...))
+
+	t.Run("catches up with a category", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		c := newCollector(3)
+		subscription := NewCatchUpSubscription(store.db, "orders", c.handle, WithCategory("order"))
+		go func() { _ = subscription.Run(ctx) }()
+
+		events := c.wait(t)
+		for _, event := range events {
+			assert.Equal(t, orders, event.AggregateId)
+			assert.Equal(t, we.CorrelationID("correlation"), event.Metadata.CorrelationId)
+			assert.Equal(t, we.EventID("cause"), event.Metadata.CausationId)
+		}
+	})
+
+	t.Run("resumes from its checkpoint", func(t *testing.T) {
+		checkpoints := we.NewMemoryCheckpointStore()
+
+		ctx, cancel := context.WithCancel(ctx)
+		c := newCollector(5)
+		subscription := NewCatchUpSubscription(store.db, "all", c.handle, WithCheckpoints(checkpoints))
+		stopped := make(chan error)
+		go func() { stopped <- subscription.Run(ctx) }()
+
+		c.wait(t)
+		cancel()
+		assert.ErrorIs(t, <-stopped, context.Canceled)
+
+		checkpoint, err := checkpoints.LoadCheckpoint(ctx, "all")
+		require.NoError(t, err)
+		assert.NotEqual(t, we.NoCheckpoint, checkpoint)
+
+		require.NoError(t, store.Publish(context.Background(), customers, we.Options(), createEvents(1)...))
+
+		ctx, cancel = context.WithCancel(context.Background())
+		defer cancel()
+
+		c = newCollector(1)
+		subscription = NewCatchUpSubscription(store.db, "all", c.handle, WithCheckpoints(checkpoints))
+		go func() { _ = subscription.Run(ctx) }()
+
+		events := c.wait(t)
+		assert.Equal(t, customers, events[0].AggregateId)
+	})
+
+	t.Run("shares a persistent group between consumers and retries failures", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		c := newCollector(3)
+		var failed sync.Once
+		handler := func(ctx context.Context, event we.RecordedEvent) error {
+			retry := false
+			failed.Do(func() { retry = true })
+			if retry {
+				return errors.New("try again")
+			}
+
+			return c.handle(ctx, event)
+		}
+
+		settings := esdb.SubscriptionSettingsDefault()
+		for i := 0; i < 2; i++ {
+			consumer := NewPersistentSubscription(store.db, "orders", handler, WithCategory("order"), CreateGroup(settings))
+			go func() { _ = consumer.Run(ctx) }()
+		}
+
+		events := c.wait(t)
+		assert.Equal(t, 3, len(events))
+	})
+}
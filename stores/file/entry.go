@@ -0,0 +1,126 @@
+package file
+
+import (
+	"encoding/json"
+
+	"github.com/oklog/ulid/v2"
+
+	"github.com/weegigs/wee-events-go/internal"
+	"github.com/weegigs/wee-events-go/we"
+)
+
+// entry is the payload of a frame, either a change set or a removal of an aggregate.
+type entry struct {
+	AggregateId we.AggregateId `json:"aggregate"`
+	Timestamp   we.Timestamp   `json:"timestamp"`
+	Events      []record       `json:"events,omitempty"`
+	Removal     *removalRecord `json:"removal,omitempty"`
+}
+
+// record is an event in a change set. Positions are global, increasing by one for each event in the log.
+type record struct {
+	Position  uint64                   `json:"position"`
+	EventID   we.EventID               `json:"id"`
+	EventType we.EventType             `json:"type"`
+	Metadata  we.RecordedEventMetadata `json:"metadata"`
+	Data      we.Data                  `json:"data"`
+	// Timestamp is set for imported events, which keep the time they were originally recorded
+	Timestamp we.Timestamp `json:"timestamp,omitempty"`
+}
+
+type removalRecord struct {
+	Mode we.RemovalMode `json:"mode"`
+	// Through is the position of the aggregate's last event when it was deleted, or the first position retained when
+	// it was truncated
+	Through uint64 `json:"through"`
+}
+
+func decodeEntry(payload []byte) (entry, error) {
+	var e entry
+	err := json.Unmarshal(payload, &e)
+
+	return e, err
+}
+
+// recorded returns the event recorded by the change set. Revisions are recorded at the time of the change set rather
+// than the event, as imported events keep their original timestamps.
+func (e entry) recorded(r record) (we.RecordedEvent, error) {
+	timestamp := e.Timestamp
+	if r.Timestamp != "" {
+		timestamp = r.Timestamp
+	}
+
+	recordedAt, err := e.Timestamp.Time()
+	if err != nil {
+		return we.RecordedEvent{}, err
+	}
+
+	revision, err := internal.EncodeRevision(ulid.Timestamp(recordedAt), r.Position, 0)
+	if err != nil {
+		return we.RecordedEvent{}, err
+	}
+
+	return we.RecordedEvent{
+		AggregateId: e.AggregateId,
+		Revision:    revision,
+		EventID:     r.EventID,
+		EventType:   r.EventType,
+		Timestamp:   timestamp,
+		Metadata:    r.Metadata,
+		Data:        r.Data,
+		Position:    we.SequencePosition(r.Position, 0),
+	}, nil
+}
+
+// location is where a change set frame is stored.
+type location struct {
+	segment uint64
+	offset  int64
+}
+
+// stream is the index of an aggregate's change sets.
+type stream struct {
+	frames []location
+	// last is the position of the aggregate's last event, zero when it has none
+	last     uint64
+	revision we.Revision
+	// removed is the position of the last event removed by a deletion
+	removed uint64
+	// before is the first position retained by truncation
+	before  uint64
+	deleted bool
+}
+
+// live reports whether the event at the position hasn't been removed.
+func (s *stream) live(position uint64) bool {
+	return !s.deleted && position > s.removed && position >= s.before
+}
+
+func (s *stream) apply(e entry, at location) {
+	if e.Removal == nil {
+		s.frames = append(s.frames, at)
+		s.last = e.Events[len(e.Events)-1].Position
+		if recorded, err := e.recorded(e.Events[len(e.Events)-1]); err == nil {
+			s.revision = recorded.Revision
+		}
+		return
+	}
+
+	if e.Removal.Mode != we.TruncateBefore && e.Removal.Through > s.removed {
+		s.removed = e.Removal.Through
+	}
+
+	switch e.Removal.Mode {
+	case we.SoftDelete:
+		s.frames = nil
+		s.last = 0
+		s.revision = ""
+	case we.HardDelete:
+		s.frames = nil
+		s.deleted = true
+	case we.TruncateBefore:
+		if e.Removal.Through > s.before {
+			s.before = e.Removal.Through
+		}
+	}
+}
@@ -0,0 +1,493 @@
+package file
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/weegigs/wee-events-go/internal"
+	"github.com/weegigs/wee-events-go/we"
+)
+
+// FileEventStore stores change sets as frames in an append-only log of segment files, indexing each aggregate's
+// frames in memory. The index is rebuilt from the log when the store is opened, truncating any torn write left by a
+// crash.
+type FileEventStore struct {
+	dir         string
+	segmentSize int64
+	policy      SyncPolicy
+	clock       we.Clock
+	ids         we.IDGenerator
+
+	mu       sync.RWMutex
+	segments []*segment
+	streams  map[we.AggregateId]*stream
+	// next is the position given to the next event published
+	next  uint64
+	dirty bool
+
+	closing chan struct{}
+	synced  chan struct{}
+	closed  bool
+}
+
+type EventStoreOption func(*FileEventStore)
+
+const defaultSegmentSize = 64 << 20
+
+// WithSegmentSize sets the size at which the active segment is sealed and a new one started. Change sets larger than
+// the size are written to a segment of their own.
+func WithSegmentSize(size int64) EventStoreOption {
+	return func(store *FileEventStore) {
+		store.segmentSize = size
+	}
+}
+
+// WithSyncPolicy sets when appends are flushed to stable storage.
+func WithSyncPolicy(policy SyncPolicy) EventStoreOption {
+	return func(store *FileEventStore) {
+		store.policy = policy
+	}
+}
+
+// WithClock sets the clock that change sets are recorded at and event ids are created with.
+func WithClock(clock we.Clock) EventStoreOption {
+	return func(store *FileEventStore) {
+		store.clock = clock
+	}
+}
+
+// WithIdGenerator sets the generator of the ids of published events.
+func WithIdGenerator(generator we.IDGenerator) EventStoreOption {
+	return func(store *FileEventStore) {
+		store.ids = generator
+	}
+}
+
+var StoreClosed = errors.New("store-closed")
+
+// NewEventStore opens the log in the directory, creating it if it does not exist. The store must be closed to release
+// its files.
+func NewEventStore(dir string, options ...EventStoreOption) (*FileEventStore, error) {
+	store := &FileEventStore{
+		dir:         dir,
+		segmentSize: defaultSegmentSize,
+		policy:      SyncAlways(),
+		clock:       we.SystemClock,
+		closing:     make(chan struct{}),
+		synced:      make(chan struct{}),
+	}
+
+	for _, option := range options {
+		option(store)
+	}
+
+	if store.ids == nil {
+		store.ids = we.NewULIDGenerator(store.clock)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, errors.Wrap(err, "failed to create log directory")
+	}
+
+	if err := store.recover(); err != nil {
+		store.closeSegments()
+		return nil, err
+	}
+
+	if store.policy.mode == syncInterval {
+		go store.syncer(store.policy.interval)
+	} else {
+		close(store.synced)
+	}
+
+	return store, nil
+}
+
+// recover opens the segments and rebuilds the index from them, truncating a torn write at the end of the last segment.
+func (fs *FileEventStore) recover() error {
+	ids, err := segmentIds(fs.dir)
+	if err != nil {
+		return errors.Wrap(err, "failed to list segments")
+	}
+
+	if len(ids) == 0 {
+		ids = []uint64{1}
+	}
+
+	fs.segments = nil
+	fs.streams = map[we.AggregateId]*stream{}
+	fs.next = 1
+
+	for i, id := range ids {
+		s, err := openSegment(fs.dir, id)
+		if err != nil {
+			return errors.Wrapf(err, "failed to open segment %d", id)
+		}
+		fs.segments = append(fs.segments, s)
+
+		end, err := s.scan(func(offset int64, payload []byte) error {
+			e, err := decodeEntry(payload)
+			if err != nil {
+				return errors.Wrapf(err, "failed to decode frame at %d in segment %d", offset, id)
+			}
+
+			fs.index(e, location{segment: id, offset: offset})
+			return nil
+		})
+
+		if errors.Is(err, TornWrite) {
+			if i != len(ids)-1 {
+				return errors.Wrapf(CorruptSegment, "torn write at %d in segment %d", end, id)
+			}
+
+			err = s.truncate(end)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (fs *FileEventStore) index(e entry, at location) {
+	s, ok := fs.streams[e.AggregateId]
+	if !ok {
+		s = &stream{}
+		fs.streams[e.AggregateId] = s
+	}
+
+	s.apply(e, at)
+
+	// removals record the positions they remove through, so positions aren't reused once compaction has discarded
+	// the removed events
+	var last uint64
+	if len(e.Events) > 0 {
+		last = e.Events[len(e.Events)-1].Position
+	} else if e.Removal != nil {
+		last = e.Removal.Through
+	}
+
+	if last >= fs.next {
+		fs.next = last + 1
+	}
+}
+
+func (fs *FileEventStore) active() *segment {
+	return fs.segments[len(fs.segments)-1]
+}
+
+func (fs *FileEventStore) segment(id uint64) (*segment, bool) {
+	for _, s := range fs.segments {
+		if s.id == id {
+			return s, true
+		}
+	}
+
+	return nil, false
+}
+
+// append writes the entry to the active segment, sealing it first if the entry would take it past the segment size.
+func (fs *FileEventStore) append(e entry) error {
+	if fs.closed {
+		return StoreClosed
+	}
+
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	frame := encodeFrame(payload)
+
+	active := fs.active()
+	if active.size > 0 && active.size+int64(len(frame)) > fs.segmentSize {
+		if active, err = fs.roll(); err != nil {
+			return err
+		}
+	}
+
+	offset, err := active.append(frame)
+	if err != nil {
+		// a partially written frame would be read as a torn write and truncate everything after it on recovery
+		_ = active.truncate(offset)
+		return errors.Wrap(err, "failed to append frame")
+	}
+
+	if fs.policy.mode == syncAlways {
+		if err := active.sync(); err != nil {
+			return errors.Wrap(err, "failed to sync segment")
+		}
+	} else {
+		fs.dirty = true
+	}
+
+	fs.index(e, location{segment: active.id, offset: offset})
+
+	return nil
+}
+
+// roll seals the active segment and starts a new one.
+func (fs *FileEventStore) roll() (*segment, error) {
+	active := fs.active()
+	if err := active.sync(); err != nil {
+		return nil, errors.Wrap(err, "failed to sync segment")
+	}
+
+	next, err := openSegment(fs.dir, active.id+1)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create segment")
+	}
+
+	if err := syncDir(fs.dir); err != nil {
+		_ = next.close()
+		return nil, errors.Wrap(err, "failed to sync log directory")
+	}
+
+	fs.segments = append(fs.segments, next)
+	fs.dirty = false
+
+	return next, nil
+}
+
+func (fs *FileEventStore) Load(ctx context.Context, id we.AggregateId) (we.Aggregate, error) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	s, ok := fs.streams[id]
+	if !ok {
+		return we.Aggregate{Id: id, Revision: we.InitialRevision}, nil
+	}
+
+	if s.deleted {
+		return we.Aggregate{}, we.AggregateDeleted
+	}
+
+	var events []we.RecordedEvent
+	for _, at := range s.frames {
+		if err := ctx.Err(); err != nil {
+			return we.Aggregate{}, err
+		}
+
+		e, err := fs.read(at)
+		if err != nil {
+			return we.Aggregate{}, err
+		}
+
+		for _, r := range e.Events {
+			if !s.live(r.Position) {
+				continue
+			}
+
+			recorded, err := e.recorded(r)
+			if err != nil {
+				return we.Aggregate{}, err
+			}
+
+			events = append(events, recorded)
+		}
+	}
+
+	revision := we.InitialRevision
+	if s.last > 0 {
+		// truncation keeps the aggregate's revision, which is the last event's, even if its events have been removed
+		revision = s.revision
+	}
+
+	return we.Aggregate{
+		Id:       id,
+		Events:   events,
+		Revision: revision,
+	}, nil
+}
+
+func (fs *FileEventStore) read(at location) (entry, error) {
+	s, ok := fs.segment(at.segment)
+	if !ok {
+		return entry{}, errors.Errorf("segment %d not found", at.segment)
+	}
+
+	payload, _, err := s.readFrame(at.offset)
+	if err != nil {
+		return entry{}, errors.Wrapf(err, "failed to read frame at %d in segment %d", at.offset, at.segment)
+	}
+
+	return decodeEntry(payload)
+}
+
+func (fs *FileEventStore) Publish(ctx context.Context, aggregateId we.AggregateId, options we.PublishOptions, events ...we.DomainEvent) error {
+	_, err := fs.PublishRevision(ctx, aggregateId, options, events...)
+	return err
+}
+
+// PublishRevision appends the events as a single change set and returns the revision of the last event.
+func (fs *FileEventStore) PublishRevision(ctx context.Context, aggregateId we.AggregateId, options we.PublishOptions, events ...we.DomainEvent) (we.Revision, error) {
+	if len(events) == 0 {
+		return "", errors.New("attempted to publish empty list of events")
+	}
+
+	records := make([]record, len(events))
+	for index, event := range events {
+		data, err := we.MarshalToData(event)
+		if err != nil {
+			return "", err
+		}
+
+		records[index] = record{
+			EventID:   fs.ids.Create(),
+			EventType: we.EventTypeOf(event),
+			Metadata:  options.RecordedEventMetadata,
+			Data:      data,
+		}
+	}
+
+	return fs.write(ctx, aggregateId, options.ExpectedRevision, records)
+}
+
+// Append imports the events as a single change set, keeping their ids, types, timestamps and metadata.
+func (fs *FileEventStore) Append(ctx context.Context, id we.AggregateId, expected we.Revision, events []we.RecordedEvent) (we.Revision, error) {
+	if len(events) == 0 {
+		return "", errors.New("attempted to append empty list of events")
+	}
+
+	records := make([]record, len(events))
+	for index, event := range events {
+		records[index] = record{
+			EventID:   event.EventID,
+			EventType: event.EventType,
+			Timestamp: event.Timestamp,
+			Metadata:  event.Metadata,
+			Data:      event.Data,
+		}
+	}
+
+	return fs.write(ctx, id, expected, records)
+}
+
+// write positions the records and appends them as a change set if the aggregate is at the expected revision.
+func (fs *FileEventStore) write(ctx context.Context, aggregateId we.AggregateId, expected we.Revision, records []record) (we.Revision, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	s := fs.streams[aggregateId]
+	if s != nil && s.deleted {
+		return "", we.AggregateDeleted
+	}
+
+	if err := expect(s, expected); err != nil {
+		return "", err
+	}
+
+	for index := range records {
+		records[index].Position = fs.next + uint64(index)
+	}
+
+	// the clock is held back to the aggregate's last revision so its revisions increase
+	now := fs.clock.Now()
+	if s != nil {
+		if previous, err := s.revision.Time(); err == nil && previous.After(now) {
+			now = previous
+		}
+	}
+
+	e := entry{
+		AggregateId: aggregateId,
+		Timestamp:   we.TimestampFromTime(now),
+		Events:      records,
+	}
+
+	if err := fs.append(e); err != nil {
+		return "", err
+	}
+
+	return fs.streams[aggregateId].revision, nil
+}
+
+// expect checks the aggregate's last position against the expected revision's sequence number.
+func expect(s *stream, expected we.Revision) error {
+	if expected == "" {
+		return nil
+	}
+
+	var last uint64
+	if s != nil {
+		last = s.last
+	}
+
+	if expected == we.InitialRevision {
+		if last != 0 {
+			return we.RevisionConflict
+		}
+		return nil
+	}
+
+	position, err := internal.DecodeSequenceNumber(expected)
+	if err != nil {
+		return err
+	}
+
+	if position != last {
+		return we.RevisionConflict
+	}
+
+	return nil
+}
+
+// Sync flushes the active segment to stable storage.
+func (fs *FileEventStore) Sync() error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if fs.closed {
+		return StoreClosed
+	}
+
+	if err := fs.active().sync(); err != nil {
+		return err
+	}
+	fs.dirty = false
+
+	return nil
+}
+
+// Close stops background syncing, flushes the active segment and closes the segment files.
+func (fs *FileEventStore) Close() error {
+	fs.mu.Lock()
+	if fs.closed {
+		fs.mu.Unlock()
+		return nil
+	}
+	fs.closed = true
+	close(fs.closing)
+	fs.mu.Unlock()
+
+	<-fs.synced
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	err := fs.active().sync()
+	if closeErr := fs.closeSegments(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+func (fs *FileEventStore) closeSegments() error {
+	var err error
+	for _, s := range fs.segments {
+		if closeErr := s.close(); err == nil {
+			err = closeErr
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,268 @@
+package file
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/weegigs/wee-events-go/we"
+)
+
+type Tested struct {
+	Value string `json:"value"`
+}
+
+func (Tested) EventType() we.EventType {
+	return "test:tested"
+}
+
+func createId() we.AggregateId {
+	return we.AggregateId{Type: "test", Key: ulid.Make().String()}
+}
+
+func openStore(t *testing.T, dir string, options ...EventStoreOption) *FileEventStore {
+	store, err := NewEventStore(dir, options...)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = store.Close() })
+
+	return store
+}
+
+func TestFileEventStore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("file event store validation", func(t *testing.T) {
+		store := openStore(t, t.TempDir())
+		we.NewEventStoreValidationSuite(ctx, store).Run(t)
+	})
+
+	t.Run("validation with small segments and interval syncing", func(t *testing.T) {
+		store := openStore(t, t.TempDir(), WithSegmentSize(1024), WithSyncPolicy(SyncEvery(10*time.Millisecond)))
+		we.NewEventStoreValidationSuite(ctx, store).Run(t)
+	})
+
+	t.Run("validation with a fixed clock", func(t *testing.T) {
+		clock := we.NewFixedClock(time.Date(2022, 2, 3, 4, 5, 6, 0, time.UTC))
+		store := openStore(t, t.TempDir(), WithClock(clock), WithIdGenerator(we.NewSequentialIDGenerator(clock)))
+		we.NewEventStoreValidationSuite(ctx, store, we.WithSuiteClock(clock)).Run(t)
+	})
+
+	t.Run("records events at the clock's time with generated ids", func(t *testing.T) {
+		at := time.Date(2022, 2, 3, 4, 5, 6, 0, time.UTC)
+		clock := we.NewSteppingClock(at, time.Second)
+		store := openStore(t, t.TempDir(), WithClock(clock), WithIdGenerator(we.IDGeneratorFunc(func() we.EventID {
+			return "generated"
+		})))
+		id := createId()
+
+		revision, err := store.PublishRevision(ctx, id, we.Options(), Tested{"event"})
+		require.NoError(t, err)
+
+		loaded, err := store.Load(ctx, id)
+		require.NoError(t, err)
+		require.Len(t, loaded.Events, 1)
+
+		event := loaded.Events[0]
+		assert.Equal(t, we.EventID("generated"), event.EventID)
+		assert.Equal(t, we.TimestampFromTime(at), event.Timestamp)
+
+		recorded, err := revision.Time()
+		require.NoError(t, err)
+		assert.Equal(t, at, recorded)
+	})
+
+	t.Run("reopened stores rebuild their index", func(t *testing.T) {
+		dir := t.TempDir()
+		id := createId()
+
+		store := openStore(t, dir, WithSegmentSize(256))
+		for i := 0; i < 10; i++ {
+			require.NoError(t, store.Publish(ctx, id, we.Options(), Tested{"event"}))
+		}
+		before, err := store.Load(ctx, id)
+		require.NoError(t, err)
+		require.NoError(t, store.Close())
+
+		reopened := openStore(t, dir)
+		after, err := reopened.Load(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, before, after)
+
+		revision, err := reopened.PublishRevision(ctx, id, we.Options(we.WithExpectedRevision(after.Revision)), Tested{"next"})
+		require.NoError(t, err)
+		assert.Greater(t, revision.String(), after.Revision.String())
+	})
+}
+
+func TestRecovery(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("torn writes at the end of the log are truncated", func(t *testing.T) {
+		dir := t.TempDir()
+		id := createId()
+
+		store := openStore(t, dir)
+		require.NoError(t, store.Publish(ctx, id, we.Options(), Tested{"one"}, Tested{"two"}))
+		require.NoError(t, store.Publish(ctx, id, we.Options(), Tested{"three"}))
+		path := store.active().path
+		size := store.active().size
+		require.NoError(t, store.Close())
+
+		// simulate a crash part way through the last frame
+		require.NoError(t, os.Truncate(path, size-5))
+
+		recovered := openStore(t, dir)
+		aggregate, err := recovered.Load(ctx, id)
+		require.NoError(t, err)
+		assert.Len(t, aggregate.Events, 2)
+
+		require.NoError(t, recovered.Publish(ctx, id, we.Options(we.WithExpectedRevision(aggregate.Revision)), Tested{"three"}))
+		require.NoError(t, recovered.Close())
+
+		reopened := openStore(t, dir)
+		aggregate, err = reopened.Load(ctx, id)
+		require.NoError(t, err)
+		assert.Len(t, aggregate.Events, 3)
+	})
+
+	t.Run("frames failing their checksum are truncated", func(t *testing.T) {
+		dir := t.TempDir()
+		id := createId()
+
+		store := openStore(t, dir)
+		require.NoError(t, store.Publish(ctx, id, we.Options(), Tested{"one"}))
+		require.NoError(t, store.Publish(ctx, id, we.Options(), Tested{"two"}))
+		path := store.active().path
+		size := store.active().size
+		require.NoError(t, store.Close())
+
+		file, err := os.OpenFile(path, os.O_RDWR, 0)
+		require.NoError(t, err)
+		_, err = file.WriteAt([]byte{'!'}, size-2)
+		require.NoError(t, err)
+		require.NoError(t, file.Close())
+
+		recovered := openStore(t, dir)
+		aggregate, err := recovered.Load(ctx, id)
+		require.NoError(t, err)
+		assert.Len(t, aggregate.Events, 1)
+	})
+
+	t.Run("torn writes in sealed segments are corruption", func(t *testing.T) {
+		dir := t.TempDir()
+		id := createId()
+
+		store := openStore(t, dir, WithSegmentSize(64))
+		require.NoError(t, store.Publish(ctx, id, we.Options(), Tested{"one"}))
+		require.NoError(t, store.Publish(ctx, id, we.Options(), Tested{"two"}))
+		require.Greater(t, len(store.segments), 1)
+		sealed := store.segments[0]
+		require.NoError(t, store.Close())
+
+		require.NoError(t, os.Truncate(sealed.path, sealed.size-1))
+
+		_, err := NewEventStore(dir)
+		assert.ErrorIs(t, err, CorruptSegment)
+	})
+}
+
+func TestCompaction(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store := openStore(t, dir, WithSegmentSize(512))
+
+	kept, soft, hard, truncated := createId(), createId(), createId(), createId()
+	for i := 0; i < 4; i++ {
+		for _, id := range []we.AggregateId{kept, soft, hard, truncated} {
+			require.NoError(t, store.Publish(ctx, id, we.Options(), Tested{"event"}))
+		}
+	}
+
+	before, err := store.Load(ctx, truncated)
+	require.NoError(t, err)
+
+	require.NoError(t, store.RemoveAggregate(ctx, soft, we.SoftDeletion()))
+	require.NoError(t, store.RemoveAggregate(ctx, hard, we.HardDeletion()))
+	require.NoError(t, store.RemoveAggregate(ctx, truncated, we.Truncation(before.Events[2].Revision)))
+
+	expected, err := store.Load(ctx, kept)
+	require.NoError(t, err)
+
+	discarded, err := store.Compact(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 10, discarded)
+
+	discarded, err = store.Compact(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, discarded)
+
+	require.NoError(t, store.Close())
+	store = openStore(t, dir)
+
+	aggregate, err := store.Load(ctx, kept)
+	require.NoError(t, err)
+	assert.Equal(t, expected, aggregate)
+
+	aggregate, err = store.Load(ctx, soft)
+	require.NoError(t, err)
+	assert.Empty(t, aggregate.Events)
+	assert.Equal(t, we.InitialRevision, aggregate.Revision)
+
+	_, err = store.Load(ctx, hard)
+	assert.ErrorIs(t, err, we.AggregateDeleted)
+
+	aggregate, err = store.Load(ctx, truncated)
+	require.NoError(t, err)
+	assert.Equal(t, before.Events[2:], aggregate.Events)
+	assert.Equal(t, before.Revision, aggregate.Revision)
+
+	// positions removed by compaction aren't reused
+	revision, err := store.PublishRevision(ctx, soft, we.Options(we.WithExpectedRevision(we.InitialRevision)), Tested{"recreated"})
+	require.NoError(t, err)
+	assert.Greater(t, revision.String(), before.Revision.String())
+}
+
+func TestReadAll(t *testing.T) {
+	ctx := context.Background()
+	store := openStore(t, t.TempDir(), WithSegmentSize(256))
+
+	first, second := createId(), createId()
+	for i := 0; i < 5; i++ {
+		require.NoError(t, store.Publish(ctx, first, we.Options(), Tested{"first"}))
+		require.NoError(t, store.Publish(ctx, second, we.Options(), Tested{"second"}, Tested{"second"}))
+	}
+	require.NoError(t, store.RemoveAggregate(ctx, second, we.SoftDeletion()))
+	require.NoError(t, store.Publish(ctx, second, we.Options(), Tested{"recreated"}))
+
+	read := func(after uint64) []we.RecordedEvent {
+		var events []we.RecordedEvent
+		err := store.ReadAll(ctx, after, func(ctx context.Context, event we.RecordedEvent) error {
+			events = append(events, event)
+			return nil
+		})
+		require.NoError(t, err)
+
+		return events
+	}
+
+	events := read(0)
+	require.Len(t, events, 6)
+
+	var last uint64
+	for _, event := range events {
+		position, err := PositionOf(event.Revision)
+		require.NoError(t, err)
+		assert.Greater(t, position, last)
+		last = position
+	}
+	assert.Equal(t, second, events[5].AggregateId)
+
+	resumed, err := PositionOf(events[2].Revision)
+	require.NoError(t, err)
+	assert.Equal(t, events[3:], read(resumed))
+}
@@ -0,0 +1,85 @@
+package file
+
+import (
+	"context"
+
+	"github.com/weegigs/wee-events-go/internal"
+	"github.com/weegigs/wee-events-go/we"
+)
+
+// PositionOf returns the global position of the event with the revision, for resuming ReadAll.
+func PositionOf(revision we.Revision) (uint64, error) {
+	return internal.DecodeSequenceNumber(revision)
+}
+
+// ReadAll calls the handler with every live event positioned after the position, across all aggregates, in the order
+// they were published. Events published while the log is being read may not be included.
+func (fs *FileEventStore) ReadAll(ctx context.Context, after uint64, handler we.EventHandler) error {
+	var read uint64
+	for {
+		events, id, ok, err := fs.readSegmentAfter(read, after)
+		if err != nil || !ok {
+			return err
+		}
+		read = id
+
+		for _, event := range events {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			if err := handler(ctx, event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// readSegmentAfter returns the live events positioned after the position from the first segment with an id greater
+// than the id given. The lock is released before events are handled, so handlers can use the store.
+func (fs *FileEventStore) readSegmentAfter(id uint64, after uint64) ([]we.RecordedEvent, uint64, bool, error) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	if fs.closed {
+		return nil, 0, false, StoreClosed
+	}
+
+	var next *segment
+	for _, s := range fs.segments {
+		if s.id > id {
+			next = s
+			break
+		}
+	}
+
+	if next == nil {
+		return nil, 0, false, nil
+	}
+
+	var events []we.RecordedEvent
+	_, err := next.scan(func(offset int64, payload []byte) error {
+		e, err := decodeEntry(payload)
+		if err != nil {
+			return err
+		}
+
+		stream := fs.streams[e.AggregateId]
+		for _, r := range e.Events {
+			if r.Position <= after || !stream.live(r.Position) {
+				continue
+			}
+
+			recorded, err := e.recorded(r)
+			if err != nil {
+				return err
+			}
+
+			events = append(events, recorded)
+		}
+
+		return nil
+	})
+
+	return events, next.id, true, err
+}
@@ -0,0 +1,51 @@
+package file
+
+import (
+	"context"
+
+	"github.com/weegigs/wee-events-go/internal"
+	"github.com/weegigs/wee-events-go/we"
+)
+
+// RemoveAggregate appends a removal to the log. The removed events remain in their segments until the log is
+// compacted.
+func (fs *FileEventStore) RemoveAggregate(ctx context.Context, id we.AggregateId, removal we.Removal) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	s := fs.streams[id]
+	if s != nil && s.deleted {
+		return we.AggregateDeleted
+	}
+
+	var last uint64
+	if s != nil {
+		last = s.last
+	}
+
+	r := &removalRecord{Mode: removal.Mode}
+	switch removal.Mode {
+	case we.SoftDelete, we.HardDelete:
+		r.Through = last
+
+	case we.TruncateBefore:
+		position, err := internal.DecodeSequenceNumber(removal.Before)
+		if err != nil {
+			return err
+		}
+		r.Through = position
+
+	default:
+		return we.UnsupportedRemoval
+	}
+
+	return fs.append(entry{
+		AggregateId: id,
+		Timestamp:   we.TimestampFromTime(fs.clock.Now()),
+		Removal:     r,
+	})
+}
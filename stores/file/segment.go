@@ -0,0 +1,185 @@
+package file
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Frames are a little endian payload length and CRC-32C of the payload followed by the payload.
+const frameHeaderSize = 8
+
+const segmentExtension = ".log"
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+var (
+	// TornWrite is returned when a frame is incomplete or fails its checksum.
+	TornWrite = errors.New("torn-write")
+	// CorruptSegment is returned when a segment other than the last contains a torn write, which can't be the
+	// result of a crash while appending.
+	CorruptSegment = errors.New("corrupt-segment")
+)
+
+type segment struct {
+	id   uint64
+	path string
+	file *os.File
+	size int64
+}
+
+func segmentPath(dir string, id uint64) string {
+	return filepath.Join(dir, fmt.Sprintf("%020d%s", id, segmentExtension))
+}
+
+// segmentIds returns the ids of the segments in the directory in ascending order.
+func segmentIds(dir string) ([]uint64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []uint64
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, segmentExtension) {
+			continue
+		}
+
+		id, err := strconv.ParseUint(strings.TrimSuffix(name, segmentExtension), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	return ids, nil
+}
+
+func openSegment(dir string, id uint64) (*segment, error) {
+	path := segmentPath(dir, id)
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	return &segment{id: id, path: path, file: file, size: info.Size()}, nil
+}
+
+func encodeFrame(payload []byte) []byte {
+	frame := make([]byte, frameHeaderSize+len(payload))
+	binary.LittleEndian.PutUint32(frame[0:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(frame[4:8], crc32.Checksum(payload, castagnoli))
+	copy(frame[frameHeaderSize:], payload)
+
+	return frame
+}
+
+// append writes the frame at the end of the segment, returning the frame's offset.
+func (s *segment) append(frame []byte) (int64, error) {
+	offset := s.size
+	if _, err := s.file.WriteAt(frame, offset); err != nil {
+		return 0, err
+	}
+
+	s.size += int64(len(frame))
+
+	return offset, nil
+}
+
+// readFrame returns the payload of the frame at the offset and the offset of the following frame.
+func (s *segment) readFrame(offset int64) ([]byte, int64, error) {
+	var header [frameHeaderSize]byte
+	if _, err := s.file.ReadAt(header[:], offset); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, offset, TornWrite
+		}
+		return nil, offset, err
+	}
+
+	// frames are never empty, so a zero length is a zero filled tail left by the file system
+	length := int64(binary.LittleEndian.Uint32(header[0:4]))
+	if length == 0 || offset+frameHeaderSize+length > s.size {
+		return nil, offset, TornWrite
+	}
+
+	payload := make([]byte, length)
+	if _, err := s.file.ReadAt(payload, offset+frameHeaderSize); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, offset, TornWrite
+		}
+		return nil, offset, err
+	}
+
+	if crc32.Checksum(payload, castagnoli) != binary.LittleEndian.Uint32(header[4:8]) {
+		return nil, offset, TornWrite
+	}
+
+	return payload, offset + frameHeaderSize + length, nil
+}
+
+// scan calls fn with each frame in the segment, stopping at the first torn write. It returns the offset of the end
+// of the last complete frame.
+func (s *segment) scan(fn func(offset int64, payload []byte) error) (int64, error) {
+	var offset int64
+	for offset < s.size {
+		payload, next, err := s.readFrame(offset)
+		if err != nil {
+			return offset, err
+		}
+
+		if err := fn(offset, payload); err != nil {
+			return offset, err
+		}
+
+		offset = next
+	}
+
+	return offset, nil
+}
+
+// truncate discards everything in the segment from the offset.
+func (s *segment) truncate(offset int64) error {
+	if err := s.file.Truncate(offset); err != nil {
+		return err
+	}
+
+	s.size = offset
+
+	return s.file.Sync()
+}
+
+func (s *segment) sync() error {
+	return s.file.Sync()
+}
+
+func (s *segment) close() error {
+	return s.file.Close()
+}
+
+// syncDir makes renames and newly created files in the directory durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return d.Sync()
+}
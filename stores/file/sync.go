@@ -0,0 +1,72 @@
+package file
+
+import "time"
+
+type syncMode int
+
+const (
+	syncAlways syncMode = iota
+	syncInterval
+	syncNever
+)
+
+// SyncPolicy controls when appended frames are flushed to stable storage.
+type SyncPolicy struct {
+	mode     syncMode
+	interval time.Duration
+}
+
+// SyncAlways flushes every publish and removal before it returns. It is the default.
+func SyncAlways() SyncPolicy {
+	return SyncPolicy{mode: syncAlways}
+}
+
+// SyncEvery flushes the active segment in the background at the interval. Events published since the last flush are
+// lost if the machine crashes.
+func SyncEvery(interval time.Duration) SyncPolicy {
+	if interval <= 0 {
+		return SyncAlways()
+	}
+
+	return SyncPolicy{mode: syncInterval, interval: interval}
+}
+
+// SyncNever leaves flushing to the operating system. Segments are still flushed when they are sealed and when the
+// store is closed.
+func SyncNever() SyncPolicy {
+	return SyncPolicy{mode: syncNever}
+}
+
+func (p SyncPolicy) String() string {
+	switch p.mode {
+	case syncAlways:
+		return "always"
+	case syncInterval:
+		return "every " + p.interval.String()
+	default:
+		return "never"
+	}
+}
+
+// syncer flushes the active segment until the store is closed.
+func (fs *FileEventStore) syncer(interval time.Duration) {
+	defer close(fs.synced)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-fs.closing:
+			return
+		case <-ticker.C:
+			fs.mu.Lock()
+			if fs.dirty {
+				if err := fs.active().sync(); err == nil {
+					fs.dirty = false
+				}
+			}
+			fs.mu.Unlock()
+		}
+	}
+}
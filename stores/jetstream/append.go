@@ -0,0 +1,35 @@
+package jetstream
+
+import (
+	"context"
+	"errors"
+
+	"github.com/weegigs/wee-events-go/we"
+)
+
+// Append imports the events as a single change set, keeping their ids, types, timestamps and metadata. The change
+// set's message id is scoped to the first event id, so repeating an import within the duplicate window is discarded.
+func (es *EventStore) Append(ctx context.Context, id we.AggregateId, expected we.Revision, events []we.RecordedEvent) (we.Revision, error) {
+	if len(events) == 0 {
+		return "", errors.New("attempted to append empty list of events")
+	}
+
+	subject, err := es.subject(ctx, id)
+	if err != nil {
+		return "", err
+	}
+
+	records := make([]EventRecord, len(events))
+	for index, event := range events {
+		records[index] = EventRecord{
+			EventID:     event.EventID,
+			EventType:   event.EventType,
+			Timestamp:   event.Timestamp,
+			AggregateId: id,
+			Data:        event.Data,
+			Metadata:    event.Metadata,
+		}
+	}
+
+	return es.publishChangeSet(ctx, subject, expected, subject+":"+events[0].EventID.String(), records)
+}
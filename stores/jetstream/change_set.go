@@ -8,10 +8,15 @@ type EventRecord struct {
 	AggregateId we.AggregateId           `json:"aggregate-id"`
 	EventID     we.EventID               `json:"id"`
 	EventType   we.EventType             `json:"type"`
+	Timestamp   we.Timestamp             `json:"timestamp,omitempty"`
 	Data        we.Data                  `json:"data"`
 	Metadata    we.RecordedEventMetadata `json:"metadata"`
 }
 
 type ChangeSet struct {
 	Events []EventRecord `json:"events"`
+	// RecordedAt is the time the change set was published, which the revisions of its events are recorded at.
+	// Imported events keep their original timestamps, so they can't be used for revisions that must follow those of
+	// the events before them.
+	RecordedAt we.Timestamp `json:"recorded-at,omitempty"`
 }
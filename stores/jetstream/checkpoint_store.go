@@ -0,0 +1,109 @@
+package jetstream
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+	"github.com/weegigs/wee-events-go/we"
+)
+
+// NewCheckpointStore creates a checkpoint store backed by the named key value bucket, creating the bucket if it does
+// not exist unless BindBucket is used.
+func NewCheckpointStore(name string, connection *nats.Conn, options ...BucketOption) (*CheckpointStore, error) {
+	kv, err := openBucket(name, "checkpoints", connection, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CheckpointStore{kv: kv, revisions: map[string]uint64{}, stale: map[string]bool{}}, nil
+}
+
+// CheckpointStore is a we.CheckpointStore that saves loaded checkpoints optimistically. A loaded checkpoint is only
+// saved if no other store has saved it since, otherwise we.CheckpointConflict is returned and the checkpoint must be
+// loaded again. Checkpoints saved without being loaded overwrite the saved checkpoint, as they do in a
+// we.MemoryCheckpointStore.
+type CheckpointStore struct {
+	kv nats.KeyValue
+
+	lk        sync.Mutex
+	revisions map[string]uint64
+	stale     map[string]bool
+}
+
+func (s *CheckpointStore) LoadCheckpoint(_ context.Context, name string) (we.Checkpoint, error) {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	entry, err := s.kv.Get(key(name))
+	if err == nil || errors.Is(err, nats.ErrKeyNotFound) {
+		delete(s.stale, name)
+	}
+	if errors.Is(err, nats.ErrKeyNotFound) {
+		s.revisions[name] = 0
+		return we.NoCheckpoint, nil
+	}
+	if err != nil {
+		return we.NoCheckpoint, err
+	}
+
+	s.revisions[name] = entry.Revision()
+	return we.Checkpoint(entry.Value()), nil
+}
+
+func (s *CheckpointStore) SaveCheckpoint(_ context.Context, name string, checkpoint we.Checkpoint) error {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	if s.stale[name] {
+		return we.CheckpointConflict
+	}
+
+	var revision uint64
+	var err error
+
+	last, loaded := s.revisions[name]
+	switch {
+	case !loaded:
+		revision, err = s.kv.Put(key(name), []byte(checkpoint))
+	case last == 0:
+		// the checkpoint didn't exist when it was loaded
+		revision, err = s.kv.Create(key(name), []byte(checkpoint))
+	default:
+		revision, err = s.kv.Update(key(name), []byte(checkpoint), last)
+	}
+
+	if conflicted(err) {
+		delete(s.revisions, name)
+		s.stale[name] = true
+		return we.CheckpointConflict
+	}
+	if err != nil {
+		return err
+	}
+
+	s.revisions[name] = revision
+	return nil
+}
+
+// History returns the retained checkpoints for the name, oldest first.
+func (s *CheckpointStore) History(_ context.Context, name string) ([]we.Checkpoint, error) {
+	entries, err := s.kv.History(key(name))
+	if errors.Is(err, nats.ErrKeyNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var checkpoints []we.Checkpoint
+	for _, entry := range entries {
+		if entry.Operation() != nats.KeyValuePut {
+			continue
+		}
+		checkpoints = append(checkpoints, we.Checkpoint(entry.Value()))
+	}
+
+	return checkpoints, nil
+}
@@ -6,15 +6,14 @@ type Clock interface {
 	Now() time.Time
 }
 
-func WithClockGenerator(clock Clock) EventStoreOption {
+// WithClock sets the clock that change sets are recorded at and event ids are created with.
+func WithClock(clock Clock) EventStoreOption {
 	return func(store *EventStore) {
 		store.clock = clock
 	}
 }
 
-type defaultClock struct {
-}
-
-func (defaultClock) Now() time.Time {
-	return time.Now()
+// Deprecated: use WithClock.
+func WithClockGenerator(clock Clock) EventStoreOption {
+	return WithClock(clock)
 }
@@ -0,0 +1,79 @@
+package jetstream
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+)
+
+// NewEmbeddedTestStore creates a store backed by an in-process, JetStream enabled, nats-server. Unlike NewTestStore it
+// does not require docker.
+func NewEmbeddedTestStore(ctx context.Context, options ...EventStoreOption) (*EventStore, func(), error) {
+	nc, cleanup, err := NewEmbeddedTestConnection(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	store, err := NewEventStore("test", nc, options...)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+
+	return store, cleanup, nil
+}
+
+// NewEmbeddedTestConnection starts an in-process nats-server, with JetStream storing to a temporary directory, and
+// connects to it. The cleanup function closes the connection, shuts the server down and removes the directory.
+func NewEmbeddedTestConnection(ctx context.Context) (*nats.Conn, func(), error) {
+	dir, err := os.MkdirTemp("", "wee-events-jetstream-")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ns, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      server.RANDOM_PORT,
+		JetStream: true,
+		StoreDir:  dir,
+		NoLog:     true,
+		NoSigs:    true,
+	})
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, nil, err
+	}
+
+	shutdown := func() {
+		ns.Shutdown()
+		ns.WaitForShutdown()
+		_ = os.RemoveAll(dir)
+	}
+
+	go ns.Start()
+
+	timeout := 10 * time.Second
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+
+	if !ns.ReadyForConnections(timeout) {
+		shutdown()
+		return nil, nil, errors.New("embedded nats server failed to start")
+	}
+
+	nc, err := nats.Connect(ns.ClientURL())
+	if err != nil {
+		shutdown()
+		return nil, nil, err
+	}
+
+	return nc, func() {
+		nc.Close()
+		shutdown()
+	}, nil
+}
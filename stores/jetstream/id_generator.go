@@ -1,7 +1,6 @@
 package jetstream
 
 import (
-	"github.com/oklog/ulid/v2"
 	"github.com/weegigs/wee-events-go/we"
 )
 
@@ -15,17 +14,12 @@ func WithIdGenerator(generator IDGenerator) EventStoreOption {
 	}
 }
 
+// Deprecated: use we.NewULIDGenerator.
 func NewDefaultIdGenerator(clock Clock) IDGenerator {
-	return &DefaultIdGenerator{
-		clock: clock,
-	}
+	return &DefaultIdGenerator{ULIDGenerator: we.NewULIDGenerator(clock)}
 }
 
+// Deprecated: use we.ULIDGenerator.
 type DefaultIdGenerator struct {
-	clock Clock
-}
-
-func (g *DefaultIdGenerator) Create() we.EventID {
-	v := ulid.MustNew(ulid.Timestamp(g.clock.Now()), ulid.DefaultEntropy()).String()
-	return we.EventID(v)
+	*we.ULIDGenerator
 }
@@ -2,9 +2,14 @@ package jetstream
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/avast/retry-go"
 	"github.com/nats-io/nats.go"
 	"github.com/oklog/ulid/v2"
-	"github.com/rs/zerolog/log"
 	"github.com/weegigs/wee-events-go/internal"
 	"github.com/weegigs/wee-events-go/we"
 )
@@ -13,25 +18,25 @@ type EventStoreOption func(*EventStore)
 
 const prefix = "change-set."
 
-func NewEventStore(name string, connection *nats.Conn, options ...EventStoreOption) *EventStore {
+// NewEventStore creates an event store backed by the named stream, creating the stream if it does not exist unless
+// BindOnly is used.
+func NewEventStore(name string, connection *nats.Conn, options ...EventStoreOption) (*EventStore, error) {
 	stream, err := connection.JetStream()
 	if err != nil {
-		return nil
-	}
-
-	_, err = stream.AddStream(&nats.StreamConfig{
-		Name:        name,
-		Description: "change set stream for " + name,
-		Subjects:    []string{prefix + ">"},
-	})
-	if err != nil {
-		return nil
+		return nil, fmt.Errorf("failed to create jetstream context: %w", err)
 	}
 
 	store := &EventStore{
 		name:    name,
 		manager: stream,
 		stream:  stream,
+		config:  streamConfig(name),
+
+		marshallers: defaultMarshallers(),
+
+		readBatchSize: defaultReadBatchSize,
+
+		publishAttempts: defaultPublishAttempts,
 	}
 
 	for _, option := range options {
@@ -39,18 +44,26 @@ func NewEventStore(name string, connection *nats.Conn, options ...EventStoreOpti
 	}
 
 	if store.clock == nil {
-		store.clock = defaultClock{}
+		store.clock = we.SystemClock
 	}
 
 	if store.id == nil {
-		store.id = NewDefaultIdGenerator(store.clock)
+		store.id = we.NewULIDGenerator(store.clock)
 	}
 
 	if store.marshaller == nil {
 		store.marshaller = JSONMarshaller{}
 	}
 
-	return store
+	if store.tenants == nil {
+		store.tenants = we.StaticTenantResolver(we.NoTenant)
+	}
+
+	if err := store.ensureStream(); err != nil {
+		return nil, err
+	}
+
+	return store, nil
 }
 
 type EventStore struct {
@@ -60,62 +73,253 @@ type EventStore struct {
 	clock      Clock
 	id         IDGenerator
 	marshaller Marshaller
+	// marshallers decode change sets by content type
+	marshallers map[string]Marshaller
+	tenants     we.TenantResolver
+	config      nats.StreamConfig
+	bind        bool
+
+	readMode      ReadMode
+	readBatchSize int
+
+	publishAttempts uint
 }
 
-func subject(aggregateId we.AggregateId) string {
-	return prefix + aggregateId.Encode().String()
+const defaultPublishAttempts = 3
+
+// WithPublishRetries sets the number of attempts made to publish a change set when publishing times out. Retries
+// are safe as replays are discarded by the stream's duplicate window.
+func WithPublishRetries(attempts uint) EventStoreOption {
+	return func(store *EventStore) {
+		if attempts == 0 {
+			attempts = 1
+		}
+
+		store.publishAttempts = attempts
+	}
+}
+
+// WithTenantResolver partitions the store by tenant, prefixing change set subjects with a tenant marker and the
+// tenant resolved from the operation context.
+func WithTenantResolver(resolver we.TenantResolver) EventStoreOption {
+	return func(store *EventStore) {
+		store.tenants = resolver
+	}
+}
+
+// tenantMarker is the subject token that tenanted change set subjects start with, so they can't be confused with the
+// subjects of aggregates without a tenant.
+const tenantMarker = "$tenant"
+
+var InvalidTenant = errors.New("tenant must be a single subject token")
+
+var InvalidAggregateType = errors.New("aggregate type must not start with the " + tenantMarker + " subject token")
+
+func (es *EventStore) subject(ctx context.Context, aggregateId we.AggregateId) (string, error) {
+	tenant, err := es.tenants.Tenant(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if tenant == we.NoTenant {
+		if strings.SplitN(aggregateId.Type, ".", 2)[0] == tenantMarker {
+			return "", InvalidAggregateType
+		}
+
+		return prefix + aggregateId.Encode().String(), nil
+	}
+
+	if strings.ContainsAny(tenant.String(), ".*> \t\r\n") {
+		return "", InvalidTenant
+	}
+
+	return prefix + tenantMarker + "." + tenant.String() + "." + aggregateId.Encode().String(), nil
 }
 
 func (es *EventStore) Publish(ctx context.Context, aggregateId we.AggregateId, options we.PublishOptions, events ...we.DomainEvent) error {
+	_, err := es.PublishRevision(ctx, aggregateId, options, events...)
+	return err
+}
+
+// PublishRevision publishes the events and returns the resulting revision, computed from the publish ack. Each change
+// set is published with a stable message id, derived from the command id or the first event id, so a change set that
+// has already been published within the stream's duplicate window is discarded and the revision of the original is
+// returned.
+func (es *EventStore) PublishRevision(ctx context.Context, aggregateId we.AggregateId, options we.PublishOptions, events ...we.DomainEvent) (we.Revision, error) {
+	if len(events) == 0 {
+		return "", errors.New("attempted to publish empty list of events")
+	}
+
+	subject, err := es.subject(ctx, aggregateId)
+	if err != nil {
+		return "", err
+	}
+
+	now := es.clock.Now()
+	timestamp := we.TimestampFromTime(now)
 	records := make([]EventRecord, len(events))
 
 	for index, event := range events {
 		data, err := encodeEvent(event)
 		if err != nil {
-			return err
+			return "", err
 		}
 		records[index] = EventRecord{
 			EventID:     es.id.Create(),
 			EventType:   we.EventTypeOf(event),
+			Timestamp:   timestamp,
 			AggregateId: aggregateId,
 			Data:        data,
 			Metadata:    options.RecordedEventMetadata,
 		}
 	}
 
-	changeset := ChangeSet{Events: records}
+	return es.publishChangeSet(ctx, subject, options.ExpectedRevision, messageId(subject, options, records), records)
+}
+
+// publishChangeSet publishes the records as a change set, conditional on the expected revision, returning the
+// revision of the last record. The change set is recorded at the current time, held back to the time of the previous
+// revision if the clock is behind it, so the revisions of an aggregate's events increase. The previous revision is the
+// expected one, or the subject's last when there isn't one.
+func (es *EventStore) publishChangeSet(ctx context.Context, subject string, expected we.Revision, msgId string, records []EventRecord) (we.Revision, error) {
+	var ack *nats.PubAck
+	var recordedAt time.Time
+	var err error
+	if expected == "" {
+		// publishes without an expected revision are made conditional on the subject's last message, which has to be
+		// read to find tombstones, so a concurrent hard delete or publish is detected and the publish retried
+		err = retry.Do(
+			func() error {
+				last, previous, err := es.lastChangeSet(ctx, subject)
+				if err != nil {
+					return err
+				}
+
+				var msg *nats.Msg
+				msg, recordedAt, err = es.changeSetMsg(subject, previous, records)
+				if err != nil {
+					return retry.Unrecoverable(err)
+				}
+
+				ack, err = es.publish(ctx, msg, msgId, last)
+				return err
+			},
+			retry.RetryIf(isWrongLastSequence),
+			retry.Context(ctx),
+			retry.LastErrorOnly(true),
+		)
+		if isWrongLastSequence(err) {
+			return "", we.RevisionConflict
+		}
+	} else {
+		var sequence uint64
+		if expected != we.InitialRevision {
+			sequence, err = internal.DecodeSequenceNumber(expected)
+			if err != nil {
+				return "", err
+			}
+		}
+
+		var msg *nats.Msg
+		msg, recordedAt, err = es.changeSetMsg(subject, expected, records)
+		if err != nil {
+			return "", err
+		}
+
+		ack, err = es.publish(ctx, msg, msgId, sequence)
+		if isWrongLastSequence(err) {
+			if deleted, _ := es.deleted(ctx, subject); deleted {
+				return "", we.AggregateDeleted
+			}
+			return "", we.RevisionConflict
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if ack.Duplicate {
+		return es.revisionAt(ctx, ack.Sequence)
+	}
+
+	return internal.EncodeRevision(ulid.Timestamp(recordedAt), ack.Sequence, uint16(len(records)-1))
+}
+
+// changeSetMsg makes the message holding the records, recorded at the current time or the time of the previous
+// revision if the clock is behind it.
+func (es *EventStore) changeSetMsg(subject string, previous we.Revision, records []EventRecord) (*nats.Msg, time.Time, error) {
+	recordedAt := es.clock.Now()
+	if at, err := previous.Time(); err == nil && at.After(recordedAt) {
+		recordedAt = at
+	}
+
+	changeset := ChangeSet{Events: records, RecordedAt: we.TimestampFromTime(recordedAt)}
 	bytes, err := es.marshaller.Marshal(changeset)
 	if err != nil {
-		return err
+		return nil, time.Time{}, err
+	}
+
+	msg := nats.NewMsg(subject)
+	if contentType := contentTypeOf(es.marshaller); contentType != "" {
+		msg.Header.Set(ContentTypeHeader, contentType)
 	}
+	msg.Data = bytes
 
-	var opts = []nats.PubOpt{nats.Context(ctx)}
+	return msg, recordedAt, nil
+}
 
-	expected := options.ExpectedRevision
-	if expected != "" {
-		if expected == we.InitialRevision {
-			opts = append(opts, nats.ExpectLastSequencePerSubject(0))
-		} else {
-			sequenceNumber, err := internal.DecodeSequenceNumber(expected)
-			if err != nil {
-				return err
-			}
+// publish publishes the message on the condition that the last message on its subject is at the sequence, retrying
+// when the publish times out.
+func (es *EventStore) publish(ctx context.Context, msg *nats.Msg, msgId string, last uint64) (*nats.PubAck, error) {
+	var ack *nats.PubAck
+	err := retry.Do(
+		func() (err error) {
+			ack, err = es.stream.PublishMsg(msg, nats.Context(ctx), nats.MsgId(msgId), nats.ExpectLastSequencePerSubject(last))
+			return err
+		},
+		retry.Attempts(es.publishAttempts),
+		retry.RetryIf(func(err error) bool {
+			return errors.Is(err, nats.ErrTimeout)
+		}),
+		retry.Context(ctx),
+		retry.LastErrorOnly(true),
+	)
+
+	return ack, err
+}
 
-			opts = append(opts, nats.ExpectLastSequencePerSubject(sequenceNumber))
-		}
+func isWrongLastSequence(err error) bool {
+	var api *nats.APIError
+	return errors.As(err, &api) && api.ErrorCode == nats.JSErrCodeStreamWrongLastSequence
+}
+
+// messageId scopes a caller supplied command id to the subject, falling back to the first event id, which is unique.
+func messageId(subject string, options we.PublishOptions, records []EventRecord) string {
+	if options.CommandId != "" {
+		return subject + ":" + options.CommandId.String()
 	}
 
-	_, err = es.stream.Publish(subject(aggregateId), bytes, opts...)
+	return records[0].EventID.String()
+}
+
+// revisionAt returns the revision of the last event in the change set stored at the sequence. The change set is read
+// from the stream leader, which has stored it, rather than with a direct get from a replica that may not have.
+func (es *EventStore) revisionAt(ctx context.Context, sequence uint64) (we.Revision, error) {
+	msg, err := es.manager.GetMsg(es.name, sequence, nats.Context(ctx))
 	if err != nil {
-		if api, ok := err.(*nats.APIError); ok {
-			if api.ErrorCode == nats.JSErrCodeStreamWrongLastSequence {
-				return we.RevisionConflict
-			}
-		}
-		return err
+		return "", err
 	}
 
-	return nil
+	recorded, err := es.decodeChangeSet(msg.Header, msg.Data, msg.Sequence, msg.Time)
+	if err != nil {
+		return "", err
+	}
+
+	if len(recorded) == 0 {
+		return "", errors.New("published change set is empty")
+	}
+
+	return recorded[len(recorded)-1].Revision, nil
 }
 
 func encodeEvent(event we.DomainEvent) (we.Data, error) {
@@ -123,9 +327,12 @@ func encodeEvent(event we.DomainEvent) (we.Data, error) {
 }
 
 func (es *EventStore) Load(ctx context.Context, id we.AggregateId) (we.Aggregate, error) {
-	var events []we.RecordedEvent
+	subject, err := es.subject(ctx, id)
+	if err != nil {
+		return we.Aggregate{}, err
+	}
 
-	events, err := es.read(ctx, subject(id))
+	events, err := es.read(ctx, subject)
 	if err != nil {
 		return we.Aggregate{}, err
 	}
@@ -144,80 +351,42 @@ func (es *EventStore) Load(ctx context.Context, id we.AggregateId) (we.Aggregate
 	}, nil
 }
 
-func (es *EventStore) latest(ctx context.Context, subject string) (*uint64, error) {
-	msg, err := es.manager.GetLastMsg(es.name, subject, nats.Context(ctx))
-	if err != nil {
-		if err == nats.ErrMsgNotFound {
-			return nil, nil
-		}
-
-		return nil, err
+func (es *EventStore) decodeChangeSet(header nats.Header, data []byte, sequence uint64, published time.Time) ([]we.RecordedEvent, error) {
+	if tombstoned(header) {
+		return nil, we.AggregateDeleted
 	}
 
-	return &msg.Sequence, nil
-}
-
-func (es *EventStore) read(ctx context.Context, subject string) ([]we.RecordedEvent, error) {
-	latest, err := es.latest(ctx, subject)
+	marshaller, err := es.marshallerFor(header)
 	if err != nil {
 		return nil, err
 	}
 
-	if latest == nil {
-		return nil, nil
-	}
-
-	subscription, err := es.stream.SubscribeSync(subject, nats.DeliverAll(), nats.OrderedConsumer())
+	cs := &ChangeSet{}
+	err = marshaller.Unmarshal(data, cs)
 	if err != nil {
 		return nil, err
 	}
-	defer func(subscription *nats.Subscription) {
-		err := subscription.Unsubscribe()
-		if err != nil {
-			log.Err(err).Msg("ephemeral stream subscription failed to unsubscribe cleanly")
-		}
-	}(subscription)
 
-	var events []we.RecordedEvent
-	for {
-		msg, err := subscription.NextMsgWithContext(ctx)
-		if err != nil {
-			return nil, err
+	var result []we.RecordedEvent
+	for i, event := range cs.Events {
+		// change sets published before timestamps were recorded fall back to the time the server stored them
+		timestamp := event.Timestamp
+		if timestamp == "" {
+			timestamp = we.TimestampFromTime(published)
 		}
 
-		metadata, err := msg.Metadata()
-		if err != nil {
-			return nil, err
+		// change sets published before they were recorded at a time of their own take it from their events
+		recordedAt := cs.RecordedAt
+		if recordedAt == "" {
+			recordedAt = timestamp
 		}
 
-		recorded, err := es.decodeChangeSet(msg.Data, metadata)
+		at, err := recordedAt.Time()
 		if err != nil {
 			return nil, err
 		}
 
-		events = append(events, recorded...)
-
-		if metadata.Sequence.Stream >= *latest {
-			break
-		}
-	}
-
-	return events, nil
-}
-
-func (es *EventStore) decodeChangeSet(data []byte, metadata *nats.MsgMetadata) ([]we.RecordedEvent, error) {
-	cs := &ChangeSet{}
-	err := es.marshaller.Unmarshal(data, cs)
-	if err != nil {
-		return nil, err
-	}
-
-	var result []we.RecordedEvent
-	ts := ulid.Timestamp(metadata.Timestamp)
-	timestamp := we.TimestampFromTime(metadata.Timestamp)
-
-	for i, event := range cs.Events {
-		revision, err := internal.EncodeRevision(ts, metadata.Sequence.Stream, uint16(i))
+		revision, err := internal.EncodeRevision(ulid.Timestamp(at), sequence, uint16(i))
 		if err != nil {
 			return nil, err
 		}
@@ -230,6 +399,7 @@ func (es *EventStore) decodeChangeSet(data []byte, metadata *nats.MsgMetadata) (
 			EventType:   event.EventType,
 			Data:        event.Data,
 			Metadata:    event.Metadata,
+			Position:    we.SequencePosition(sequence, uint16(i)),
 		}
 
 		result = append(result, recorded)
@@ -2,14 +2,21 @@ package jetstream_test
 
 import (
 	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/stretchr/testify/assert"
 	"github.com/weegigs/wee-events-go/stores/jetstream"
 	"github.com/weegigs/wee-events-go/we"
-	"testing"
 )
 
 func TestEventStore(t *testing.T) {
 	ctx := context.Background()
-	store, cleanup, err := jetstream.NewTestStore(ctx)
+	store, cleanup, err := jetstream.NewEmbeddedTestStore(ctx)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -20,3 +27,271 @@ func TestEventStore(t *testing.T) {
 		suite.Run(t)
 	})
 }
+
+func TestBackwardsClock(t *testing.T) {
+	ctx := context.Background()
+	clock := we.NewSteppingClock(time.Date(2022, 2, 3, 4, 5, 6, 0, time.UTC), -time.Second)
+	store, cleanup, err := jetstream.NewEmbeddedTestStore(ctx, jetstream.WithClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	t.Run("jetstream event store validation with a clock that goes backwards", func(t *testing.T) {
+		suite := we.NewEventStoreValidationSuite(ctx, store, we.WithSuiteClock(clock))
+		suite.Run(t)
+	})
+}
+
+func TestPublishDeduplication(t *testing.T) {
+	ctx := context.Background()
+	store, cleanup, err := jetstream.NewEmbeddedTestStore(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	t.Run("returns the revision of the published change set", func(t *testing.T) {
+		aggregateId := we.AggregateId{Type: "test", Key: "publish-revision"}
+
+		revision, err := store.PublishRevision(ctx, aggregateId, we.Options(), we.StoreValidationEvent{}, we.StoreValidationEvent{})
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		loaded, err := store.Load(ctx, aggregateId)
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, loaded.Revision, revision)
+	})
+
+	t.Run("discards replays of a command", func(t *testing.T) {
+		aggregateId := we.AggregateId{Type: "test", Key: "replayed-command"}
+		options := we.Options(we.WithCommandId("command-1"))
+
+		first, err := store.PublishRevision(ctx, aggregateId, options, we.StoreValidationEvent{TestStringValue: "first"})
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		replayed, err := store.PublishRevision(ctx, aggregateId, options, we.StoreValidationEvent{TestStringValue: "first"})
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, first, replayed)
+
+		loaded, err := store.Load(ctx, aggregateId)
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, 1, len(loaded.Events))
+	})
+
+	t.Run("scopes command ids to the aggregate", func(t *testing.T) {
+		options := we.Options(we.WithCommandId("command-2"))
+
+		first, err := store.PublishRevision(ctx, we.AggregateId{Type: "test", Key: "scoped-1"}, options, we.StoreValidationEvent{})
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		second, err := store.PublishRevision(ctx, we.AggregateId{Type: "test", Key: "scoped-2"}, options, we.StoreValidationEvent{})
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.NotEqual(t, first, second)
+	})
+}
+
+func TestConcurrentPublishes(t *testing.T) {
+	ctx := context.Background()
+	store, cleanup, err := jetstream.NewEmbeddedTestStore(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	aggregateId := we.AggregateId{Type: "test", Key: "concurrent"}
+	publishers := 10
+
+	var wg sync.WaitGroup
+	errs := make(chan error, publishers)
+	for i := 0; i < publishers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- store.Publish(ctx, aggregateId, we.Options(), we.StoreValidationEvent{})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.Nil(t, err)
+	}
+
+	loaded, err := store.Load(ctx, aggregateId)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, publishers, len(loaded.Events))
+}
+
+func TestClock(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2021, 3, 4, 5, 6, 7, 8000000, time.UTC)
+	store, cleanup, err := jetstream.NewEmbeddedTestStore(ctx, jetstream.WithClock(we.NewFixedClock(now)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	aggregateId := we.AggregateId{Type: "test", Key: "clock"}
+	revision, err := store.PublishRevision(ctx, aggregateId, we.Options(), we.StoreValidationEvent{})
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	loaded, err := store.Load(ctx, aggregateId)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	assert.Equal(t, we.TimestampFromTime(now), loaded.Events[0].Timestamp)
+	assert.Equal(t, we.TimestampFromTime(now), revision.Timestamp())
+}
+
+func TestTenantedEventStore(t *testing.T) {
+	ctx := context.Background()
+	store, cleanup, err := jetstream.NewEmbeddedTestStore(ctx, jetstream.WithTenantResolver(we.ContextTenantResolver()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	t.Run("tenanted jetstream event store validation", func(t *testing.T) {
+		suite := we.NewEventStoreValidationSuite(we.WithTenant(ctx, "acme"), store)
+		suite.Run(t)
+	})
+
+	t.Run("isolates tenants", func(t *testing.T) {
+		red := we.WithTenant(ctx, "red")
+		blue := we.WithTenant(ctx, "blue")
+		aggregateId := we.AggregateId{Type: "test", Key: "isolates-tenants"}
+
+		err := store.Publish(red, aggregateId, we.Options(), we.StoreValidationEvent{TestStringValue: "red"})
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		loaded, err := store.Load(blue, aggregateId)
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, we.InitialRevision, loaded.Revision)
+
+		loaded, err = store.Load(red, aggregateId)
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, 1, len(loaded.Events))
+	})
+
+	t.Run("rejects tenants that span subject tokens", func(t *testing.T) {
+		_, err := store.Load(we.WithTenant(ctx, "red.blue"), we.AggregateId{Type: "test", Key: "invalid"})
+		assert.Equal(t, jetstream.InvalidTenant, err)
+	})
+
+	t.Run("separates tenants from aggregates without a tenant", func(t *testing.T) {
+		nc, cleanup, err := jetstream.NewEmbeddedTestConnection(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer cleanup()
+
+		untenanted, err := jetstream.NewEventStore("separated", nc)
+		if !assert.Nil(t, err) {
+			return
+		}
+		tenanted, err := jetstream.NewEventStore("separated", nc, jetstream.WithTenantResolver(we.ContextTenantResolver()))
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		// without a marker both aggregates would be published to change-set.red.counter.abc
+		err = untenanted.Publish(ctx, we.AggregateId{Type: "red", Key: "counter.abc"}, we.Options(), we.StoreValidationEvent{})
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		loaded, err := tenanted.Load(we.WithTenant(ctx, "red"), we.AggregateId{Type: "counter", Key: "abc"})
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, we.InitialRevision, loaded.Revision)
+
+		err = untenanted.Publish(ctx, we.AggregateId{Type: "$tenant", Key: "red.counter.abc"}, we.Options(), we.StoreValidationEvent{})
+		assert.Equal(t, jetstream.InvalidAggregateType, err)
+	})
+}
+
+func TestStreamConfiguration(t *testing.T) {
+	ctx := context.Background()
+	nc, cleanup, err := jetstream.NewEmbeddedTestConnection(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	js, err := nc.JetStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("creates the stream with the configured settings", func(t *testing.T) {
+		_, err := jetstream.NewEventStore(
+			"configured", nc,
+			jetstream.WithStorage(nats.MemoryStorage),
+			jetstream.WithMaxAge(time.Hour),
+			jetstream.WithMaxBytes(1024*1024),
+			jetstream.WithDuplicateWindow(time.Minute),
+		)
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		info, err := js.StreamInfo("configured")
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		assert.Equal(t, nats.MemoryStorage, info.Config.Storage)
+		assert.Equal(t, time.Hour, info.Config.MaxAge)
+		assert.Equal(t, int64(1024*1024), info.Config.MaxBytes)
+		assert.Equal(t, time.Minute, info.Config.Duplicates)
+		assert.Equal(t, []string{"change-set.>"}, info.Config.Subjects)
+	})
+
+	t.Run("binds to an existing stream", func(t *testing.T) {
+		store, err := jetstream.NewEventStore("configured", nc, jetstream.BindOnly())
+		assert.Nil(t, err)
+		assert.NotNil(t, store)
+	})
+
+	t.Run("fails to bind to a missing stream", func(t *testing.T) {
+		_, err := jetstream.NewEventStore("missing", nc, jetstream.BindOnly())
+		assert.Equal(t, jetstream.StreamNotFound, err)
+	})
+
+	t.Run("rejects a stream with unexpected subjects", func(t *testing.T) {
+		_, err := js.AddStream(&nats.StreamConfig{Name: "mismatched", Subjects: []string{"other.>"}})
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		_, err = jetstream.NewEventStore("mismatched", nc)
+		var mismatch *jetstream.StreamSubjectsMismatch
+		assert.True(t, errors.As(err, &mismatch))
+	})
+}
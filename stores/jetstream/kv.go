@@ -0,0 +1,106 @@
+package jetstream
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+type BucketOption func(*bucket)
+
+type bucket struct {
+	config nats.KeyValueConfig
+	bind   bool
+}
+
+// WithBucketHistory sets the number of values retained for each key when the bucket is created, at most
+// nats.KeyValueMaxHistory.
+func WithBucketHistory(history uint8) BucketOption {
+	return func(b *bucket) {
+		b.config.History = history
+	}
+}
+
+// WithBucketTTL expires values that have not been updated within the ttl.
+func WithBucketTTL(ttl time.Duration) BucketOption {
+	return func(b *bucket) {
+		b.config.TTL = ttl
+	}
+}
+
+// WithBucketStorage sets the storage used when the bucket is created.
+func WithBucketStorage(storage nats.StorageType) BucketOption {
+	return func(b *bucket) {
+		b.config.Storage = storage
+	}
+}
+
+// WithBucketReplicas sets the number of replicas used when the bucket is created.
+func WithBucketReplicas(replicas int) BucketOption {
+	return func(b *bucket) {
+		b.config.Replicas = replicas
+	}
+}
+
+// BindBucket binds to an existing bucket rather than creating it.
+func BindBucket() BucketOption {
+	return func(b *bucket) {
+		b.bind = true
+	}
+}
+
+var BucketNotFound = errors.New("key value bucket not found")
+
+// openBucket binds to the named bucket, creating it if it does not exist unless BindBucket is used. The options are
+// only applied when the bucket is created.
+func openBucket(name string, description string, connection *nats.Conn, options []BucketOption) (nats.KeyValue, error) {
+	js, err := connection.JetStream()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create jetstream context: %w", err)
+	}
+
+	b := &bucket{
+		config: nats.KeyValueConfig{
+			Bucket:      name,
+			Description: description,
+			History:     1,
+		},
+	}
+
+	for _, option := range options {
+		option(b)
+	}
+
+	kv, err := js.KeyValue(name)
+	if errors.Is(err, nats.ErrBucketNotFound) {
+		if b.bind {
+			return nil, BucketNotFound
+		}
+
+		kv, err = js.CreateKeyValue(&b.config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create bucket %s: %w", name, err)
+		}
+
+		return kv, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to bind bucket %s: %w", name, err)
+	}
+
+	return kv, nil
+}
+
+// key encodes a name as a valid key. Keys are limited to a small set of characters so names are base64 encoded
+// rather than escaped.
+func key(name string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(name))
+}
+
+// conflicted reports whether a create or update was rejected because the key has been written since it was read.
+func conflicted(err error) bool {
+	return errors.Is(err, nats.ErrKeyExists)
+}
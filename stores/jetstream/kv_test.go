@@ -0,0 +1,225 @@
+package jetstream_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/weegigs/wee-events-go/stores/jetstream"
+	"github.com/weegigs/wee-events-go/we"
+)
+
+func TestCheckpointStore(t *testing.T) {
+	ctx := context.Background()
+	nc, cleanup, err := jetstream.NewEmbeddedTestConnection(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	store, err := jetstream.NewCheckpointStore("checkpoints", nc, jetstream.WithBucketHistory(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("loads no checkpoint for unknown names", func(t *testing.T) {
+		checkpoint, err := store.LoadCheckpoint(ctx, "unknown")
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, we.NoCheckpoint, checkpoint)
+	})
+
+	t.Run("saves and loads checkpoints", func(t *testing.T) {
+		name := "projection/shard-0001"
+
+		if _, err := store.LoadCheckpoint(ctx, name); !assert.Nil(t, err) {
+			return
+		}
+
+		for _, checkpoint := range []we.Checkpoint{"1", "2", "3"} {
+			if err := store.SaveCheckpoint(ctx, name, checkpoint); !assert.Nil(t, err) {
+				return
+			}
+		}
+
+		loaded, err := store.LoadCheckpoint(ctx, name)
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, we.Checkpoint("3"), loaded)
+
+		history, err := store.History(ctx, name)
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, []we.Checkpoint{"1", "2", "3"}, history)
+	})
+
+	t.Run("rejects concurrent saves", func(t *testing.T) {
+		name := "contended"
+
+		other, err := jetstream.NewCheckpointStore("checkpoints", nc, jetstream.BindBucket())
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		_, _ = store.LoadCheckpoint(ctx, name)
+		_, _ = other.LoadCheckpoint(ctx, name)
+
+		if err := store.SaveCheckpoint(ctx, name, "1"); !assert.Nil(t, err) {
+			return
+		}
+		assert.ErrorIs(t, other.SaveCheckpoint(ctx, name, "2"), we.CheckpointConflict)
+
+		// the conflicting store must reload before it can save
+		assert.ErrorIs(t, other.SaveCheckpoint(ctx, name, "2"), we.CheckpointConflict)
+
+		if _, err := other.LoadCheckpoint(ctx, name); !assert.Nil(t, err) {
+			return
+		}
+		assert.Nil(t, other.SaveCheckpoint(ctx, name, "2"))
+	})
+
+	t.Run("overwrites checkpoints saved without being loaded", func(t *testing.T) {
+		name := "unloaded"
+
+		if err := store.SaveCheckpoint(ctx, name, "1"); !assert.Nil(t, err) {
+			return
+		}
+
+		other, err := jetstream.NewCheckpointStore("checkpoints", nc)
+		if !assert.Nil(t, err) {
+			return
+		}
+		if err := other.SaveCheckpoint(ctx, name, "2"); !assert.Nil(t, err) {
+			return
+		}
+
+		checkpoint, err := store.LoadCheckpoint(ctx, name)
+		assert.Nil(t, err)
+		assert.Equal(t, we.Checkpoint("2"), checkpoint)
+	})
+
+	t.Run("rejects saves of checkpoints created since they were loaded", func(t *testing.T) {
+		name := "created"
+
+		other, err := jetstream.NewCheckpointStore("checkpoints", nc)
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		_, _ = store.LoadCheckpoint(ctx, name)
+		if err := other.SaveCheckpoint(ctx, name, "1"); !assert.Nil(t, err) {
+			return
+		}
+		assert.ErrorIs(t, store.SaveCheckpoint(ctx, name, "2"), we.CheckpointConflict)
+	})
+}
+
+func TestBucketOptions(t *testing.T) {
+	ctx := context.Background()
+	nc, cleanup, err := jetstream.NewEmbeddedTestConnection(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	js, err := nc.JetStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("bind only fails when the bucket does not exist", func(t *testing.T) {
+		_, err := jetstream.NewCheckpointStore("missing", nc, jetstream.BindBucket())
+		assert.ErrorIs(t, err, jetstream.BucketNotFound)
+
+		_, err = jetstream.NewSnapshotStore("missing", nc, jetstream.BindBucket())
+		assert.ErrorIs(t, err, jetstream.BucketNotFound)
+	})
+
+	t.Run("creates buckets with the configured settings", func(t *testing.T) {
+		_, err := jetstream.NewSnapshotStore("configured", nc, jetstream.WithBucketHistory(3), jetstream.WithBucketStorage(nats.MemoryStorage))
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		kv, err := js.KeyValue("configured")
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		status, err := kv.Status()
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, int64(3), status.History())
+		assert.Equal(t, nats.MemoryStorage, status.(*nats.KeyValueBucketStatus).StreamInfo().Config.Storage)
+	})
+}
+
+func TestSnapshotStore(t *testing.T) {
+	ctx := context.Background()
+	nc, cleanup, err := jetstream.NewEmbeddedTestConnection(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	store, err := jetstream.NewSnapshotStore("snapshots", nc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot := func(id we.AggregateId, revision we.Revision, state string) we.Snapshot {
+		data, _ := json.Marshal(state)
+		return we.Snapshot{
+			AggregateId: id,
+			Revision:    revision,
+			Timestamp:   revision.Timestamp(),
+			Data:        we.Data{Encoding: "application/json", Data: data},
+		}
+	}
+
+	t.Run("returns not found for aggregates without a snapshot", func(t *testing.T) {
+		_, err := store.LoadSnapshot(ctx, we.AggregateId{Type: "test", Key: "missing"})
+		assert.ErrorIs(t, err, we.SnapshotNotFound)
+	})
+
+	t.Run("saves and loads snapshots", func(t *testing.T) {
+		id := we.AggregateId{Type: "test", Key: "key with spaces.and.dots"}
+		saved := snapshot(id, "01GV8Y3VQ7S3W1J8D4B5E4Y0K1", "first")
+
+		if err := store.SaveSnapshot(ctx, saved); !assert.Nil(t, err) {
+			return
+		}
+
+		loaded, err := store.LoadSnapshot(ctx, id)
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, saved, loaded)
+	})
+
+	t.Run("keeps the latest snapshot", func(t *testing.T) {
+		id := we.AggregateId{Type: "test", Key: "latest"}
+		newer := snapshot(id, "01GV8Y3VQ7S3W1J8D4B5E4Y0K2", "newer")
+		older := snapshot(id, "01GV8Y3VQ7S3W1J8D4B5E4Y0K1", "older")
+
+		if err := store.SaveSnapshot(ctx, newer); !assert.Nil(t, err) {
+			return
+		}
+		if err := store.SaveSnapshot(ctx, older); !assert.Nil(t, err) {
+			return
+		}
+
+		loaded, err := store.LoadSnapshot(ctx, id)
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.Equal(t, newer, loaded)
+	})
+}
@@ -1,10 +1,32 @@
 package jetstream
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"fmt"
 
+	"github.com/nats-io/nats.go"
+)
+
+// ContentTypeHeader identifies the marshaller used to encode a change set message.
+const ContentTypeHeader = "Wee-Content-Type"
+
+// WithMarshaller sets the marshaller used to encode published change sets. Change sets are decoded with the
+// marshaller identified by their content type header, so a stream can hold change sets written with different
+// marshallers while it is migrated. Marshallers that don't identify their content type publish change sets without
+// the header and are used to decode change sets that don't have one.
 func WithMarshaller(marshaller Marshaller) EventStoreOption {
 	return func(store *EventStore) {
 		store.marshaller = marshaller
+		store.marshallers[contentTypeOf(marshaller)] = marshaller
+	}
+}
+
+// WithDecoders registers additional marshallers used to decode change sets.
+func WithDecoders(marshallers ...Marshaller) EventStoreOption {
+	return func(store *EventStore) {
+		for _, marshaller := range marshallers {
+			store.marshallers[contentTypeOf(marshaller)] = marshaller
+		}
 	}
 }
 
@@ -13,6 +35,43 @@ type Marshaller interface {
 	Marshal(v any) ([]byte, error)
 }
 
+// ContentTyped is implemented by marshallers that identify the content type of the change sets they encode.
+type ContentTyped interface {
+	ContentType() string
+}
+
+func contentTypeOf(marshaller Marshaller) string {
+	if typed, ok := marshaller.(ContentTyped); ok {
+		return typed.ContentType()
+	}
+
+	return ""
+}
+
+// defaultMarshallers decodes change sets published without a content type header as JSON, as they were before the
+// header was introduced.
+func defaultMarshallers() map[string]Marshaller {
+	return map[string]Marshaller{
+		"":                  JSONMarshaller{},
+		JSONContentType:     JSONMarshaller{},
+		ProtobufContentType: ProtobufMarshaller{},
+	}
+}
+
+// marshallerFor returns the marshaller for a message.
+func (es *EventStore) marshallerFor(header nats.Header) (Marshaller, error) {
+	contentType := header.Get(ContentTypeHeader)
+
+	marshaller, ok := es.marshallers[contentType]
+	if !ok {
+		return nil, fmt.Errorf("no marshaller registered for change set content type %q", contentType)
+	}
+
+	return marshaller, nil
+}
+
+const JSONContentType = "application/json"
+
 type JSONMarshaller struct{}
 
 func (J JSONMarshaller) Unmarshal(data []byte, v any) error {
@@ -22,3 +81,7 @@ func (J JSONMarshaller) Unmarshal(data []byte, v any) error {
 func (J JSONMarshaller) Marshal(v any) ([]byte, error) {
 	return json.Marshal(v)
 }
+
+func (J JSONMarshaller) ContentType() string {
+	return JSONContentType
+}
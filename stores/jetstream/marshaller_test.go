@@ -0,0 +1,289 @@
+package jetstream_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/bufbuild/protocompile"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/weegigs/wee-events-go/stores/jetstream"
+	"github.com/weegigs/wee-events-go/we"
+)
+
+func changeSet(events int) jetstream.ChangeSet {
+	cs := jetstream.ChangeSet{}
+	for i := 0; i < events; i++ {
+		data, _ := json.Marshal(we.StoreValidationEvent{TestStringValue: fmt.Sprintf("event \"%d\"", i), TestIntValue: i})
+		cs.Events = append(cs.Events, jetstream.EventRecord{
+			AggregateId: we.AggregateId{Type: "test", Key: "marshalling"},
+			EventID:     we.EventID(fmt.Sprintf("01GV8Y3VQ7S3W1J8D4B5E4Y0K%d", i)),
+			EventType:   "test:event",
+			Timestamp:   "2023-03-12T01:02:03.456Z",
+			Data:        we.Data{Encoding: "application/json", Data: data},
+			Metadata: we.RecordedEventMetadata{
+				CausationId:   "cause",
+				CorrelationId: "correlation",
+				Actor:         "user/1234",
+				TraceParent:   "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+				Extensions:    we.Extensions{we.RemoteAddressExtension: "192.0.2.1:4321", "example.com/tag": ""},
+			},
+		})
+	}
+
+	return cs
+}
+
+func TestProtobufMarshaller(t *testing.T) {
+	marshaller := jetstream.ProtobufMarshaller{}
+
+	t.Run("round trips change sets", func(t *testing.T) {
+		cs := changeSet(3)
+
+		data, err := marshaller.Marshal(cs)
+		require.NoError(t, err)
+
+		var decoded jetstream.ChangeSet
+		require.NoError(t, marshaller.Unmarshal(data, &decoded))
+		assert.Equal(t, cs, decoded)
+	})
+
+	t.Run("omits empty fields", func(t *testing.T) {
+		cs := jetstream.ChangeSet{Events: []jetstream.EventRecord{{EventID: "id"}}}
+
+		data, err := marshaller.Marshal(&cs)
+		require.NoError(t, err)
+
+		var decoded jetstream.ChangeSet
+		require.NoError(t, marshaller.Unmarshal(data, &decoded))
+		assert.Equal(t, cs, decoded)
+	})
+
+	t.Run("is smaller than json", func(t *testing.T) {
+		cs := changeSet(10)
+
+		encoded, err := marshaller.Marshal(cs)
+		require.NoError(t, err)
+
+		js, err := jetstream.JSONMarshaller{}.Marshal(cs)
+		require.NoError(t, err)
+
+		assert.Less(t, len(encoded), len(js))
+	})
+
+	t.Run("matches the change set schema", func(t *testing.T) {
+		descriptor := changeSetDescriptor(t)
+		cs := changeSet(2)
+		cs.RecordedAt = "2023-03-12T01:02:04.000Z"
+
+		data, err := marshaller.Marshal(cs)
+		require.NoError(t, err)
+
+		message := dynamicpb.NewMessage(descriptor)
+		require.NoError(t, proto.UnmarshalOptions{DiscardUnknown: false}.Unmarshal(data, message))
+		assert.Empty(t, message.GetUnknown())
+
+		js, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(message)
+		require.NoError(t, err)
+
+		var decoded struct {
+			Events []struct {
+				AggregateId struct{ Type, Key string } `json:"aggregate_id"`
+				Id          string                     `json:"id"`
+				Type        string                     `json:"type"`
+				Timestamp   string                     `json:"timestamp"`
+				Data        struct {
+					Encoding string `json:"encoding"`
+					Data     []byte `json:"data"`
+				} `json:"data"`
+				Metadata struct {
+					CausationId   string            `json:"causation_id"`
+					CorrelationId string            `json:"correlation_id"`
+					Actor         string            `json:"actor"`
+					TraceParent   string            `json:"trace_parent"`
+					Extensions    map[string]string `json:"extensions"`
+				} `json:"metadata"`
+			} `json:"events"`
+			RecordedAt string `json:"recorded_at"`
+		}
+		require.NoError(t, json.Unmarshal(js, &decoded))
+
+		assert.EqualValues(t, cs.RecordedAt, decoded.RecordedAt)
+		require.Equal(t, len(cs.Events), len(decoded.Events))
+		for i, event := range cs.Events {
+			d := decoded.Events[i]
+			assert.EqualValues(t, event.AggregateId.Type, d.AggregateId.Type)
+			assert.EqualValues(t, event.AggregateId.Key, d.AggregateId.Key)
+			assert.EqualValues(t, event.EventID, d.Id)
+			assert.EqualValues(t, event.EventType, d.Type)
+			assert.EqualValues(t, event.Timestamp, d.Timestamp)
+			assert.EqualValues(t, event.Data.Encoding, d.Data.Encoding)
+			assert.EqualValues(t, event.Data.Data, d.Data.Data)
+			assert.EqualValues(t, event.Metadata.CausationId, d.Metadata.CausationId)
+			assert.EqualValues(t, event.Metadata.CorrelationId, d.Metadata.CorrelationId)
+			assert.EqualValues(t, event.Metadata.Actor, d.Metadata.Actor)
+			assert.EqualValues(t, event.Metadata.TraceParent, d.Metadata.TraceParent)
+			for key, value := range event.Metadata.Extensions {
+				assert.EqualValues(t, value, d.Metadata.Extensions[string(key)])
+			}
+		}
+
+		// and change sets encoded from the schema decode to the same change set
+		encoded, err := proto.MarshalOptions{Deterministic: true}.Marshal(message)
+		require.NoError(t, err)
+
+		var roundTripped jetstream.ChangeSet
+		require.NoError(t, marshaller.Unmarshal(encoded, &roundTripped))
+		assert.Equal(t, cs, roundTripped)
+	})
+
+	t.Run("rejects truncated data", func(t *testing.T) {
+		data, err := marshaller.Marshal(changeSet(1))
+		require.NoError(t, err)
+
+		var decoded jetstream.ChangeSet
+		assert.Error(t, marshaller.Unmarshal(data[:len(data)-3], &decoded))
+	})
+}
+
+// changeSetDescriptor compiles change_set.proto, which the protobuf marshaller is written against.
+func changeSetDescriptor(t *testing.T) protoreflect.MessageDescriptor {
+	compiler := protocompile.Compiler{Resolver: &protocompile.SourceResolver{}}
+	files, err := compiler.Compile(context.Background(), "change_set.proto")
+	require.NoError(t, err)
+
+	descriptor := files[0].Messages().ByName("ChangeSet")
+	require.NotNil(t, descriptor)
+
+	return descriptor
+}
+
+func TestProtobufEventStore(t *testing.T) {
+	ctx := context.Background()
+	store, cleanup, err := jetstream.NewEmbeddedTestStore(ctx, jetstream.WithMarshaller(jetstream.ProtobufMarshaller{}))
+	require.NoError(t, err)
+	defer cleanup()
+
+	t.Run("jetstream protobuf event store validation", func(t *testing.T) {
+		suite := we.NewEventStoreValidationSuite(ctx, store)
+		suite.Run(t)
+	})
+}
+
+func TestMixedFormatStreams(t *testing.T) {
+	ctx := context.Background()
+	nc := embeddedConnection(t)
+
+	id := we.AggregateId{Type: "test", Key: "mixed"}
+
+	// change sets published before marshallers were identified have no content type header
+	legacy, err := jetstream.JSONMarshaller{}.Marshal(changeSet(1))
+	require.NoError(t, err)
+
+	writer, err := jetstream.NewEventStore("mixed", nc)
+	require.NoError(t, err)
+
+	js, err := nc.JetStream()
+	require.NoError(t, err)
+	_, err = js.Publish("change-set."+id.Encode().String(), legacy)
+	require.NoError(t, err)
+
+	publishChangeSets(t, writer, id, 2)
+
+	writer, err = jetstream.NewEventStore("mixed", nc, jetstream.WithMarshaller(jetstream.ProtobufMarshaller{}))
+	require.NoError(t, err)
+	publishChangeSets(t, writer, id, 2)
+
+	for _, m := range readModes {
+		m := m
+		t.Run(m.name, func(t *testing.T) {
+			reader, err := jetstream.NewEventStore("mixed", nc, jetstream.WithReadMode(m.mode))
+			require.NoError(t, err)
+
+			aggregate, err := reader.Load(ctx, id)
+			require.NoError(t, err)
+			require.Equal(t, 5, len(aggregate.Events))
+
+			for i, event := range aggregate.Events[1:] {
+				var decoded we.StoreValidationEvent
+				require.NoError(t, json.Unmarshal(event.Data.Data, &decoded))
+				assert.Equal(t, fmt.Sprintf("event %d", i%2), decoded.TestStringValue)
+			}
+		})
+	}
+
+	t.Run("fails for unregistered content types", func(t *testing.T) {
+		msg := nats.NewMsg("change-set." + id.Encode().String())
+		msg.Header.Set(jetstream.ContentTypeHeader, "application/unknown")
+		msg.Data = legacy
+		_, err := js.PublishMsg(msg)
+		require.NoError(t, err)
+
+		reader, err := jetstream.NewEventStore("mixed", nc)
+		require.NoError(t, err)
+
+		_, err = reader.Load(ctx, id)
+		assert.ErrorContains(t, err, "application/unknown")
+	})
+}
+
+// untypedMarshaller is a marshaller written before marshallers identified their content type.
+type untypedMarshaller struct{}
+
+func (untypedMarshaller) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+func (untypedMarshaller) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func TestUntypedMarshaller(t *testing.T) {
+	ctx := context.Background()
+	store, cleanup, err := jetstream.NewEmbeddedTestStore(ctx, jetstream.WithMarshaller(untypedMarshaller{}))
+	require.NoError(t, err)
+	defer cleanup()
+
+	t.Run("jetstream untyped marshaller event store validation", func(t *testing.T) {
+		suite := we.NewEventStoreValidationSuite(ctx, store)
+		suite.Run(t)
+	})
+}
+
+func BenchmarkMarshallers(b *testing.B) {
+	cs := changeSet(10)
+
+	for _, marshaller := range []jetstream.Marshaller{jetstream.JSONMarshaller{}, jetstream.ProtobufMarshaller{}} {
+		marshaller := marshaller
+		data, err := marshaller.Marshal(cs)
+		require.NoError(b, err)
+
+		b.Run(fmt.Sprintf("%T marshal", marshaller), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := marshaller.Marshal(cs); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("%T unmarshal", marshaller), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				var decoded jetstream.ChangeSet
+				if err := marshaller.Unmarshal(data, &decoded); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,286 @@
+package jetstream
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/weegigs/wee-events-go/we"
+)
+
+const ProtobufContentType = "application/x-protobuf; messageType=weegigs.events.jetstream.v1.ChangeSet"
+
+// ProtobufMarshaller encodes change sets using the protobuf schema in change_set.proto. Event data is carried as
+// bytes rather than being nested in the change set's encoding, so it is neither escaped nor re-parsed.
+type ProtobufMarshaller struct{}
+
+func (p ProtobufMarshaller) ContentType() string {
+	return ProtobufContentType
+}
+
+func (p ProtobufMarshaller) Marshal(v any) ([]byte, error) {
+	switch cs := v.(type) {
+	case ChangeSet:
+		return appendChangeSet(nil, &cs), nil
+	case *ChangeSet:
+		return appendChangeSet(nil, cs), nil
+	default:
+		return nil, fmt.Errorf("protobuf marshaller can't marshal %T", v)
+	}
+}
+
+func (p ProtobufMarshaller) Unmarshal(data []byte, v any) error {
+	cs, ok := v.(*ChangeSet)
+	if !ok {
+		return fmt.Errorf("protobuf marshaller can't unmarshal into %T", v)
+	}
+
+	return consumeMessage(data, func(number protowire.Number, value []byte) error {
+		switch number {
+		case 1:
+			var record EventRecord
+			if err := consumeEventRecord(value, &record); err != nil {
+				return err
+			}
+			cs.Events = append(cs.Events, record)
+		case 2:
+			cs.RecordedAt = we.Timestamp(value)
+		}
+
+		return nil
+	})
+}
+
+func appendChangeSet(b []byte, cs *ChangeSet) []byte {
+	size := 0
+	for i := range cs.Events {
+		size += sizeMessage(1, sizeEventRecord(&cs.Events[i]))
+	}
+	size += sizeString(2, string(cs.RecordedAt))
+
+	b = append(make([]byte, 0, len(b)+size), b...)
+	for i := range cs.Events {
+		record := &cs.Events[i]
+		b = appendMessage(b, 1, sizeEventRecord(record), func(b []byte) []byte {
+			return appendEventRecord(b, record)
+		})
+	}
+	b = appendString(b, 2, string(cs.RecordedAt))
+
+	return b
+}
+
+func appendEventRecord(b []byte, record *EventRecord) []byte {
+	b = appendMessage(b, 1, sizeAggregateId(record.AggregateId), func(b []byte) []byte {
+		b = appendString(b, 1, record.AggregateId.Type)
+		return appendString(b, 2, record.AggregateId.Key)
+	})
+	b = appendString(b, 2, record.EventID.String())
+	b = appendString(b, 3, record.EventType.String())
+	b = appendString(b, 4, string(record.Timestamp))
+	b = appendMessage(b, 5, sizeData(record.Data), func(b []byte) []byte {
+		b = appendString(b, 1, record.Data.Encoding)
+		return appendString(b, 2, string(record.Data.Data))
+	})
+	b = appendMessage(b, 6, sizeMetadata(record.Metadata), func(b []byte) []byte {
+		return appendMetadata(b, record.Metadata)
+	})
+
+	return b
+}
+
+// appendMetadata appends the metadata's fields, with its extensions in key order so the encoding is deterministic.
+func appendMetadata(b []byte, metadata we.RecordedEventMetadata) []byte {
+	b = appendString(b, 1, metadata.CausationId.String())
+	b = appendString(b, 2, string(metadata.CorrelationId))
+	b = appendString(b, 3, metadata.Actor.String())
+	b = appendString(b, 4, metadata.TraceParent.String())
+	for _, key := range metadata.Extensions.Keys() {
+		value := metadata.Extensions[key]
+		b = appendMessage(b, 5, sizeExtension(key, value), func(b []byte) []byte {
+			b = appendString(b, 1, key.String())
+			return appendString(b, 2, value)
+		})
+	}
+
+	return b
+}
+
+func sizeEventRecord(record *EventRecord) int {
+	return sizeMessage(1, sizeAggregateId(record.AggregateId)) +
+		sizeString(2, record.EventID.String()) +
+		sizeString(3, record.EventType.String()) +
+		sizeString(4, string(record.Timestamp)) +
+		sizeMessage(5, sizeData(record.Data)) +
+		sizeMessage(6, sizeMetadata(record.Metadata))
+}
+
+func sizeAggregateId(id we.AggregateId) int {
+	return sizeString(1, id.Type) + sizeString(2, id.Key)
+}
+
+func sizeData(data we.Data) int {
+	return sizeString(1, data.Encoding) + sizeString(2, string(data.Data))
+}
+
+func sizeMetadata(metadata we.RecordedEventMetadata) int {
+	size := sizeString(1, metadata.CausationId.String()) +
+		sizeString(2, string(metadata.CorrelationId)) +
+		sizeString(3, metadata.Actor.String()) +
+		sizeString(4, metadata.TraceParent.String())
+	for key, value := range metadata.Extensions {
+		size += sizeMessage(5, sizeExtension(key, value))
+	}
+
+	return size
+}
+
+func sizeExtension(key we.ExtensionKey, value string) int {
+	return sizeString(1, key.String()) + sizeString(2, value)
+}
+
+// appendString appends a string or bytes field, omitting it when empty as proto3 does.
+func appendString(b []byte, number protowire.Number, value string) []byte {
+	if value == "" {
+		return b
+	}
+
+	b = protowire.AppendTag(b, number, protowire.BytesType)
+	return protowire.AppendString(b, value)
+}
+
+func sizeString(number protowire.Number, value string) int {
+	if value == "" {
+		return 0
+	}
+
+	return protowire.SizeTag(number) + protowire.SizeBytes(len(value))
+}
+
+// appendMessage appends a message field of the given encoded size, omitting it when every field of the message is
+// empty.
+func appendMessage(b []byte, number protowire.Number, size int, message func(b []byte) []byte) []byte {
+	if size == 0 {
+		return b
+	}
+
+	b = protowire.AppendTag(b, number, protowire.BytesType)
+	b = protowire.AppendVarint(b, uint64(size))
+	return message(b)
+}
+
+func sizeMessage(number protowire.Number, size int) int {
+	if size == 0 {
+		return 0
+	}
+
+	return protowire.SizeTag(number) + protowire.SizeBytes(size)
+}
+
+func consumeEventRecord(data []byte, record *EventRecord) error {
+	return consumeMessage(data, func(number protowire.Number, value []byte) error {
+		switch number {
+		case 1:
+			return consumeMessage(value, func(number protowire.Number, value []byte) error {
+				switch number {
+				case 1:
+					record.AggregateId.Type = string(value)
+				case 2:
+					record.AggregateId.Key = string(value)
+				}
+				return nil
+			})
+		case 2:
+			record.EventID = we.EventID(value)
+		case 3:
+			record.EventType = we.EventType(value)
+		case 4:
+			record.Timestamp = we.Timestamp(value)
+		case 5:
+			return consumeMessage(value, func(number protowire.Number, value []byte) error {
+				switch number {
+				case 1:
+					record.Data.Encoding = string(value)
+				case 2:
+					record.Data.Data = append([]byte(nil), value...)
+				}
+				return nil
+			})
+		case 6:
+			return consumeMessage(value, func(number protowire.Number, value []byte) error {
+				switch number {
+				case 1:
+					record.Metadata.CausationId = we.EventID(value)
+				case 2:
+					record.Metadata.CorrelationId = we.CorrelationID(value)
+				case 3:
+					record.Metadata.Actor = we.Actor(value)
+				case 4:
+					record.Metadata.TraceParent = we.TraceParent(value)
+				case 5:
+					return consumeExtension(value, &record.Metadata)
+				}
+				return nil
+			})
+		}
+
+		return nil
+	})
+}
+
+func consumeExtension(data []byte, metadata *we.RecordedEventMetadata) error {
+	var key we.ExtensionKey
+	var value string
+	err := consumeMessage(data, func(number protowire.Number, field []byte) error {
+		switch number {
+		case 1:
+			key = we.ExtensionKey(field)
+		case 2:
+			value = string(field)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if metadata.Extensions == nil {
+		metadata.Extensions = we.Extensions{}
+	}
+	metadata.Extensions[key] = value
+
+	return nil
+}
+
+// consumeMessage calls field with the value of each length delimited field in the message. Every field in the schema
+// is length delimited, so fields of other wire types are skipped as unknown.
+func consumeMessage(data []byte, field func(number protowire.Number, value []byte) error) error {
+	for len(data) > 0 {
+		number, kind, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		if kind != protowire.BytesType {
+			n = protowire.ConsumeFieldValue(number, kind, data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			data = data[n:]
+			continue
+		}
+
+		value, n := protowire.ConsumeBytes(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		if err := field(number, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
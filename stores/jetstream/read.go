@@ -0,0 +1,220 @@
+package jetstream
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/rs/zerolog/log"
+
+	"github.com/weegigs/wee-events-go/we"
+)
+
+// ReadMode selects how the store reads the change sets for an aggregate.
+type ReadMode int
+
+const (
+	// DefaultReads reads change sets through an ordered consumer, which is served by the stream leader.
+	DefaultReads ReadMode = iota
+	// DirectGetReads reads each change set with a direct get, without creating a consumer. The stream must have been
+	// created with AllowDirect. Direct gets are answered by any of the stream's replicas, or its mirrors, which can lag
+	// the leader, so a load can miss change sets that were just published. Use them when reads can be stale.
+	DirectGetReads
+	// BatchedFetchReads reads change sets in batches through an ephemeral pull consumer.
+	BatchedFetchReads
+	// OrderedConsumerReads reads change sets through an ephemeral ordered push consumer.
+	OrderedConsumerReads
+)
+
+const defaultReadBatchSize = 256
+
+// fetchWait bounds each batched fetch when the context has no deadline.
+const fetchWait = 5 * time.Second
+
+// WithReadMode sets how the store loads aggregates. Loads are consistent with publishes unless DirectGetReads is
+// chosen, which trades that for reads that don't need the stream leader. Publishes and removals always read the last
+// change set from the leader.
+func WithReadMode(mode ReadMode) EventStoreOption {
+	return func(store *EventStore) {
+		store.readMode = mode
+	}
+}
+
+// WithReadBatchSize sets the number of change sets requested in each batched fetch.
+func WithReadBatchSize(size int) EventStoreOption {
+	return func(store *EventStore) {
+		if size <= 0 {
+			size = defaultReadBatchSize
+		}
+
+		store.readBatchSize = size
+	}
+}
+
+var DirectGetNotAllowed = errors.New("direct get reads require a stream created with AllowDirect")
+
+func (es *EventStore) selectReadMode(config nats.StreamConfig) error {
+	switch es.readMode {
+	case DefaultReads:
+		es.readMode = OrderedConsumerReads
+	case DirectGetReads:
+		if !config.AllowDirect {
+			return DirectGetNotAllowed
+		}
+	}
+
+	return nil
+}
+
+func (es *EventStore) read(ctx context.Context, subject string) ([]we.RecordedEvent, error) {
+	switch es.readMode {
+	case DirectGetReads:
+		return es.readDirect(ctx, subject)
+	case BatchedFetchReads:
+		return es.readBatched(ctx, subject)
+	default:
+		return es.readOrdered(ctx, subject)
+	}
+}
+
+// readDirect walks the subject with direct gets, each returning the next change set after the previous sequence.
+func (es *EventStore) readDirect(ctx context.Context, subject string) ([]we.RecordedEvent, error) {
+	var events []we.RecordedEvent
+	var sequence uint64 = 1
+	for {
+		msg, err := es.manager.GetMsg(es.name, sequence, nats.DirectGetNext(subject), nats.Context(ctx))
+		if errors.Is(err, nats.ErrMsgNotFound) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		recorded, err := es.decodeChangeSet(msg.Header, msg.Data, msg.Sequence, msg.Time)
+		if err != nil {
+			return nil, err
+		}
+
+		events = append(events, recorded...)
+		sequence = msg.Sequence + 1
+	}
+
+	return events, nil
+}
+
+func (es *EventStore) latest(ctx context.Context, subject string) (*uint64, error) {
+	msg, err := es.manager.GetLastMsg(es.name, subject, nats.Context(ctx))
+	if err != nil {
+		if err == nats.ErrMsgNotFound {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &msg.Sequence, nil
+}
+
+func (es *EventStore) readBatched(ctx context.Context, subject string) ([]we.RecordedEvent, error) {
+	latest, err := es.latest(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+
+	if latest == nil {
+		return nil, nil
+	}
+
+	subscription, err := es.stream.PullSubscribe(
+		subject, "",
+		nats.BindStream(es.name),
+		nats.DeliverAll(),
+		nats.AckNone(),
+		nats.InactiveThreshold(time.Minute),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer unsubscribe(subscription)
+
+	wait := []nats.PullOpt{nats.MaxWait(fetchWait)}
+	if _, ok := ctx.Deadline(); ok {
+		wait = []nats.PullOpt{nats.Context(ctx)}
+	}
+
+	var events []we.RecordedEvent
+	for {
+		msgs, err := subscription.Fetch(es.readBatchSize, wait...)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, msg := range msgs {
+			metadata, err := msg.Metadata()
+			if err != nil {
+				return nil, err
+			}
+
+			recorded, err := es.decodeChangeSet(msg.Header, msg.Data, metadata.Sequence.Stream, metadata.Timestamp)
+			if err != nil {
+				return nil, err
+			}
+
+			events = append(events, recorded...)
+
+			if metadata.Sequence.Stream >= *latest {
+				return events, nil
+			}
+		}
+	}
+}
+
+func (es *EventStore) readOrdered(ctx context.Context, subject string) ([]we.RecordedEvent, error) {
+	latest, err := es.latest(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+
+	if latest == nil {
+		return nil, nil
+	}
+
+	subscription, err := es.stream.SubscribeSync(subject, nats.DeliverAll(), nats.OrderedConsumer())
+	if err != nil {
+		return nil, err
+	}
+	defer unsubscribe(subscription)
+
+	var events []we.RecordedEvent
+	for {
+		msg, err := subscription.NextMsgWithContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		metadata, err := msg.Metadata()
+		if err != nil {
+			return nil, err
+		}
+
+		recorded, err := es.decodeChangeSet(msg.Header, msg.Data, metadata.Sequence.Stream, metadata.Timestamp)
+		if err != nil {
+			return nil, err
+		}
+
+		events = append(events, recorded...)
+
+		if metadata.Sequence.Stream >= *latest {
+			break
+		}
+	}
+
+	return events, nil
+}
+
+func unsubscribe(subscription *nats.Subscription) {
+	if err := subscription.Unsubscribe(); err != nil {
+		log.Err(err).Msg("ephemeral stream subscription failed to unsubscribe cleanly")
+	}
+}
@@ -0,0 +1,105 @@
+package jetstream_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/weegigs/wee-events-go/stores/jetstream"
+	"github.com/weegigs/wee-events-go/we"
+)
+
+func embeddedConnection(tb testing.TB) *nats.Conn {
+	nc, cleanup, err := jetstream.NewEmbeddedTestConnection(context.Background())
+	require.NoError(tb, err)
+	tb.Cleanup(cleanup)
+
+	return nc
+}
+
+var readModes = []struct {
+	name string
+	mode jetstream.ReadMode
+}{
+	{name: "direct get", mode: jetstream.DirectGetReads},
+	{name: "batched fetch", mode: jetstream.BatchedFetchReads},
+	{name: "ordered consumer", mode: jetstream.OrderedConsumerReads},
+}
+
+func publishChangeSets(tb testing.TB, store *jetstream.EventStore, id we.AggregateId, count int) {
+	for i := 0; i < count; i++ {
+		event := we.StoreValidationEvent{TestStringValue: fmt.Sprintf("event %d", i), TestIntValue: i}
+		require.NoError(tb, store.Publish(context.Background(), id, we.Options(), event))
+	}
+}
+
+func TestReadModes(t *testing.T) {
+	ctx := context.Background()
+	nc := embeddedConnection(t)
+
+	store, err := jetstream.NewEventStore("reads", nc, jetstream.WithReadMode(jetstream.OrderedConsumerReads))
+	require.NoError(t, err)
+
+	id := we.AggregateId{Type: "test", Key: "read-modes"}
+	publishChangeSets(t, store, id, 25)
+	publishChangeSets(t, store, we.AggregateId{Type: "test", Key: "other"}, 5)
+
+	expected, err := store.Load(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, 25, len(expected.Events))
+
+	for _, m := range readModes {
+		m := m
+		t.Run(m.name, func(t *testing.T) {
+			reader, err := jetstream.NewEventStore("reads", nc, jetstream.WithReadMode(m.mode), jetstream.WithReadBatchSize(7))
+			require.NoError(t, err)
+
+			loaded, err := reader.Load(ctx, id)
+			require.NoError(t, err)
+
+			assert.Equal(t, expected, loaded)
+		})
+	}
+
+	t.Run("direct gets require AllowDirect", func(t *testing.T) {
+		nc := embeddedConnection(t)
+		js, err := nc.JetStream()
+		require.NoError(t, err)
+
+		_, err = js.AddStream(&nats.StreamConfig{Name: "indirect", Subjects: []string{"change-set.>"}})
+		require.NoError(t, err)
+
+		_, err = jetstream.NewEventStore("indirect", nc, jetstream.WithReadMode(jetstream.DirectGetReads))
+		assert.Equal(t, jetstream.DirectGetNotAllowed, err)
+	})
+}
+
+func BenchmarkLoad(b *testing.B) {
+	ctx := context.Background()
+	nc := embeddedConnection(b)
+
+	writer, err := jetstream.NewEventStore("bench", nc)
+	require.NoError(b, err)
+
+	for _, size := range []int{1, 10, 100} {
+		id := we.AggregateId{Type: "bench", Key: fmt.Sprintf("change-sets-%d", size)}
+		publishChangeSets(b, writer, id, size)
+
+		for _, m := range readModes {
+			store, err := jetstream.NewEventStore("bench", nc, jetstream.WithReadMode(m.mode))
+			require.NoError(b, err)
+
+			b.Run(fmt.Sprintf("%s/%d", m.name, size), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					if _, err := store.Load(ctx, id); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
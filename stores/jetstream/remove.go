@@ -0,0 +1,127 @@
+package jetstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/weegigs/wee-events-go/internal"
+	"github.com/weegigs/wee-events-go/we"
+)
+
+// TombstoneHeader marks the message left on a hard deleted aggregate's subject.
+const TombstoneHeader = "Wee-Tombstone"
+
+// RemoveAggregate purges the aggregate's change sets from the stream. A hard deleted aggregate's subject is left
+// holding a tombstone, so publishing to it again fails, as does soft deleting it.
+func (es *EventStore) RemoveAggregate(ctx context.Context, id we.AggregateId, removal we.Removal) error {
+	subject, err := es.subject(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	switch removal.Mode {
+	case we.SoftDelete:
+		last, err := es.lastSequence(ctx, subject)
+		if err != nil || last == 0 {
+			return err
+		}
+
+		// only the messages up to the last one are purged, so a concurrently published tombstone is kept
+		return es.purge(subject, last+1)
+
+	case we.HardDelete:
+		tombstone := nats.NewMsg(subject)
+		tombstone.Header.Set(TombstoneHeader, "true")
+
+		ack, err := es.stream.PublishMsg(tombstone, nats.Context(ctx))
+		if err != nil {
+			return err
+		}
+
+		return es.purge(subject, ack.Sequence)
+
+	case we.TruncateBefore:
+		if removal.Before == we.InitialRevision {
+			return nil
+		}
+
+		// a purge from sequence 0 removes every message, so revisions that aren't from this store are rejected
+		sequence, err := internal.DecodeSequenceNumber(removal.Before)
+		if err != nil {
+			return err
+		}
+		if sequence == 0 {
+			return fmt.Errorf("%w: %q has no stream sequence", we.InvalidRevision, removal.Before)
+		}
+
+		return es.purge(subject, sequence)
+
+	default:
+		return we.UnsupportedRemoval
+	}
+}
+
+// purge removes the subject's messages before the sequence, or all of them when the sequence is 0.
+func (es *EventStore) purge(subject string, before uint64) error {
+	return es.manager.PurgeStream(es.name, &nats.StreamPurgeRequest{Subject: subject, Sequence: before})
+}
+
+func tombstoned(header nats.Header) bool {
+	return header.Get(TombstoneHeader) != ""
+}
+
+// deleted reports whether the last message on the subject is a tombstone.
+func (es *EventStore) deleted(ctx context.Context, subject string) (bool, error) {
+	_, err := es.lastSequence(ctx, subject)
+	if errors.Is(err, we.AggregateDeleted) {
+		return true, nil
+	}
+
+	return false, err
+}
+
+// lastSequence returns the sequence of the last message on the subject, 0 when there isn't one, or AggregateDeleted
+// when it's a tombstone. The message is read from the stream leader, even with direct get reads, as conditional
+// publishes depend on it being current.
+func (es *EventStore) lastSequence(ctx context.Context, subject string) (uint64, error) {
+	msg, err := es.manager.GetLastMsg(es.name, subject, nats.Context(ctx))
+	if errors.Is(err, nats.ErrMsgNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if tombstoned(msg.Header) {
+		return 0, we.AggregateDeleted
+	}
+
+	return msg.Sequence, nil
+}
+
+// lastChangeSet returns the sequence of the last message on the subject and the revision of its last event, 0 and the
+// initial revision when there isn't one, or AggregateDeleted when it's a tombstone. Like lastSequence, the message is
+// read from the stream leader.
+func (es *EventStore) lastChangeSet(ctx context.Context, subject string) (uint64, we.Revision, error) {
+	msg, err := es.manager.GetLastMsg(es.name, subject, nats.Context(ctx))
+	if errors.Is(err, nats.ErrMsgNotFound) {
+		return 0, we.InitialRevision, nil
+	}
+	if err != nil {
+		return 0, "", err
+	}
+
+	recorded, err := es.decodeChangeSet(msg.Header, msg.Data, msg.Sequence, msg.Time)
+	if err != nil {
+		return 0, "", err
+	}
+
+	if len(recorded) == 0 {
+		return msg.Sequence, we.InitialRevision, nil
+	}
+
+	return msg.Sequence, recorded[len(recorded)-1].Revision, nil
+}
@@ -0,0 +1,86 @@
+package jetstream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/nats-io/nats.go"
+	"github.com/weegigs/wee-events-go/we"
+)
+
+// NewSnapshotStore creates a snapshot store backed by the named key value bucket, creating the bucket if it does not
+// exist unless BindBucket is used.
+func NewSnapshotStore(name string, connection *nats.Conn, options ...BucketOption) (*SnapshotStore, error) {
+	kv, err := openBucket(name, "aggregate snapshots", connection, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SnapshotStore{kv: kv}, nil
+}
+
+// SnapshotStore is a we.SnapshotStore that keeps the latest snapshot of each aggregate. Saving a snapshot older than
+// the one stored is a no-op, so concurrent writers can't replace a snapshot with a stale one.
+type SnapshotStore struct {
+	kv nats.KeyValue
+}
+
+func (s *SnapshotStore) LoadSnapshot(_ context.Context, id we.AggregateId) (we.Snapshot, error) {
+	entry, err := s.kv.Get(key(id.Encode().String()))
+	if errors.Is(err, nats.ErrKeyNotFound) {
+		return we.Snapshot{}, we.SnapshotNotFound
+	}
+	if err != nil {
+		return we.Snapshot{}, err
+	}
+
+	var snapshot we.Snapshot
+	if err := json.Unmarshal(entry.Value(), &snapshot); err != nil {
+		return we.Snapshot{}, err
+	}
+
+	return snapshot, nil
+}
+
+func (s *SnapshotStore) SaveSnapshot(ctx context.Context, snapshot we.Snapshot) error {
+	k := key(snapshot.AggregateId.Encode().String())
+
+	value, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		entry, err := s.kv.Get(k)
+		if errors.Is(err, nats.ErrKeyNotFound) {
+			_, err = s.kv.Create(k, value)
+			if conflicted(err) {
+				continue
+			}
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		var current we.Snapshot
+		if err := json.Unmarshal(entry.Value(), &current); err != nil {
+			return err
+		}
+
+		if current.Revision >= snapshot.Revision {
+			return nil
+		}
+
+		_, err = s.kv.Update(k, value, entry.Revision())
+		if conflicted(err) {
+			continue
+		}
+		return err
+	}
+}
@@ -0,0 +1,108 @@
+package jetstream
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// WithStorage sets the storage used when the store creates its stream.
+func WithStorage(storage nats.StorageType) EventStoreOption {
+	return func(store *EventStore) {
+		store.config.Storage = storage
+	}
+}
+
+// WithReplicas sets the number of stream replicas used when the store creates its stream.
+func WithReplicas(replicas int) EventStoreOption {
+	return func(store *EventStore) {
+		store.config.Replicas = replicas
+	}
+}
+
+// WithMaxAge limits the age of change sets retained by a created stream.
+func WithMaxAge(age time.Duration) EventStoreOption {
+	return func(store *EventStore) {
+		store.config.MaxAge = age
+	}
+}
+
+// WithMaxBytes limits the size of a created stream.
+func WithMaxBytes(bytes int64) EventStoreOption {
+	return func(store *EventStore) {
+		store.config.MaxBytes = bytes
+	}
+}
+
+// WithDuplicateWindow sets the window in which a created stream rejects duplicate messages.
+func WithDuplicateWindow(window time.Duration) EventStoreOption {
+	return func(store *EventStore) {
+		store.config.Duplicates = window
+	}
+}
+
+// BindOnly binds the store to an existing stream rather than creating it.
+func BindOnly() EventStoreOption {
+	return func(store *EventStore) {
+		store.bind = true
+	}
+}
+
+var StreamNotFound = errors.New("change set stream not found")
+
+// StreamSubjectsMismatch is returned when an existing stream does not capture the store's change set subjects.
+type StreamSubjectsMismatch struct {
+	Stream   string
+	Expected []string
+	Actual   []string
+}
+
+func (e *StreamSubjectsMismatch) Error() string {
+	return fmt.Sprintf("stream %s has subjects %v, expected %v", e.Stream, e.Actual, e.Expected)
+}
+
+func streamConfig(name string) nats.StreamConfig {
+	return nats.StreamConfig{
+		Name:        name,
+		Description: "change set stream for " + name,
+		Subjects:    []string{prefix + ">"},
+		AllowDirect: true,
+	}
+}
+
+func (es *EventStore) ensureStream() error {
+	info, err := es.manager.StreamInfo(es.name)
+	if errors.Is(err, nats.ErrStreamNotFound) {
+		if es.bind {
+			return StreamNotFound
+		}
+
+		info, err = es.manager.AddStream(&es.config)
+		if err != nil {
+			return fmt.Errorf("failed to create change set stream: %w", err)
+		}
+
+		return es.selectReadMode(info.Config)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to read change set stream: %w", err)
+	}
+
+	if err := verifySubjects(info.Config); err != nil {
+		return err
+	}
+
+	return es.selectReadMode(info.Config)
+}
+
+func verifySubjects(config nats.StreamConfig) error {
+	expected := []string{prefix + ">"}
+	if len(config.Subjects) != 1 || config.Subjects[0] != expected[0] {
+		return &StreamSubjectsMismatch{Stream: config.Name, Expected: expected, Actual: config.Subjects}
+	}
+
+	return nil
+}
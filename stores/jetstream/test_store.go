@@ -3,12 +3,29 @@ package jetstream
 import (
   "context"
   "fmt"
+
   "github.com/nats-io/nats.go"
   "github.com/testcontainers/testcontainers-go"
   "github.com/testcontainers/testcontainers-go/wait"
 )
 
 func NewTestStore(ctx context.Context, options ...EventStoreOption) (*EventStore, func(), error) {
+  nc, cleanup, err := NewTestConnection(ctx)
+  if err != nil {
+    return nil, nil, err
+  }
+
+  store, err := NewEventStore("test", nc, options...)
+  if err != nil {
+    cleanup()
+    return nil, nil, err
+  }
+
+  return store, cleanup, nil
+}
+
+// NewTestConnection starts a JetStream enabled nats container and connects to it.
+func NewTestConnection(ctx context.Context) (*nats.Conn, func(), error) {
   db, err := testcontainers.GenericContainer(
     ctx, testcontainers.GenericContainerRequest{
       ContainerRequest: testcontainers.ContainerRequest{
@@ -24,27 +41,33 @@ func NewTestStore(ctx context.Context, options ...EventStoreOption) (*EventStore
     return nil, nil, err
   }
 
+  cleanup := func() {
+    if err := db.Terminate(ctx); err != nil {
+      panic(err)
+    }
+  }
+
   host, err := db.Host(ctx)
   if err != nil {
+    cleanup()
     return nil, nil, err
   }
 
   port, err := db.MappedPort(ctx, "4222")
   if err != nil {
+    cleanup()
     return nil, nil, err
   }
 
   url := fmt.Sprintf("nats://%s:%s", host, port.Port())
   nc, err := nats.Connect(url)
   if err != nil {
+    cleanup()
     return nil, nil, err
   }
 
-  store := NewEventStore("test", nc, options...)
-
-  return store, func() {
-    if err := db.Terminate(ctx); err != nil {
-      panic(err)
-    }
+  return nc, func() {
+    nc.Close()
+    cleanup()
   }, nil
 }
@@ -0,0 +1,31 @@
+-- KEYS[1]: the aggregate's stream
+-- ARGV[1]: the expected last entry id, "0-0" for an empty stream or "" to append unconditionally
+-- ARGV[2]: the number of field and value arguments per event
+-- ARGV[3..]: the fields and values of each event
+local expected = ARGV[1]
+
+if expected ~= "" then
+  local last = redis.call("XREVRANGE", KEYS[1], "+", "-", "COUNT", 1)
+  local current = "0-0"
+  if #last > 0 then
+    current = last[1][1]
+  end
+
+  if current ~= expected then
+    return false
+  end
+end
+
+local width = tonumber(ARGV[2])
+local ids = {}
+
+for i = 3, #ARGV, width do
+  local command = { "XADD", KEYS[1], "*" }
+  for j = i, i + width - 1 do
+    command[#command + 1] = ARGV[j]
+  end
+
+  ids[#ids + 1] = redis.call(unpack(command))
+end
+
+return ids
@@ -0,0 +1,308 @@
+package rediss
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/weegigs/wee-events-go/we"
+)
+
+// RedisEventStore stores each aggregate as a Redis stream with an entry per event. Entry ids are assigned by Redis and
+// map directly to revisions.
+type RedisEventStore struct {
+	client    redis.UniversalClient
+	prefix    string
+	batchSize int64
+	clock     we.Clock
+	ids       we.IDGenerator
+}
+
+type EventStoreOption func(*RedisEventStore)
+
+const (
+	defaultPrefix    = "we:aggregate:"
+	defaultBatchSize = 500
+)
+
+// WithKeyPrefix sets the prefix of the aggregate stream keys.
+func WithKeyPrefix(prefix string) EventStoreOption {
+	return func(store *RedisEventStore) {
+		store.prefix = prefix
+	}
+}
+
+// WithReadBatchSize sets the number of entries read from a stream per request when loading an aggregate.
+func WithReadBatchSize(size int64) EventStoreOption {
+	return func(store *RedisEventStore) {
+		if size > 0 {
+			store.batchSize = size
+		}
+	}
+}
+
+// WithClock sets the clock that published events are timestamped and their ids created with. Revisions are still
+// taken from the entry ids assigned by Redis.
+func WithClock(clock we.Clock) EventStoreOption {
+	return func(store *RedisEventStore) {
+		store.clock = clock
+	}
+}
+
+// WithIdGenerator sets the generator of the ids of published events.
+func WithIdGenerator(generator we.IDGenerator) EventStoreOption {
+	return func(store *RedisEventStore) {
+		store.ids = generator
+	}
+}
+
+func NewEventStore(client redis.UniversalClient, options ...EventStoreOption) *RedisEventStore {
+	store := &RedisEventStore{client: client, prefix: defaultPrefix, batchSize: defaultBatchSize, clock: we.SystemClock}
+
+	for _, option := range options {
+		option(store)
+	}
+
+	if store.ids == nil {
+		store.ids = we.NewULIDGenerator(store.clock)
+	}
+
+	return store
+}
+
+func (rs *RedisEventStore) key(id we.AggregateId) string {
+	return rs.prefix + id.Encode().String()
+}
+
+// entry fields
+const (
+	fieldId            = "id"
+	fieldType          = "type"
+	fieldEncoding      = "encoding"
+	fieldData          = "data"
+	fieldCausationId   = "causation"
+	fieldCorrelationId = "correlation"
+	// fieldTimestamp holds the time events were recorded at, entries written before it was take theirs from the entry id
+	fieldTimestamp   = "timestamp"
+	fieldActor       = "actor"
+	fieldTraceParent = "traceparent"
+	// fieldExtensions holds the metadata extensions as a JSON object, empty when there are none
+	fieldExtensions = "extensions"
+
+	fieldsPerEvent = 20
+)
+
+// appendScript adds the events to the stream if its last entry id is the expected one, returning the ids of the new
+// entries, or nil when the stream has moved on. An empty expected id appends unconditionally.
+//
+//go:embed append.lua
+var appendSource string
+
+var appendScript = redis.NewScript(appendSource)
+
+func (rs *RedisEventStore) Publish(ctx context.Context, aggregateId we.AggregateId, options we.PublishOptions, events ...we.DomainEvent) error {
+	_, err := rs.PublishRevision(ctx, aggregateId, options, events...)
+	return err
+}
+
+// PublishRevision appends the events atomically with a script that compares the stream's last entry id with the
+// expected revision, returning the revision of the last event.
+func (rs *RedisEventStore) PublishRevision(ctx context.Context, aggregateId we.AggregateId, options we.PublishOptions, events ...we.DomainEvent) (we.Revision, error) {
+	if len(events) == 0 {
+		return "", errors.New("attempted to publish empty list of events")
+	}
+
+	timestamp := we.TimestampFromTime(rs.clock.Now())
+	recorded := make([]we.RecordedEvent, len(events))
+	for index, event := range events {
+		data, err := we.MarshalToData(event)
+		if err != nil {
+			return "", err
+		}
+
+		recorded[index] = we.RecordedEvent{
+			EventID:   rs.ids.Create(),
+			EventType: we.EventTypeOf(event),
+			Timestamp: timestamp,
+			Metadata:  options.RecordedEventMetadata,
+			Data:      data,
+		}
+	}
+
+	return rs.append(ctx, aggregateId, options.ExpectedRevision, recorded)
+}
+
+// Append imports the events atomically, keeping their ids, types, timestamps and metadata.
+func (rs *RedisEventStore) Append(ctx context.Context, id we.AggregateId, expected we.Revision, events []we.RecordedEvent) (we.Revision, error) {
+	if len(events) == 0 {
+		return "", errors.New("attempted to append empty list of events")
+	}
+
+	return rs.append(ctx, id, expected, events)
+}
+
+func (rs *RedisEventStore) append(ctx context.Context, aggregateId we.AggregateId, expectedRevision we.Revision, events []we.RecordedEvent) (we.Revision, error) {
+	expected := ""
+	if expectedRevision != "" {
+		id, err := entryId(expectedRevision)
+		if err != nil {
+			return "", err
+		}
+		expected = id
+	}
+
+	args := make([]interface{}, 0, 2+len(events)*fieldsPerEvent)
+	args = append(args, expected, fieldsPerEvent)
+
+	for _, event := range events {
+		extensions, err := encodeExtensions(event.Metadata.Extensions)
+		if err != nil {
+			return "", err
+		}
+
+		args = append(args,
+			fieldId, event.EventID.String(),
+			fieldType, event.EventType.String(),
+			fieldEncoding, event.Data.Encoding,
+			fieldData, []byte(event.Data.Data),
+			fieldCausationId, event.Metadata.CausationId.String(),
+			fieldCorrelationId, event.Metadata.CorrelationId.String(),
+			fieldTimestamp, event.Timestamp.String(),
+			fieldActor, event.Metadata.Actor.String(),
+			fieldTraceParent, event.Metadata.TraceParent.String(),
+			fieldExtensions, extensions,
+		)
+	}
+
+	ids, err := appendScript.Run(ctx, rs.client, []string{rs.key(aggregateId)}, args...).StringSlice()
+	if errors.Is(err, redis.Nil) {
+		return "", we.RevisionConflict
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to append events: %w", err)
+	}
+
+	if len(ids) != len(events) {
+		return "", fmt.Errorf("appended %d of %d events", len(ids), len(events))
+	}
+
+	return encodeRevision(ids[len(ids)-1])
+}
+
+func (rs *RedisEventStore) Load(ctx context.Context, id we.AggregateId) (we.Aggregate, error) {
+	events, err := rs.read(ctx, id)
+	if err != nil {
+		return we.Aggregate{}, err
+	}
+
+	revision := we.InitialRevision
+	if len(events) > 0 {
+		revision = events[len(events)-1].Revision
+	}
+
+	return we.Aggregate{
+		Id:       id,
+		Events:   events,
+		Revision: revision,
+	}, nil
+}
+
+func (rs *RedisEventStore) read(ctx context.Context, id we.AggregateId) ([]we.RecordedEvent, error) {
+	key := rs.key(id)
+	start := "-"
+
+	var events []we.RecordedEvent
+	for {
+		messages, err := rs.client.XRangeN(ctx, key, start, "+", rs.batchSize).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stream: %w", err)
+		}
+
+		for _, message := range messages {
+			event, err := recordedEvent(id, message)
+			if err != nil {
+				return nil, err
+			}
+
+			events = append(events, event)
+		}
+
+		if int64(len(messages)) < rs.batchSize {
+			return events, nil
+		}
+
+		// ranges are inclusive, so the next batch starts at the entry after the last one read
+		ms, sequence, err := parseEntryId(messages[len(messages)-1].ID)
+		if err != nil {
+			return nil, err
+		}
+		start = fmt.Sprintf("%d-%d", ms, sequence+1)
+	}
+}
+
+func recordedEvent(id we.AggregateId, message redis.XMessage) (we.RecordedEvent, error) {
+	revision, err := encodeRevision(message.ID)
+	if err != nil {
+		return we.RecordedEvent{}, err
+	}
+
+	ms, _, err := parseEntryId(message.ID)
+	if err != nil {
+		return we.RecordedEvent{}, err
+	}
+
+	field := func(name string) string {
+		value, _ := message.Values[name].(string)
+		return value
+	}
+
+	timestamp := we.Timestamp(field(fieldTimestamp))
+	if timestamp == "" {
+		timestamp = we.TimestampFromTime(time.UnixMilli(int64(ms)))
+	}
+
+	var extensions we.Extensions
+	if encoded := field(fieldExtensions); encoded != "" {
+		if err := json.Unmarshal([]byte(encoded), &extensions); err != nil {
+			return we.RecordedEvent{}, fmt.Errorf("failed to unmarshal metadata extensions: %w", err)
+		}
+	}
+
+	return we.RecordedEvent{
+		AggregateId: id,
+		Revision:    revision,
+		EventID:     we.EventID(field(fieldId)),
+		EventType:   we.EventType(field(fieldType)),
+		Timestamp:   timestamp,
+		Metadata: we.RecordedEventMetadata{
+			CausationId:   we.EventID(field(fieldCausationId)),
+			CorrelationId: we.CorrelationID(field(fieldCorrelationId)),
+			Actor:         we.Actor(field(fieldActor)),
+			TraceParent:   we.TraceParent(field(fieldTraceParent)),
+			Extensions:    extensions,
+		},
+		Data: we.Data{
+			Encoding: field(fieldEncoding),
+			Data:     []byte(field(fieldData)),
+		},
+	}, nil
+}
+
+// encodeExtensions returns the extensions as a JSON object, or an empty string when there are none.
+func encodeExtensions(extensions we.Extensions) (string, error) {
+	if len(extensions) == 0 {
+		return "", nil
+	}
+
+	encoded, err := json.Marshal(extensions)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal metadata extensions: %w", err)
+	}
+
+	return string(encoded), nil
+}
@@ -0,0 +1,87 @@
+package rediss
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/oklog/ulid/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/weegigs/wee-events-go/we"
+)
+
+type Tested struct {
+	Value string `json:"value"`
+}
+
+func (Tested) EventType() we.EventType {
+	return "test:tested"
+}
+
+func testClient(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	return server, client
+}
+
+func TestRedisEventStore(t *testing.T) {
+	ctx := context.Background()
+	server, client := testClient(t)
+	store := NewEventStore(client)
+
+	t.Run("redis event store validation", func(t *testing.T) {
+		we.NewEventStoreValidationSuite(ctx, store).Run(t)
+	})
+
+	t.Run("validation with small read batches", func(t *testing.T) {
+		we.NewEventStoreValidationSuite(ctx, NewEventStore(client, WithReadBatchSize(2))).Run(t)
+	})
+
+	t.Run("aggregates are stored as streams", func(t *testing.T) {
+		id := we.AggregateId{Type: "test", Key: ulid.Make().String()}
+		store := NewEventStore(client, WithKeyPrefix("events:"))
+
+		revision, err := store.PublishRevision(ctx, id, we.Options(), Tested{"one"}, Tested{"two"})
+		require.NoError(t, err)
+
+		entries, err := server.Stream("events:" + id.Encode().String())
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+
+		last, err := entryId(revision)
+		require.NoError(t, err)
+		assert.Equal(t, entries[1].ID, last)
+	})
+}
+
+func TestRevisionEncoding(t *testing.T) {
+	for _, id := range []string{"1-0", "1526919030474-55", "1700000000000-18446744073709551615"} {
+		revision, err := encodeRevision(id)
+		require.NoError(t, err)
+
+		decoded, err := entryId(revision)
+		require.NoError(t, err)
+		assert.Equal(t, id, decoded)
+	}
+
+	earlier, err := encodeRevision("1526919030474-9")
+	require.NoError(t, err)
+	later, err := encodeRevision("1526919030474-10")
+	require.NoError(t, err)
+	assert.Less(t, earlier.String(), later.String())
+
+	initial, err := entryId(we.InitialRevision)
+	require.NoError(t, err)
+	assert.Equal(t, initialEntryId, initial)
+
+	_, err = entryId("not-a-revision")
+	assert.ErrorIs(t, err, InvalidRevision)
+
+	_, err = encodeRevision("not-an-id")
+	assert.Error(t, err)
+}
@@ -0,0 +1,67 @@
+package rediss
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/oklog/ulid/v2"
+
+	"github.com/weegigs/wee-events-go/internal"
+	"github.com/weegigs/wee-events-go/we"
+)
+
+var InvalidRevision = errors.New("invalid-revision")
+
+// initialEntryId is the id Redis reports as the last id of a stream with no entries.
+const initialEntryId = "0-0"
+
+// encodeRevision maps a stream entry id, milliseconds and sequence, to a revision with the same time and sequence, so
+// revisions sort in the same order as the entries.
+func encodeRevision(entryId string) (we.Revision, error) {
+	ms, sequence, err := parseEntryId(entryId)
+	if err != nil {
+		return "", err
+	}
+
+	return internal.EncodeRevision(ms, sequence, 0)
+}
+
+// entryId maps a revision back to the id of the stream entry it was encoded from.
+func entryId(revision we.Revision) (string, error) {
+	if revision == we.InitialRevision {
+		return initialEntryId, nil
+	}
+
+	parsed, err := ulid.Parse(revision.String())
+	if err != nil {
+		return "", InvalidRevision
+	}
+
+	sequence, err := internal.DecodeSequenceNumber(revision)
+	if err != nil {
+		return "", InvalidRevision
+	}
+
+	return fmt.Sprintf("%d-%d", parsed.Time(), sequence), nil
+}
+
+func parseEntryId(id string) (uint64, uint64, error) {
+	ms, sequence, ok := strings.Cut(id, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid stream entry id %q", id)
+	}
+
+	timestamp, err := strconv.ParseUint(ms, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid stream entry id %q: %w", id, err)
+	}
+
+	seq, err := strconv.ParseUint(sequence, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid stream entry id %q: %w", id, err)
+	}
+
+	return timestamp, seq, nil
+}
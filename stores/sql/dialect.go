@@ -0,0 +1,146 @@
+package sqls
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Dialect describes the differences between the databases supported by the store. The store doesn't import any
+// drivers, callers open the database with the driver of their choice.
+type Dialect interface {
+	Name() string
+	// Placeholder returns the bind parameter for the nth, 1 based, argument.
+	Placeholder(n int) string
+	// Migrations returns the statements that create the store's schema, in the order they are applied.
+	Migrations() []Migration
+	// UniqueViolation reports whether the error was caused by a unique constraint.
+	UniqueViolation(err error) bool
+}
+
+// Migration is a numbered set of statements applied in a single transaction.
+type Migration struct {
+	Version    int
+	Statements []string
+}
+
+const (
+	eventsTable     = "we_events"
+	migrationsTable = "we_migrations"
+)
+
+// SQLite is the dialect for SQLite, tested with modernc.org/sqlite. Publishing reads the latest version before
+// writing, so databases shared by concurrent publishers should begin transactions immediately, with _txlock=immediate,
+// and set a busy timeout.
+var SQLite Dialect = sqlite{}
+
+type sqlite struct{}
+
+func (sqlite) Name() string {
+	return "sqlite"
+}
+
+func (sqlite) Placeholder(int) string {
+	return "?"
+}
+
+func (sqlite) Migrations() []Migration {
+	return []Migration{
+		{
+			Version: 1,
+			Statements: []string{
+				`CREATE TABLE ` + eventsTable + ` (
+					aggregate_type TEXT NOT NULL,
+					aggregate_key TEXT NOT NULL,
+					version INTEGER NOT NULL,
+					event_id TEXT NOT NULL,
+					event_type TEXT NOT NULL,
+					revision TEXT NOT NULL,
+					recorded_at TEXT NOT NULL,
+					encoding TEXT NOT NULL,
+					data BLOB NOT NULL,
+					causation_id TEXT NOT NULL DEFAULT '',
+					correlation_id TEXT NOT NULL DEFAULT '',
+					PRIMARY KEY (aggregate_type, aggregate_key, version)
+				)`,
+			},
+		},
+		metadataMigration,
+	}
+}
+
+// metadataMigration adds the columns of the metadata beyond causation and correlation, with extensions kept as a
+// JSON object. The statements are common to the dialects.
+var metadataMigration = Migration{
+	Version: 2,
+	Statements: []string{
+		`ALTER TABLE ` + eventsTable + ` ADD COLUMN actor TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE ` + eventsTable + ` ADD COLUMN trace_parent TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE ` + eventsTable + ` ADD COLUMN extensions TEXT NOT NULL DEFAULT ''`,
+	},
+}
+
+// sqlite constraint violations are reported as extended result codes
+const (
+	sqliteConstraintPrimaryKey = 1555
+	sqliteConstraintUnique     = 2067
+)
+
+func (sqlite) UniqueViolation(err error) bool {
+	var coded interface{ Code() int }
+	if errors.As(err, &coded) {
+		code := coded.Code()
+		return code == sqliteConstraintPrimaryKey || code == sqliteConstraintUnique
+	}
+
+	return strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
+
+// PostgreSQL is the dialect for PostgreSQL, tested with github.com/lib/pq. Drivers such as pgx can also be used.
+var PostgreSQL Dialect = postgres{}
+
+type postgres struct{}
+
+func (postgres) Name() string {
+	return "postgres"
+}
+
+func (postgres) Placeholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+func (postgres) Migrations() []Migration {
+	return []Migration{
+		{
+			Version: 1,
+			Statements: []string{
+				`CREATE TABLE ` + eventsTable + ` (
+					aggregate_type TEXT NOT NULL,
+					aggregate_key TEXT NOT NULL,
+					version BIGINT NOT NULL,
+					event_id TEXT NOT NULL,
+					event_type TEXT NOT NULL,
+					revision TEXT NOT NULL,
+					recorded_at TEXT NOT NULL,
+					encoding TEXT NOT NULL,
+					data BYTEA NOT NULL,
+					causation_id TEXT NOT NULL DEFAULT '',
+					correlation_id TEXT NOT NULL DEFAULT '',
+					PRIMARY KEY (aggregate_type, aggregate_key, version)
+				)`,
+			},
+		},
+		metadataMigration,
+	}
+}
+
+const postgresUniqueViolation = "23505"
+
+func (postgres) UniqueViolation(err error) bool {
+	var state interface{ SQLState() string }
+	if errors.As(err, &state) {
+		return state.SQLState() == postgresUniqueViolation
+	}
+
+	return strings.Contains(err.Error(), postgresUniqueViolation)
+}
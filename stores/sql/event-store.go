@@ -0,0 +1,336 @@
+package sqls
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/avast/retry-go"
+	"github.com/oklog/ulid/v2"
+	"github.com/pkg/errors"
+
+	"github.com/weegigs/wee-events-go/internal"
+	"github.com/weegigs/wee-events-go/we"
+)
+
+// SQLEventStore stores events as rows keyed by aggregate and version, the 1 based position of the event in the
+// aggregate. Optimistic concurrency is enforced by the (aggregate, version) primary key.
+type SQLEventStore struct {
+	db      *sql.DB
+	dialect Dialect
+	clock   we.Clock
+	ids     we.IDGenerator
+
+	publishAttempts uint
+}
+
+type EventStoreOption func(*SQLEventStore)
+
+const defaultPublishAttempts = 3
+
+// WithPublishAttempts sets the number of attempts made to publish events without an expected revision when a
+// concurrent publish claims the same versions.
+func WithPublishAttempts(attempts uint) EventStoreOption {
+	return func(store *SQLEventStore) {
+		if attempts == 0 {
+			attempts = 1
+		}
+
+		store.publishAttempts = attempts
+	}
+}
+
+// WithClock sets the clock that events are recorded at and event ids are created with.
+func WithClock(clock we.Clock) EventStoreOption {
+	return func(store *SQLEventStore) {
+		store.clock = clock
+	}
+}
+
+// WithIdGenerator sets the generator of the ids of published events.
+func WithIdGenerator(generator we.IDGenerator) EventStoreOption {
+	return func(store *SQLEventStore) {
+		store.ids = generator
+	}
+}
+
+// NewEventStore creates an event store using the database, which must have been migrated with Migrate.
+func NewEventStore(db *sql.DB, dialect Dialect, options ...EventStoreOption) *SQLEventStore {
+	store := &SQLEventStore{db: db, dialect: dialect, clock: we.SystemClock, publishAttempts: defaultPublishAttempts}
+
+	for _, option := range options {
+		option(store)
+	}
+
+	if store.ids == nil {
+		store.ids = we.NewULIDGenerator(store.clock)
+	}
+
+	return store
+}
+
+// placeholders returns the comma separated bind parameters for count arguments starting at the nth.
+func (es *SQLEventStore) placeholders(n int, count int) string {
+	params := make([]string, count)
+	for i := range params {
+		params[i] = es.dialect.Placeholder(n + i)
+	}
+
+	return strings.Join(params, ", ")
+}
+
+func (es *SQLEventStore) Load(ctx context.Context, id we.AggregateId) (we.Aggregate, error) {
+	events, err := es.read(ctx, id)
+	if err != nil {
+		return we.Aggregate{}, err
+	}
+
+	revision := we.InitialRevision
+	if len(events) > 0 {
+		revision = events[len(events)-1].Revision
+	}
+
+	return we.Aggregate{
+		Id:       id,
+		Events:   events,
+		Revision: revision,
+	}, nil
+}
+
+func (es *SQLEventStore) read(ctx context.Context, id we.AggregateId) ([]we.RecordedEvent, error) {
+	query := fmt.Sprintf(`SELECT event_id, event_type, revision, recorded_at, encoding, data, causation_id, correlation_id,
+		actor, trace_parent, extensions FROM %s WHERE aggregate_type = %s AND aggregate_key = %s ORDER BY version`,
+		eventsTable, es.dialect.Placeholder(1), es.dialect.Placeholder(2))
+
+	rows, err := es.db.QueryContext(ctx, query, id.Type, id.Key)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read events")
+	}
+	defer rows.Close()
+
+	var events []we.RecordedEvent
+	for rows.Next() {
+		event := we.RecordedEvent{AggregateId: id}
+		var extensions string
+		err := rows.Scan(
+			&event.EventID,
+			&event.EventType,
+			&event.Revision,
+			&event.Timestamp,
+			&event.Data.Encoding,
+			&event.Data.Data,
+			&event.Metadata.CausationId,
+			&event.Metadata.CorrelationId,
+			&event.Metadata.Actor,
+			&event.Metadata.TraceParent,
+			&extensions,
+		)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan event")
+		}
+
+		if extensions != "" {
+			if err := json.Unmarshal([]byte(extensions), &event.Metadata.Extensions); err != nil {
+				return nil, errors.Wrap(err, "failed to unmarshal metadata extensions")
+			}
+		}
+
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read events")
+	}
+
+	return events, nil
+}
+
+func (es *SQLEventStore) Publish(ctx context.Context, aggregateId we.AggregateId, options we.PublishOptions, events ...we.DomainEvent) error {
+	_, err := es.PublishRevision(ctx, aggregateId, options, events...)
+	return err
+}
+
+// PublishRevision publishes the events in a single transaction and returns the revision of the last event. Without
+// an expected revision the events are appended to the latest version, retrying if a concurrent publish gets there
+// first.
+func (es *SQLEventStore) PublishRevision(ctx context.Context, aggregateId we.AggregateId, options we.PublishOptions, events ...we.DomainEvent) (we.Revision, error) {
+	if len(events) == 0 {
+		return "", errors.New("attempted to publish empty list of events")
+	}
+
+	timestamp := we.TimestampFromTime(es.clock.Now())
+	recorded := make([]we.RecordedEvent, len(events))
+	for index, event := range events {
+		data, err := we.MarshalToData(event)
+		if err != nil {
+			return "", err
+		}
+
+		recorded[index] = we.RecordedEvent{
+			EventID:   es.ids.Create(),
+			EventType: we.EventTypeOf(event),
+			Timestamp: timestamp,
+			Metadata:  options.RecordedEventMetadata,
+			Data:      data,
+		}
+	}
+
+	return es.write(ctx, aggregateId, options.ExpectedRevision, recorded)
+}
+
+// Append imports the events in a single transaction, keeping their ids, types, timestamps and metadata.
+func (es *SQLEventStore) Append(ctx context.Context, id we.AggregateId, expected we.Revision, events []we.RecordedEvent) (we.Revision, error) {
+	if len(events) == 0 {
+		return "", errors.New("attempted to append empty list of events")
+	}
+
+	return es.write(ctx, id, expected, events)
+}
+
+func (es *SQLEventStore) write(ctx context.Context, aggregateId we.AggregateId, expectedRevision we.Revision, events []we.RecordedEvent) (we.Revision, error) {
+	expected, err := expectedVersion(expectedRevision)
+	if err != nil {
+		return "", err
+	}
+
+	if expected >= 0 {
+		return es.insert(ctx, aggregateId, expected, events)
+	}
+
+	var revision we.Revision
+	err = retry.Do(
+		func() error {
+			revision, err = es.insert(ctx, aggregateId, expected, events)
+			return err
+		},
+		retry.Attempts(es.publishAttempts),
+		retry.RetryIf(func(err error) bool {
+			return errors.Is(err, we.RevisionConflict)
+		}),
+		retry.Context(ctx),
+		retry.LastErrorOnly(true),
+	)
+
+	return revision, err
+}
+
+// expectedVersion returns the version the aggregate must be at, or -1 when any version will do. Revisions whose
+// sequence is too large to be a version, like those made by a RevisionGenerator or another store, are invalid.
+func expectedVersion(revision we.Revision) (int64, error) {
+	switch revision {
+	case "":
+		return -1, nil
+	case we.InitialRevision:
+		return 0, nil
+	}
+
+	version, err := internal.DecodeSequenceNumber(revision)
+	if err != nil {
+		return 0, errors.Wrap(err, "invalid expected revision")
+	}
+
+	if version > math.MaxInt64 {
+		return 0, errors.Wrapf(we.InvalidRevision, "expected revision %s doesn't encode a version", revision)
+	}
+
+	return int64(version), nil
+}
+
+// insert writes the events after the aggregate's latest version, failing with a revision conflict if the aggregate
+// isn't at the expected version.
+func (es *SQLEventStore) insert(ctx context.Context, aggregateId we.AggregateId, expected int64, events []we.RecordedEvent) (we.Revision, error) {
+	tx, err := es.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to begin transaction")
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var latest int64
+	var previous we.Revision
+	query := fmt.Sprintf(`SELECT version, revision FROM %s WHERE aggregate_type = %s AND aggregate_key = %s
+		ORDER BY version DESC LIMIT 1`, eventsTable, es.dialect.Placeholder(1), es.dialect.Placeholder(2))
+	err = tx.QueryRowContext(ctx, query, aggregateId.Type, aggregateId.Key).Scan(&latest, &previous)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", es.conflicted(err, "failed to read latest version")
+	}
+
+	if expected >= 0 && latest != expected {
+		return "", we.RevisionConflict
+	}
+
+	// the clock is held back to the latest revision so the aggregate's revisions increase
+	now := es.clock.Now()
+	if recorded, err := previous.Time(); err == nil && recorded.After(now) {
+		now = recorded
+	}
+
+	insert := fmt.Sprintf(`INSERT INTO %s (aggregate_type, aggregate_key, version, event_id, event_type, revision,
+		recorded_at, encoding, data, causation_id, correlation_id, actor, trace_parent, extensions) VALUES (%s)`,
+		eventsTable, es.placeholders(1, 14))
+
+	var revision we.Revision
+	for index, event := range events {
+		extensions, err := encodeExtensions(event.Metadata.Extensions)
+		if err != nil {
+			return "", err
+		}
+
+		version := latest + int64(index) + 1
+		revision, err = internal.EncodeRevision(ulid.Timestamp(now), uint64(version), 0)
+		if err != nil {
+			return "", err
+		}
+
+		_, err = tx.ExecContext(ctx, insert,
+			aggregateId.Type,
+			aggregateId.Key,
+			version,
+			event.EventID.String(),
+			event.EventType.String(),
+			revision.String(),
+			event.Timestamp.String(),
+			event.Data.Encoding,
+			[]byte(event.Data.Data),
+			event.Metadata.CausationId.String(),
+			event.Metadata.CorrelationId.String(),
+			event.Metadata.Actor.String(),
+			event.Metadata.TraceParent.String(),
+			extensions,
+		)
+		if err != nil {
+			return "", es.conflicted(err, "failed to insert event")
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", es.conflicted(err, "failed to commit events")
+	}
+
+	return revision, nil
+}
+
+// encodeExtensions returns the extensions as a JSON object, or an empty string when there are none.
+func encodeExtensions(extensions we.Extensions) (string, error) {
+	if len(extensions) == 0 {
+		return "", nil
+	}
+
+	encoded, err := json.Marshal(extensions)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to marshal metadata extensions")
+	}
+
+	return string(encoded), nil
+}
+
+// conflicted maps unique violations, raised when a concurrent publish has claimed the versions, to revision conflicts.
+func (es *SQLEventStore) conflicted(err error, message string) error {
+	if es.dialect.UniqueViolation(err) {
+		return we.RevisionConflict
+	}
+
+	return errors.Wrap(err, message)
+}
@@ -0,0 +1,167 @@
+package sqls
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+
+	"github.com/weegigs/wee-events-go/we"
+)
+
+type Tested struct {
+	Value string `json:"value"`
+}
+
+func (Tested) EventType() we.EventType {
+	return "test:tested"
+}
+
+func openSQLite(t *testing.T) *sql.DB {
+	dsn := filepath.Join(t.TempDir(), "events.db") + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_txlock=immediate"
+	db, err := sql.Open("sqlite", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	return db
+}
+
+func TestSQLiteEventStore(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+
+	applied, err := Migrate(ctx, db, SQLite)
+	require.NoError(t, err)
+	assert.Equal(t, len(SQLite.Migrations()), applied)
+
+	store := NewEventStore(db, SQLite)
+
+	t.Run("sqlite event store validation", func(t *testing.T) {
+		suite := we.NewEventStoreValidationSuite(ctx, store)
+		suite.Run(t)
+	})
+
+	t.Run("migrations are only applied once", func(t *testing.T) {
+		applied, err := Migrate(ctx, db, SQLite)
+		require.NoError(t, err)
+		assert.Equal(t, 0, applied)
+	})
+
+	t.Run("revisions decode to the aggregate version", func(t *testing.T) {
+		id := we.AggregateId{Type: "test", Key: ulid.Make().String()}
+
+		revision, err := store.PublishRevision(ctx, id, we.Options(), Tested{"one"}, Tested{"two"})
+		require.NoError(t, err)
+
+		version, err := expectedVersion(revision)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), version)
+	})
+
+	t.Run("rejects expected revisions that don't encode a version", func(t *testing.T) {
+		id := we.AggregateId{Type: "test", Key: ulid.Make().String()}
+		require.NoError(t, store.Publish(ctx, id, we.Options(), Tested{"one"}))
+
+		// half the revisions a generator makes have a sequence too large for a version
+		generator := we.NewRevisionGenerator()
+		expected := generator.NewRevision(time.Now())
+		for sequence, _, _ := expected.Sequence(); sequence <= math.MaxInt64; sequence, _, _ = expected.Sequence() {
+			expected = generator.NewRevision(time.Now())
+		}
+
+		event := we.RecordedEvent{EventID: "imported", EventType: "test:tested", Timestamp: we.TimestampFromTime(time.Now()),
+			Data: we.Data{Encoding: "application/json", Data: []byte(`{}`)}}
+		_, err := store.Append(ctx, id, expected, []we.RecordedEvent{event})
+		assert.ErrorIs(t, err, we.InvalidRevision)
+
+		aggregate, err := store.Load(ctx, id)
+		require.NoError(t, err)
+		assert.Len(t, aggregate.Events, 1)
+	})
+
+	t.Run("concurrent publishes without an expected revision are serialised", func(t *testing.T) {
+		id := we.AggregateId{Type: "test", Key: ulid.Make().String()}
+		store := NewEventStore(db, SQLite, WithPublishAttempts(10))
+
+		var wg sync.WaitGroup
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, store.Publish(ctx, id, we.Options(), Tested{"concurrent"}))
+			}()
+		}
+		wg.Wait()
+
+		aggregate, err := store.Load(ctx, id)
+		require.NoError(t, err)
+		assert.Len(t, aggregate.Events, 5)
+	})
+}
+
+// initialSchema is a dialect limited to its first migration, as databases were migrated before later ones existed.
+type initialSchema struct {
+	Dialect
+}
+
+func (d initialSchema) Migrations() []Migration {
+	return d.Dialect.Migrations()[:1]
+}
+
+func TestMigratesExistingEvents(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+	id := we.AggregateId{Type: "test", Key: ulid.Make().String()}
+
+	_, err := Migrate(ctx, db, initialSchema{SQLite})
+	require.NoError(t, err)
+
+	insert := `INSERT INTO ` + eventsTable + ` (aggregate_type, aggregate_key, version, event_id, event_type, revision,
+		recorded_at, encoding, data, causation_id, correlation_id) VALUES (?, ?, 1, 'event', 'test:tested',
+		'00000000000000000000000001', '2023-03-12T01:02:03.456Z', 'application/json', ?, 'cause', 'correlation')`
+	_, err = db.ExecContext(ctx, insert, id.Type, id.Key, []byte(`{}`))
+	require.NoError(t, err)
+
+	applied, err := Migrate(ctx, db, SQLite)
+	require.NoError(t, err)
+	assert.Equal(t, len(SQLite.Migrations())-1, applied)
+
+	store := NewEventStore(db, SQLite)
+	loaded, err := store.Load(ctx, id)
+	require.NoError(t, err)
+	require.Len(t, loaded.Events, 1)
+	assert.Equal(t, we.RecordedEventMetadata{CausationId: "cause", CorrelationId: "correlation"}, loaded.Events[0].Metadata)
+
+	require.NoError(t, store.Publish(ctx, id, we.Options(we.WithActor("user/1"), we.WithExtension("example.com/key", "value")), Tested{"two"}))
+	loaded, err = store.Load(ctx, id)
+	require.NoError(t, err)
+	require.Len(t, loaded.Events, 2)
+	assert.Equal(t, we.Actor("user/1"), loaded.Events[1].Metadata.Actor)
+	assert.Equal(t, we.Extensions{"example.com/key": "value"}, loaded.Events[1].Metadata.Extensions)
+}
+
+func TestUniqueViolations(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+
+	_, err := Migrate(ctx, db, SQLite)
+	require.NoError(t, err)
+
+	insert := `INSERT INTO ` + eventsTable + ` (aggregate_type, aggregate_key, version, event_id, event_type, revision,
+		recorded_at, encoding, data) VALUES ('test', 'key', 1, 'id', 'type', 'rev', 'now', 'json', x'')`
+
+	_, err = db.ExecContext(ctx, insert)
+	require.NoError(t, err)
+
+	_, err = db.ExecContext(ctx, insert)
+	require.Error(t, err)
+	assert.True(t, SQLite.UniqueViolation(err))
+}
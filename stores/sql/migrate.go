@@ -0,0 +1,69 @@
+package sqls
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Migrate applies the dialect's migrations that haven't been recorded in the migrations table, returning the number
+// applied.
+func Migrate(ctx context.Context, db *sql.DB, dialect Dialect) (int, error) {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+migrationsTable+` (
+		version INTEGER NOT NULL PRIMARY KEY,
+		applied_at TEXT NOT NULL
+	)`)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to create migrations table")
+	}
+
+	applied := 0
+	for _, migration := range dialect.Migrations() {
+		ok, err := migrate(ctx, db, dialect, migration)
+		if err != nil {
+			return applied, errors.Wrapf(err, "failed to apply migration %d", migration.Version)
+		}
+
+		if ok {
+			applied++
+		}
+	}
+
+	return applied, nil
+}
+
+func migrate(ctx context.Context, db *sql.DB, dialect Dialect, migration Migration) (bool, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var count int
+	query := fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE version = %s`, migrationsTable, dialect.Placeholder(1))
+	if err := tx.QueryRowContext(ctx, query, migration.Version).Scan(&count); err != nil {
+		return false, err
+	}
+
+	if count > 0 {
+		return false, nil
+	}
+
+	for _, statement := range migration.Statements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return false, err
+		}
+	}
+
+	// a concurrent migration fails here, on the version's primary key, rolling back its statements
+	insert := fmt.Sprintf(`INSERT INTO %s (version, applied_at) VALUES (%s, %s)`,
+		migrationsTable, dialect.Placeholder(1), dialect.Placeholder(2))
+	if _, err := tx.ExecContext(ctx, insert, migration.Version, time.Now().UTC().Format(time.RFC3339)); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
@@ -0,0 +1,110 @@
+package sqls
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"testing"
+
+	_ "github.com/lib/pq"
+	"github.com/oklog/ulid/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+
+	"github.com/weegigs/wee-events-go/we"
+)
+
+// openPostgres starts a PostgreSQL container, which is terminated when the test completes.
+func openPostgres(t *testing.T) *sql.DB {
+	ctx := context.Background()
+
+	db, err := testcontainers.GenericContainer(
+		ctx, testcontainers.GenericContainerRequest{
+			ContainerRequest: testcontainers.ContainerRequest{
+				Image: "postgres:15-alpine",
+				Env: map[string]string{
+					"POSTGRES_USER":     "we",
+					"POSTGRES_PASSWORD": "we",
+					"POSTGRES_DB":       "events",
+				},
+				ExposedPorts: []string{"5432/tcp"},
+				// the server is restarted once the database is initialised
+				WaitingFor: wait.ForLog("database system is ready to accept connections").WithOccurrence(2),
+			},
+			Started: true,
+		},
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Terminate(ctx) })
+
+	host, err := db.Host(ctx)
+	require.NoError(t, err)
+
+	port, err := db.MappedPort(ctx, "5432")
+	require.NoError(t, err)
+
+	dsn := fmt.Sprintf("postgres://we:we@%s:%s/events?sslmode=disable", host, port.Port())
+	conn, err := sql.Open("postgres", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	require.NoError(t, conn.PingContext(ctx))
+
+	return conn
+}
+
+func TestPostgreSQLEventStore(t *testing.T) {
+	ctx := context.Background()
+	db := openPostgres(t)
+
+	applied, err := Migrate(ctx, db, PostgreSQL)
+	require.NoError(t, err)
+	assert.Equal(t, len(PostgreSQL.Migrations()), applied)
+
+	store := NewEventStore(db, PostgreSQL)
+
+	t.Run("postgres event store validation", func(t *testing.T) {
+		suite := we.NewEventStoreValidationSuite(ctx, store)
+		suite.Run(t)
+	})
+
+	t.Run("migrations are only applied once", func(t *testing.T) {
+		applied, err := Migrate(ctx, db, PostgreSQL)
+		require.NoError(t, err)
+		assert.Equal(t, 0, applied)
+	})
+
+	t.Run("concurrent publishes without an expected revision are serialised", func(t *testing.T) {
+		id := we.AggregateId{Type: "test", Key: ulid.Make().String()}
+		store := NewEventStore(db, PostgreSQL, WithPublishAttempts(10))
+
+		var wg sync.WaitGroup
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, store.Publish(ctx, id, we.Options(), Tested{"concurrent"}))
+			}()
+		}
+		wg.Wait()
+
+		aggregate, err := store.Load(ctx, id)
+		require.NoError(t, err)
+		assert.Len(t, aggregate.Events, 5)
+	})
+
+	t.Run("reports unique violations", func(t *testing.T) {
+		insert := `INSERT INTO ` + eventsTable + ` (aggregate_type, aggregate_key, version, event_id, event_type, revision,
+			recorded_at, encoding, data) VALUES ('test', 'key', 1, 'id', 'type', 'rev', 'now', 'json', '')`
+
+		_, err := db.ExecContext(ctx, insert)
+		require.NoError(t, err)
+
+		_, err = db.ExecContext(ctx, insert)
+		require.Error(t, err)
+		assert.True(t, PostgreSQL.UniqueViolation(err))
+	})
+}
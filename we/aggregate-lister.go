@@ -0,0 +1,27 @@
+package we
+
+import "context"
+
+// AggregateListHandler receives each listed aggregate with a checkpoint that resumes the listing after it.
+type AggregateListHandler func(ctx context.Context, id AggregateId, checkpoint Checkpoint) error
+
+// AggregateLister is implemented by stores that can enumerate the aggregates they hold. Aggregates are listed in a
+// stable order, starting after the checkpoint, so a listing can be resumed. Hard deleted aggregates may be listed.
+type AggregateLister interface {
+	ListAggregates(ctx context.Context, after Checkpoint, handler AggregateListHandler) error
+}
+
+// ChangeLister is implemented by listers that can report whether a listing after a checkpoint includes every
+// aggregate published to since the checkpoint was taken, as listers reading the store's log in order do. Listers that
+// resume a scan of the store's aggregates don't.
+type ChangeLister interface {
+	AggregateLister
+	ListsChanges() bool
+}
+
+// TypeLister is implemented by listers that can enumerate the aggregates of a single type without listing every
+// aggregate. Checkpoints resume the listing of the type they were taken from.
+type TypeLister interface {
+	AggregateLister
+	ListAggregatesOfType(ctx context.Context, aggregateType string, after Checkpoint, handler AggregateListHandler) error
+}
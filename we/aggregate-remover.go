@@ -0,0 +1,59 @@
+package we
+
+import (
+	"context"
+	"errors"
+)
+
+type RemovalMode int
+
+const (
+	// SoftDelete removes an aggregate's events. Publishing to the aggregate again recreates it.
+	SoftDelete RemovalMode = iota
+	// HardDelete removes an aggregate's events and prevents it being published to again. Loading or publishing to a
+	// hard deleted aggregate returns AggregateDeleted.
+	HardDelete
+	// TruncateBefore removes the events recorded before a revision, leaving the aggregate's revision unchanged. Stores
+	// that record events in change sets retain the whole change set containing the revision. Nothing is recorded
+	// before the InitialRevision, so truncating before it removes nothing.
+	TruncateBefore
+)
+
+func (m RemovalMode) String() string {
+	switch m {
+	case SoftDelete:
+		return "soft-delete"
+	case HardDelete:
+		return "hard-delete"
+	case TruncateBefore:
+		return "truncate-before"
+	default:
+		return "unknown"
+	}
+}
+
+type Removal struct {
+	Mode   RemovalMode
+	Before Revision
+}
+
+func SoftDeletion() Removal {
+	return Removal{Mode: SoftDelete}
+}
+
+func HardDeletion() Removal {
+	return Removal{Mode: HardDelete}
+}
+
+func Truncation(before Revision) Removal {
+	return Removal{Mode: TruncateBefore, Before: before}
+}
+
+// AggregateRemover is implemented by stores that can delete or truncate aggregates.
+type AggregateRemover interface {
+	RemoveAggregate(ctx context.Context, id AggregateId, removal Removal) error
+}
+
+var AggregateDeleted = errors.New("aggregate-deleted")
+
+var UnsupportedRemoval = errors.New("unsupported-removal")
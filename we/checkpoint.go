@@ -0,0 +1,49 @@
+package we
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Checkpoint is an opaque, store specific, token recording how far a consumer has read.
+type Checkpoint string
+
+const NoCheckpoint = Checkpoint("")
+
+func (c Checkpoint) String() string {
+	return string(c)
+}
+
+// CheckpointConflict is returned by stores that detect a checkpoint being saved concurrently by another consumer.
+var CheckpointConflict = errors.New("checkpoint-conflict")
+
+// CheckpointStore persists consumer checkpoints by name. LoadCheckpoint returns NoCheckpoint for unknown names.
+type CheckpointStore interface {
+	LoadCheckpoint(ctx context.Context, name string) (Checkpoint, error)
+	SaveCheckpoint(ctx context.Context, name string, checkpoint Checkpoint) error
+}
+
+func NewMemoryCheckpointStore() *MemoryCheckpointStore {
+	return &MemoryCheckpointStore{checkpoints: map[string]Checkpoint{}}
+}
+
+type MemoryCheckpointStore struct {
+	lk          sync.Mutex
+	checkpoints map[string]Checkpoint
+}
+
+func (s *MemoryCheckpointStore) LoadCheckpoint(_ context.Context, name string) (Checkpoint, error) {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	return s.checkpoints[name], nil
+}
+
+func (s *MemoryCheckpointStore) SaveCheckpoint(_ context.Context, name string, checkpoint Checkpoint) error {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	s.checkpoints[name] = checkpoint
+	return nil
+}
@@ -0,0 +1,74 @@
+package we
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the time events are recorded at. Stores, the RevisionGenerator and the validation suite take a clock
+// so tests can control the timestamps and revisions they record.
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc adapts a function to a Clock.
+type ClockFunc func() time.Time
+
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// SystemClock tells the time from the system clock.
+var SystemClock Clock = ClockFunc(time.Now)
+
+// FixedClock is a fake clock that tells the same time until it is set or advanced.
+type FixedClock struct {
+	lk  sync.Mutex
+	now time.Time
+}
+
+func NewFixedClock(now time.Time) *FixedClock {
+	return &FixedClock{now: now}
+}
+
+func (c *FixedClock) Now() time.Time {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+
+	return c.now
+}
+
+func (c *FixedClock) Set(now time.Time) {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+
+	c.now = now
+}
+
+func (c *FixedClock) Advance(d time.Duration) {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+// SteppingClock is a fake clock that moves on by its step each time it is read, starting from its start time.
+type SteppingClock struct {
+	lk   sync.Mutex
+	next time.Time
+	step time.Duration
+}
+
+func NewSteppingClock(start time.Time, step time.Duration) *SteppingClock {
+	return &SteppingClock{next: start, step: step}
+}
+
+func (c *SteppingClock) Now() time.Time {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+
+	now := c.next
+	c.next = c.next.Add(c.step)
+
+	return now
+}
@@ -0,0 +1,62 @@
+package we
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFakeClocks(t *testing.T) {
+	start := time.Date(2022, 2, 3, 4, 5, 6, 0, time.UTC)
+
+	t.Run("fixed clocks tell the same time until moved", func(t *testing.T) {
+		clock := NewFixedClock(start)
+		assert.Equal(t, start, clock.Now())
+		assert.Equal(t, start, clock.Now())
+
+		clock.Advance(time.Minute)
+		assert.Equal(t, start.Add(time.Minute), clock.Now())
+
+		clock.Set(start)
+		assert.Equal(t, start, clock.Now())
+	})
+
+	t.Run("stepping clocks move on each time they are read", func(t *testing.T) {
+		clock := NewSteppingClock(start, time.Second)
+		assert.Equal(t, start, clock.Now())
+		assert.Equal(t, start.Add(time.Second), clock.Now())
+		assert.Equal(t, start.Add(2*time.Second), clock.Now())
+	})
+}
+
+func TestSequentialIDGenerator(t *testing.T) {
+	start := time.Date(2022, 2, 3, 4, 5, 6, 0, time.UTC)
+
+	first := NewSequentialIDGenerator(NewFixedClock(start))
+	second := NewSequentialIDGenerator(NewFixedClock(start))
+
+	previous := EventID("")
+	for i := 0; i < 3; i++ {
+		id := first.Create()
+		assert.Equal(t, id, second.Create())
+		assert.Greater(t, id, previous)
+
+		parsed, err := ParseRevision(id.String())
+		if assert.Nil(t, err) {
+			recorded, _ := parsed.Time()
+			assert.Equal(t, start, recorded)
+		}
+
+		previous = id
+	}
+}
+
+func TestRevisionGeneratorClock(t *testing.T) {
+	start := time.Date(2022, 2, 3, 4, 5, 6, 0, time.UTC)
+
+	first := NewRevisionGenerator(WithRevisionClock(NewFixedClock(start)))
+	second := NewRevisionGenerator(WithRevisionClock(NewFixedClock(start)))
+
+	assert.Equal(t, first.NewRevision(start), second.NewRevision(start))
+}
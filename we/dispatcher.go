@@ -54,7 +54,7 @@ func (d *RoutedDispatcher[T]) Dispatch(ctx context.Context, entity Entity[T], co
 }
 
 func execute[T any](ctx context.Context, handler CommandHandler[T], command Command, state Entity[T], publish EventPublisher) (bool, error) {
-	tracking := &trackingPublisher{publish: publish}
+	tracking := &trackingPublisher{publish: ContextMetadataPublisher(publish)}
 
 	switch cmd := command.(type) {
 	case RemoteCommand:
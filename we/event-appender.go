@@ -0,0 +1,10 @@
+package we
+
+import "context"
+
+// EventAppender is implemented by stores that can import events recorded elsewhere. Events are written with their
+// ids, types, timestamps, metadata and data unchanged, while revisions are assigned by the store. The append is
+// conditional on the expected revision in the same way as publishing.
+type EventAppender interface {
+	Append(ctx context.Context, id AggregateId, expected Revision, events []RecordedEvent) (Revision, error)
+}
@@ -0,0 +1,6 @@
+package we
+
+import "context"
+
+// EventHandler receives recorded events from a subscription or stream reader.
+type EventHandler func(ctx context.Context, event RecordedEvent) error
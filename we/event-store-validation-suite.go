@@ -6,6 +6,7 @@ import (
 	"math/rand"
 	"strings"
 	"testing"
+	"testing/quick"
 	"time"
 
 	"github.com/jaswdr/faker"
@@ -16,19 +17,48 @@ import (
 
 var entropy = ulid.Monotonic(rand.New(rand.NewSource(time.Now().UnixNano())), 0)
 
-func NewEventStoreValidationSuite(ctx context.Context, store EventStore) *EventStoreValidationSuite {
+type ValidationSuiteOption func(*EventStoreValidationSuite)
+
+// WithSuiteClock sets the clock the suite makes aggregate ids and recorded events with.
+func WithSuiteClock(clock Clock) ValidationSuiteOption {
+	return func(s *EventStoreValidationSuite) {
+		s.clock = clock
+	}
+}
+
+// WithSuiteIdGenerator sets the generator of the ids of the recorded events the suite appends.
+func WithSuiteIdGenerator(generator IDGenerator) ValidationSuiteOption {
+	return func(s *EventStoreValidationSuite) {
+		s.ids = generator
+	}
+}
+
+func NewEventStoreValidationSuite(ctx context.Context, store EventStore, options ...ValidationSuiteOption) *EventStoreValidationSuite {
 	f := faker.New()
-	return &EventStoreValidationSuite{
+	s := &EventStoreValidationSuite{
 		store: store,
 		ctx:   ctx,
 		faker: f,
+		clock: SystemClock,
+	}
+
+	for _, option := range options {
+		option(s)
+	}
+
+	if s.ids == nil {
+		s.ids = NewULIDGenerator(s.clock)
 	}
+
+	return s
 }
 
 type EventStoreValidationSuite struct {
 	store EventStore
 	ctx   context.Context
 	faker faker.Faker
+	clock Clock
+	ids   IDGenerator
 }
 
 type StoreValidationEvent struct {
@@ -46,13 +76,26 @@ func (s *EventStoreValidationSuite) Run(t *testing.T) {
 	t.Run("published with an expected revision", s.PublishesWithAnExpectedRevision)
 	t.Run("returns a revision conflict with an initial revision", s.RevisionConflictOnInitialRevision)
 	t.Run("returns a revision conflict on subsequent revision", s.RevisionConflictOnSubsequentRevision)
+	t.Run("publishes many events with expected revisions", s.PublishesManyEventsWithExpectedRevisions)
 	t.Run("supports causation id", s.Causation)
+	t.Run("records actor, trace parent and extensions", s.ExtendedMetadata)
+	t.Run("reports the published revision", s.PublishesRevision)
+	t.Run("soft deletes aggregates", s.SoftDeletes)
+	t.Run("hard deletes aggregates", s.HardDeletes)
+	t.Run("soft delete after hard delete", s.SoftDeletesAfterHardDelete)
+	t.Run("truncates aggregates before a revision", s.TruncatesBefore)
+	t.Run("truncates nothing before the initial revision", s.TruncatesBeforeInitialRevision)
+	t.Run("appends recorded events verbatim", s.AppendsRecordedEvents)
+	t.Run("returns a revision conflict when appending", s.AppendRevisionConflict)
+	t.Run("appends after published events", s.AppendsAfterPublishedEvents)
+	t.Run("positions events across aggregates", s.PositionsEvents)
+	t.Run("records increasing revisions", s.RecordsIncreasingRevisions)
 }
 
 func (s *EventStoreValidationSuite) MakeTestAggregateId() AggregateId {
 	return AggregateId{
 		Type: "go-test",
-		Key:  ulid.MustNew(ulid.Timestamp(time.Now()), entropy).String(),
+		Key:  ulid.MustNew(ulid.Timestamp(s.clock.Now()), entropy).String(),
 	}
 }
 
@@ -269,6 +312,59 @@ func (s *EventStoreValidationSuite) Causation(t *testing.T) {
 	assert.Equal(t, first.EventID, second.Metadata.CausationId)
 }
 
+// MakeMetadata creates metadata with every field set.
+func (s *EventStoreValidationSuite) MakeMetadata() RecordedEventMetadata {
+	return RecordedEventMetadata{
+		CorrelationId: CorrelationID("correlation/" + s.faker.UUID().V4()),
+		CausationId:   EventID(s.faker.UUID().V4()),
+		Actor:         Actor("user/" + s.faker.Internet().User()),
+		TraceParent:   TraceParent("00-" + strings.ReplaceAll(s.faker.UUID().V4(), "-", "") + "-00f067aa0ba902b7-01"),
+		Extensions: Extensions{
+			RemoteAddressExtension: s.faker.Internet().Ipv4(),
+			"example.com/quoted":   `"` + s.faker.Lorem().Word() + `"`,
+		},
+	}
+}
+
+func (s *EventStoreValidationSuite) ExtendedMetadata(t *testing.T) {
+	aggregateId := s.MakeTestAggregateId()
+	metadata := s.MakeMetadata()
+
+	options := Options(
+		WithCausationId(metadata.CorrelationId, metadata.CausationId),
+		WithActor(metadata.Actor),
+		WithTraceParent(metadata.TraceParent),
+	)
+	for key, value := range metadata.Extensions {
+		WithExtension(key, value)(&options)
+	}
+
+	err := s.store.Publish(s.ctx, aggregateId, options, s.MakeTestEvents(2)...)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	loaded, err := s.LoadAggregate(aggregateId)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	for _, event := range loaded.Events {
+		assert.Equal(t, metadata, event.Metadata)
+	}
+
+	err = s.store.Publish(s.ctx, aggregateId, Options(), s.MakeTestEvent())
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	last, err := s.Last(aggregateId)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, RecordedEventMetadata{}, last.Metadata)
+}
+
 func (s *EventStoreValidationSuite) PublishesWithAnExpectedRevision(t *testing.T) {
 	aggregateId := s.MakeTestAggregateId()
 	event := s.MakeTestEvent()
@@ -289,3 +385,442 @@ func (s *EventStoreValidationSuite) PublishesWithAnExpectedRevision(t *testing.T
 	err = s.ExpectEventCount(t, aggregateId, 2)
 	assert.Nil(t, err)
 }
+
+func (s *EventStoreValidationSuite) PublishesRevision(t *testing.T) {
+	publisher, ok := s.store.(RevisionPublisher)
+	if !ok {
+		t.Skip("store does not report published revisions")
+	}
+
+	aggregateId := s.MakeTestAggregateId()
+
+	first, err := publisher.PublishRevision(s.ctx, aggregateId, Options(), s.MakeTestEvents(3)...)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	loaded, err := s.store.Load(s.ctx, aggregateId)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, loaded.Revision, first)
+
+	second, err := publisher.PublishRevision(s.ctx, aggregateId, Options(WithExpectedRevision(first)), s.MakeTestEvent())
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	loaded, err = s.store.Load(s.ctx, aggregateId)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, loaded.Revision, second)
+	assert.NotEqual(t, first, second)
+}
+
+// PublishesManyEventsWithExpectedRevisions checks that revisions survive the round trip through the store once an
+// aggregate has more events than a single digit revision can represent, and that revisions increase.
+func (s *EventStoreValidationSuite) PublishesManyEventsWithExpectedRevisions(t *testing.T) {
+	aggregateId := s.MakeTestAggregateId()
+
+	err := s.store.Publish(s.ctx, aggregateId, Options(WithExpectedRevision(InitialRevision)), s.MakeTestEvents(9)...)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	var revisions []Revision
+	for i := 0; i < 12; i++ {
+		aggregate, err := s.store.Load(s.ctx, aggregateId)
+		if !assert.Nil(t, err) {
+			return
+		}
+		revisions = append(revisions, aggregate.Revision)
+
+		err = s.store.Publish(s.ctx, aggregateId, Options(WithExpectedRevision(aggregate.Revision)), s.MakeTestEvent())
+		if !assert.Nil(t, err, "publishing event %d", i+10) {
+			return
+		}
+	}
+
+	for i := 1; i < len(revisions); i++ {
+		assert.Less(t, revisions[i-1].String(), revisions[i].String())
+	}
+
+	aggregate, err := s.store.Load(s.ctx, aggregateId)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, 21, len(aggregate.Events))
+
+	for i := 1; i < len(aggregate.Events); i++ {
+		assert.Less(t, aggregate.Events[i-1].Revision.String(), aggregate.Events[i].Revision.String())
+	}
+
+	err = s.store.Publish(s.ctx, aggregateId, Options(WithExpectedRevision(revisions[len(revisions)-1])), s.MakeTestEvent())
+	assert.Equal(t, RevisionConflict, err)
+}
+
+func (s *EventStoreValidationSuite) remover(t *testing.T) AggregateRemover {
+	remover, ok := s.store.(AggregateRemover)
+	if !ok {
+		t.Skip("store does not remove aggregates")
+	}
+
+	return remover
+}
+
+func (s *EventStoreValidationSuite) SoftDeletes(t *testing.T) {
+	remover := s.remover(t)
+	aggregateId := s.MakeTestAggregateId()
+
+	err := s.store.Publish(s.ctx, aggregateId, Options(), s.MakeTestEvents(3)...)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	err = remover.RemoveAggregate(s.ctx, aggregateId, SoftDeletion())
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	loaded, err := s.store.Load(s.ctx, aggregateId)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Empty(t, loaded.Events)
+
+	err = s.store.Publish(s.ctx, aggregateId, Options(), s.MakeTestEvent())
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	err = s.ExpectEventCount(t, aggregateId, 1)
+	assert.Nil(t, err)
+}
+
+func (s *EventStoreValidationSuite) HardDeletes(t *testing.T) {
+	remover := s.remover(t)
+	aggregateId := s.MakeTestAggregateId()
+
+	err := s.store.Publish(s.ctx, aggregateId, Options(), s.MakeTestEvents(3)...)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	before, err := s.store.Load(s.ctx, aggregateId)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	err = remover.RemoveAggregate(s.ctx, aggregateId, HardDeletion())
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	_, err = s.store.Load(s.ctx, aggregateId)
+	assert.ErrorIs(t, err, AggregateDeleted)
+
+	err = s.store.Publish(s.ctx, aggregateId, Options(), s.MakeTestEvent())
+	assert.ErrorIs(t, err, AggregateDeleted)
+
+	err = s.store.Publish(s.ctx, aggregateId, Options(WithExpectedRevision(before.Revision)), s.MakeTestEvent())
+	assert.ErrorIs(t, err, AggregateDeleted)
+
+	err = s.store.Publish(s.ctx, aggregateId, Options(WithExpectedRevision(InitialRevision)), s.MakeTestEvent())
+	assert.ErrorIs(t, err, AggregateDeleted)
+}
+
+func (s *EventStoreValidationSuite) SoftDeletesAfterHardDelete(t *testing.T) {
+	remover := s.remover(t)
+	aggregateId := s.MakeTestAggregateId()
+
+	err := s.store.Publish(s.ctx, aggregateId, Options(), s.MakeTestEvents(2)...)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	err = remover.RemoveAggregate(s.ctx, aggregateId, HardDeletion())
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	err = remover.RemoveAggregate(s.ctx, aggregateId, SoftDeletion())
+	assert.ErrorIs(t, err, AggregateDeleted)
+
+	_, err = s.store.Load(s.ctx, aggregateId)
+	assert.ErrorIs(t, err, AggregateDeleted)
+
+	err = s.store.Publish(s.ctx, aggregateId, Options(), s.MakeTestEvent())
+	assert.ErrorIs(t, err, AggregateDeleted)
+}
+
+func (s *EventStoreValidationSuite) TruncatesBefore(t *testing.T) {
+	remover := s.remover(t)
+	aggregateId := s.MakeTestAggregateId()
+
+	// events are published individually so truncation isn't affected by change set boundaries
+	for i := 0; i < 5; i++ {
+		err := s.store.Publish(s.ctx, aggregateId, Options(), s.MakeTestEvent())
+		if !assert.Nil(t, err) {
+			return
+		}
+	}
+
+	before, err := s.store.Load(s.ctx, aggregateId)
+	if !assert.Nil(t, err) {
+		return
+	}
+	require.Equal(t, 5, len(before.Events))
+
+	err = remover.RemoveAggregate(s.ctx, aggregateId, Truncation(before.Events[2].Revision))
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	after, err := s.store.Load(s.ctx, aggregateId)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, before.Events[2:], after.Events)
+	assert.Equal(t, before.Revision, after.Revision)
+
+	err = s.store.Publish(s.ctx, aggregateId, Options(WithExpectedRevision(after.Revision)), s.MakeTestEvent())
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	err = s.ExpectEventCount(t, aggregateId, 4)
+	assert.Nil(t, err)
+}
+
+func (s *EventStoreValidationSuite) TruncatesBeforeInitialRevision(t *testing.T) {
+	remover := s.remover(t)
+	aggregateId := s.MakeTestAggregateId()
+
+	for i := 0; i < 3; i++ {
+		err := s.store.Publish(s.ctx, aggregateId, Options(), s.MakeTestEvent())
+		if !assert.Nil(t, err) {
+			return
+		}
+	}
+
+	before, err := s.store.Load(s.ctx, aggregateId)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	err = remover.RemoveAggregate(s.ctx, aggregateId, Truncation(InitialRevision))
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	after, err := s.store.Load(s.ctx, aggregateId)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, before.Events, after.Events)
+	assert.Equal(t, before.Revision, after.Revision)
+}
+
+func (s *EventStoreValidationSuite) appender(t *testing.T) EventAppender {
+	appender, ok := s.store.(EventAppender)
+	if !ok {
+		t.Skip("store does not append recorded events")
+	}
+
+	return appender
+}
+
+// MakeRecordedEvents creates events as they would have been recorded by another store, a day apart and a year ago.
+func (s *EventStoreValidationSuite) MakeRecordedEvents(t *testing.T, id AggregateId, count int) []RecordedEvent {
+	recordedAt := s.clock.Now().AddDate(-1, 0, 0)
+
+	events := make([]RecordedEvent, count)
+	for i := range events {
+		data, err := MarshalToData(s.MakeTestEvent())
+		require.Nil(t, err)
+
+		at := recordedAt.AddDate(0, 0, i)
+		events[i] = RecordedEvent{
+			AggregateId: id,
+			EventID:     s.ids.Create(),
+			EventType:   EventTypeOf(StoreValidationEvent{}),
+			Timestamp:   TimestampFromTime(at),
+			Metadata:    s.MakeMetadata(),
+			Data:        data,
+		}
+	}
+
+	return events
+}
+
+func (s *EventStoreValidationSuite) AppendsRecordedEvents(t *testing.T) {
+	appender := s.appender(t)
+	aggregateId := s.MakeTestAggregateId()
+	events := s.MakeRecordedEvents(t, aggregateId, 3)
+
+	revision, err := appender.Append(s.ctx, aggregateId, InitialRevision, events)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	loaded, err := s.LoadAggregate(aggregateId)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	assert.Equal(t, revision, loaded.Revision)
+	require.Equal(t, len(events), len(loaded.Events))
+	for i, event := range events {
+		recorded := loaded.Events[i]
+		assert.Equal(t, aggregateId, recorded.AggregateId)
+		assert.Equal(t, event.EventID, recorded.EventID)
+		assert.Equal(t, event.EventType, recorded.EventType)
+		assert.Equal(t, event.Timestamp, recorded.Timestamp)
+		assert.Equal(t, event.Metadata, recorded.Metadata)
+		assert.Equal(t, event.Data.Encoding, recorded.Data.Encoding)
+		assert.JSONEq(t, string(event.Data.Data), string(recorded.Data.Data))
+	}
+}
+
+func (s *EventStoreValidationSuite) AppendRevisionConflict(t *testing.T) {
+	appender := s.appender(t)
+	aggregateId := s.MakeTestAggregateId()
+
+	_, err := appender.Append(s.ctx, aggregateId, InitialRevision, s.MakeRecordedEvents(t, aggregateId, 1))
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	_, err = appender.Append(s.ctx, aggregateId, InitialRevision, s.MakeRecordedEvents(t, aggregateId, 1))
+	assert.ErrorIs(t, err, RevisionConflict)
+}
+
+func (s *EventStoreValidationSuite) AppendsAfterPublishedEvents(t *testing.T) {
+	appender := s.appender(t)
+	aggregateId := s.MakeTestAggregateId()
+
+	err := s.store.Publish(s.ctx, aggregateId, Options(), s.MakeTestEvents(2)...)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	published, err := s.LoadAggregate(aggregateId)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	revision, err := appender.Append(s.ctx, aggregateId, published.Revision, s.MakeRecordedEvents(t, aggregateId, 2))
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	err = s.store.Publish(s.ctx, aggregateId, Options(WithExpectedRevision(revision)), s.MakeTestEvent())
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	err = s.ExpectEventCount(t, aggregateId, 5)
+	assert.Nil(t, err)
+}
+
+// PositionsEvents checks that events are positioned in the order they were published across aggregates, for stores
+// that position events.
+func (s *EventStoreValidationSuite) PositionsEvents(t *testing.T) {
+	first := s.MakeTestAggregateId()
+	second := s.MakeTestAggregateId()
+
+	for _, id := range []AggregateId{first, second, first} {
+		err := s.store.Publish(s.ctx, id, Options(), s.MakeTestEvents(2)...)
+		if !assert.Nil(t, err) {
+			return
+		}
+	}
+
+	loadedFirst, err := s.LoadAggregate(first)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	loadedSecond, err := s.LoadAggregate(second)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	if loadedFirst.Events[0].Position == NoPosition {
+		t.Skip("store doesn't position events")
+	}
+
+	var positions []Position
+	for _, event := range append(append(loadedFirst.Events[:2:2], loadedSecond.Events...), loadedFirst.Events[2:]...) {
+		positions = append(positions, event.Position)
+	}
+
+	for i := 1; i < len(positions); i++ {
+		assert.True(t, positions[i].After(positions[i-1]), "%s is after %s", positions[i], positions[i-1])
+	}
+}
+
+// revisionStep is a write made by RecordsIncreasingRevisions, publishing or importing up to three events, with or
+// without the aggregate's revision as the expected one.
+type revisionStep struct {
+	Import   bool
+	Expected bool
+	Count    uint8
+}
+
+// RecordsIncreasingRevisions checks, for random sequences of publishes and imports of historical events, that every
+// revision the store reports is valid and after the revision before it.
+func (s *EventStoreValidationSuite) RecordsIncreasingRevisions(t *testing.T) {
+	_, appends := s.store.(EventAppender)
+
+	property := func(steps []revisionStep) bool {
+		aggregateId := s.MakeTestAggregateId()
+
+		revision := InitialRevision
+		for _, step := range steps {
+			count := int(step.Count%3) + 1
+
+			var expected Revision
+			if step.Expected {
+				expected = revision
+			}
+
+			var err error
+			if step.Import && appends {
+				revision, err = s.store.(EventAppender).Append(s.ctx, aggregateId, expected, s.MakeRecordedEvents(t, aggregateId, count))
+			} else {
+				err = s.store.Publish(s.ctx, aggregateId, Options(WithExpectedRevision(expected)), s.MakeTestEvents(count)...)
+				if err == nil {
+					var loaded Aggregate
+					loaded, err = s.LoadAggregate(aggregateId)
+					revision = loaded.Revision
+				}
+			}
+			if !assert.Nil(t, err) {
+				return false
+			}
+		}
+
+		loaded, err := s.LoadAggregate(aggregateId)
+		if !assert.Nil(t, err) {
+			return false
+		}
+
+		previous := InitialRevision
+		for _, event := range loaded.Events {
+			if _, err := ParseRevision(event.Revision.String()); !assert.Nil(t, err) {
+				return false
+			}
+			if !assert.True(t, event.Revision.After(previous), "%s is after %s", event.Revision, previous) {
+				return false
+			}
+			previous = event.Revision
+		}
+
+		return assert.Equal(t, revision, loaded.Revision)
+	}
+
+	err := quick.Check(property, &quick.Config{MaxCount: 10})
+	assert.Nil(t, err)
+}
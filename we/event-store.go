@@ -13,6 +13,31 @@ type EventStore interface {
 	Publish(ctx context.Context, aggregateId AggregateId, options PublishOptions, events ...DomainEvent) error
 }
 
+// RevisionPublisher is implemented by stores that can report the revision resulting from a publish without the
+// aggregate being reloaded.
+type RevisionPublisher interface {
+	PublishRevision(ctx context.Context, aggregateId AggregateId, options PublishOptions, events ...DomainEvent) (Revision, error)
+}
+
+// PublishRevision publishes the events and returns the aggregate's new revision, reloading the aggregate when the
+// store is not a RevisionPublisher.
+func PublishRevision(ctx context.Context, store EventStore, aggregateId AggregateId, options PublishOptions, events ...DomainEvent) (Revision, error) {
+	if publisher, ok := store.(RevisionPublisher); ok {
+		return publisher.PublishRevision(ctx, aggregateId, options, events...)
+	}
+
+	if err := store.Publish(ctx, aggregateId, options, events...); err != nil {
+		return "", err
+	}
+
+	aggregate, err := store.Load(ctx, aggregateId)
+	if err != nil {
+		return "", err
+	}
+
+	return aggregate.Revision, nil
+}
+
 func Loader(store EventStore) EventLoader {
 	return store.Load
 }
@@ -26,6 +51,7 @@ var RevisionConflict = errors.New("revision-conflict")
 type PublishOptions struct {
 	RecordedEventMetadata
 	ExpectedRevision Revision
+	CommandId        CommandID
 	Encrypt          bool
 }
 
@@ -59,6 +85,33 @@ func WithCausationId(correlationId CorrelationID, causationId EventID) PublishOp
 	}
 }
 
+// WithCommandId identifies the command being published, stores that support deduplication use it to discard
+// retried publishes.
+func WithCommandId(commandId CommandID) PublishOption {
+	return func(modifier *PublishOptions) {
+		modifier.CommandId = commandId
+	}
+}
+
+func WithActor(actor Actor) PublishOption {
+	return func(modifier *PublishOptions) {
+		modifier.RecordedEventMetadata.Actor = actor
+	}
+}
+
+func WithTraceParent(traceParent TraceParent) PublishOption {
+	return func(modifier *PublishOptions) {
+		modifier.RecordedEventMetadata.TraceParent = traceParent
+	}
+}
+
+// WithExtension records the extension in the metadata of the published events.
+func WithExtension(key ExtensionKey, value string) PublishOption {
+	return func(modifier *PublishOptions) {
+		modifier.RecordedEventMetadata.Extensions = modifier.RecordedEventMetadata.Extensions.merge(Extensions{key: value})
+	}
+}
+
 func WithEncryption() PublishOption {
 	return func(modifier *PublishOptions) {
 		modifier.Encrypt = true
@@ -24,6 +24,13 @@ func (id CorrelationID) String() string {
 	return string(id)
 }
 
+// CommandID identifies the command that caused a publish, allowing stores to recognise retries of it.
+type CommandID string
+
+func (id CommandID) String() string {
+	return string(id)
+}
+
 type Payload any
 type Data struct {
 	Encoding string          `json:"encoding"`
@@ -67,6 +74,9 @@ func EventTypeOf(event DomainEvent) EventType {
 type RecordedEventMetadata struct {
 	CausationId   EventID       `json:"causationId,omitempty"`
 	CorrelationId CorrelationID `json:"correlationId,omitempty"`
+	Actor         Actor         `json:"actor,omitempty"`
+	TraceParent   TraceParent   `json:"traceParent,omitempty"`
+	Extensions    Extensions    `json:"extensions,omitempty"`
 }
 
 type RecordedEvent struct {
@@ -77,4 +87,6 @@ type RecordedEvent struct {
 	Timestamp   Timestamp             `json:"timestamp"`
 	Metadata    RecordedEventMetadata `json:"metadata"`
 	Data        Data                  `json:"data"`
+	// Position orders the event among every event in the store, for stores with a global order
+	Position Position `json:"position,omitempty"`
 }
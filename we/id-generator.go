@@ -0,0 +1,60 @@
+package we
+
+import (
+	"encoding/binary"
+	"sync"
+
+	"github.com/oklog/ulid/v2"
+)
+
+// IDGenerator creates the ids of published events.
+type IDGenerator interface {
+	Create() EventID
+}
+
+// IDGeneratorFunc adapts a function to an IDGenerator.
+type IDGeneratorFunc func() EventID
+
+func (f IDGeneratorFunc) Create() EventID {
+	return f()
+}
+
+// ULIDGenerator creates ULID event ids at the time told by its clock, increasing within a millisecond.
+type ULIDGenerator struct {
+	clock Clock
+}
+
+func NewULIDGenerator(clock Clock) *ULIDGenerator {
+	return &ULIDGenerator{clock: clock}
+}
+
+func (g *ULIDGenerator) Create() EventID {
+	return EventID(ulid.MustNew(ulid.Timestamp(g.clock.Now()), ulid.DefaultEntropy()).String())
+}
+
+// SequentialIDGenerator is a fake generator of ULID event ids at the time told by its clock, with entropy counting up
+// from one, so the ids made for a test are the same each time it runs.
+type SequentialIDGenerator struct {
+	lk    sync.Mutex
+	clock Clock
+	count uint64
+}
+
+func NewSequentialIDGenerator(clock Clock) *SequentialIDGenerator {
+	return &SequentialIDGenerator{clock: clock}
+}
+
+func (g *SequentialIDGenerator) Create() EventID {
+	g.lk.Lock()
+	defer g.lk.Unlock()
+
+	g.count++
+	entropy := make([]byte, 10)
+	binary.BigEndian.PutUint64(entropy[2:], g.count)
+
+	var id ulid.ULID
+	_ = id.SetTime(ulid.Timestamp(g.clock.Now()))
+	_ = id.SetEntropy(entropy)
+
+	return EventID(id.String())
+}
@@ -0,0 +1,117 @@
+package we
+
+import (
+	"context"
+	"sort"
+
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// Actor identifies who, or what, issued the command that caused an event.
+type Actor string
+
+func (a Actor) String() string {
+	return string(a)
+}
+
+// TraceParent is a W3C trace context traceparent, linking an event to the trace of the operation that recorded it.
+type TraceParent string
+
+func (tp TraceParent) String() string {
+	return string(tp)
+}
+
+// ExtensionKey names an extension to an event's metadata. Applications should qualify their keys to avoid clashes,
+// the unqualified keys are reserved.
+type ExtensionKey string
+
+func (key ExtensionKey) String() string {
+	return string(key)
+}
+
+// RemoteAddressExtension records the network address the command that caused an event was received from.
+const RemoteAddressExtension = ExtensionKey("remote-address")
+
+// Extensions holds metadata beyond that recorded for every event.
+type Extensions map[ExtensionKey]string
+
+// Keys returns the extension keys in order.
+func (e Extensions) Keys() []ExtensionKey {
+	keys := make([]ExtensionKey, 0, len(e))
+	for key := range e {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	return keys
+}
+
+// merge returns the extensions with those of the other added, the other's values taking precedence. Neither is
+// modified.
+func (e Extensions) merge(other Extensions) Extensions {
+	if len(other) == 0 {
+		return e
+	}
+	if len(e) == 0 {
+		return other
+	}
+
+	merged := make(Extensions, len(e)+len(other))
+	for key, value := range e {
+		merged[key] = value
+	}
+	for key, value := range other {
+		merged[key] = value
+	}
+
+	return merged
+}
+
+// Merge returns the metadata with the values set in the other taking precedence.
+func (m RecordedEventMetadata) Merge(other RecordedEventMetadata) RecordedEventMetadata {
+	if other.CausationId != "" {
+		m.CausationId = other.CausationId
+	}
+	if other.CorrelationId != "" {
+		m.CorrelationId = other.CorrelationId
+	}
+	if other.Actor != "" {
+		m.Actor = other.Actor
+	}
+	if other.TraceParent != "" {
+		m.TraceParent = other.TraceParent
+	}
+	m.Extensions = m.Extensions.merge(other.Extensions)
+
+	return m
+}
+
+type metadataKey struct{}
+
+// WithMetadata adds the metadata to that carried by the context, for recording with the events published in it.
+func WithMetadata(ctx context.Context, metadata RecordedEventMetadata) context.Context {
+	existing, _ := ctx.Value(metadataKey{}).(RecordedEventMetadata)
+	return context.WithValue(ctx, metadataKey{}, existing.Merge(metadata))
+}
+
+// MetadataFrom returns the metadata carried by the context. Without a trace parent of its own, the trace parent is
+// that of the context's span, if it is recording one.
+func MetadataFrom(ctx context.Context) RecordedEventMetadata {
+	metadata, _ := ctx.Value(metadataKey{}).(RecordedEventMetadata)
+	if metadata.TraceParent == "" {
+		carrier := propagation.MapCarrier{}
+		propagation.TraceContext{}.Inject(ctx, carrier)
+		metadata.TraceParent = TraceParent(carrier.Get("traceparent"))
+	}
+
+	return metadata
+}
+
+// ContextMetadataPublisher is publisher middleware recording the metadata carried by the context with the events,
+// where the publish options don't set it. Commands dispatched by an EntityService are published through it.
+func ContextMetadataPublisher(publish EventPublisher) EventPublisher {
+	return func(ctx context.Context, aggregateId AggregateId, options PublishOptions, events ...DomainEvent) error {
+		options.RecordedEventMetadata = MetadataFrom(ctx).Merge(options.RecordedEventMetadata)
+		return publish(ctx, aggregateId, options, events...)
+	}
+}
@@ -0,0 +1,74 @@
+package we
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestMetadata(t *testing.T) {
+	t.Run("merges with the other's values taking precedence", func(t *testing.T) {
+		base := RecordedEventMetadata{
+			CorrelationId: "correlation",
+			Actor:         "user/1",
+			Extensions:    Extensions{RemoteAddressExtension: "192.0.2.1:4321", "example.com/kept": "kept"},
+		}
+		other := RecordedEventMetadata{
+			Actor:      "user/2",
+			Extensions: Extensions{RemoteAddressExtension: "192.0.2.2:4321"},
+		}
+
+		assert.Equal(t, RecordedEventMetadata{
+			CorrelationId: "correlation",
+			Actor:         "user/2",
+			Extensions:    Extensions{RemoteAddressExtension: "192.0.2.2:4321", "example.com/kept": "kept"},
+		}, base.Merge(other))
+		assert.Equal(t, Extensions{RemoteAddressExtension: "192.0.2.1:4321", "example.com/kept": "kept"}, base.Extensions)
+	})
+
+	t.Run("accumulates in the context", func(t *testing.T) {
+		ctx := WithMetadata(context.Background(), RecordedEventMetadata{Actor: "user/1"})
+		ctx = WithMetadata(ctx, RecordedEventMetadata{TraceParent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"})
+
+		assert.Equal(t, RecordedEventMetadata{
+			Actor:       "user/1",
+			TraceParent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		}, MetadataFrom(ctx))
+	})
+
+	t.Run("takes the trace parent from the context's span", func(t *testing.T) {
+		ctx, span := trace.NewTracerProvider().Tracer("test").Start(context.Background(), "test")
+		defer span.End()
+
+		sc := span.SpanContext()
+		expected := TraceParent(fmt.Sprintf("00-%s-%s-%s", sc.TraceID(), sc.SpanID(), sc.TraceFlags()))
+		assert.Equal(t, expected, MetadataFrom(ctx).TraceParent)
+
+		assert.Equal(t, TraceParent(""), MetadataFrom(context.Background()).TraceParent)
+	})
+
+	t.Run("publishes the context's metadata unless the options set it", func(t *testing.T) {
+		var published PublishOptions
+		publish := ContextMetadataPublisher(func(ctx context.Context, aggregateId AggregateId, options PublishOptions, events ...DomainEvent) error {
+			published = options
+			return nil
+		})
+
+		ctx := WithMetadata(context.Background(), RecordedEventMetadata{
+			Actor:      "user/1",
+			Extensions: Extensions{RemoteAddressExtension: "192.0.2.1:4321"},
+		})
+		options := Options(WithActor("system"), WithExtension("example.com/key", "value"), WithCorrelationId("correlation"))
+
+		require.NoError(t, publish(ctx, AggregateId{Type: "test", Key: "metadata"}, options))
+		assert.Equal(t, RecordedEventMetadata{
+			CorrelationId: "correlation",
+			Actor:         "system",
+			Extensions:    Extensions{RemoteAddressExtension: "192.0.2.1:4321", "example.com/key": "value"},
+		}, published.RecordedEventMetadata)
+	})
+}
@@ -0,0 +1,76 @@
+package we
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Position is the store assigned place of an event in the order of every event in the store, allowing consumers to
+// order and checkpoint events across aggregates. Positions are only comparable between events of the same store and
+// are encoded so that they order lexically. Stores without a global order leave it empty.
+type Position string
+
+const NoPosition = Position("")
+
+var InvalidPosition = errors.New("invalid-position")
+
+// SequencePosition is the position of the index'th event recorded in a store sequence number, such as a JetStream
+// stream sequence or the position of an event in the file store's log.
+func SequencePosition(sequence uint64, index uint16) Position {
+	return Position(fmt.Sprintf("%020d.%05d", sequence, index))
+}
+
+// CommitPosition is the position of an event in an EventStoreDB transaction log.
+func CommitPosition(commit uint64, prepare uint64) Position {
+	return Position(fmt.Sprintf("%020d/%020d", commit, prepare))
+}
+
+func (p Position) String() string {
+	return string(p)
+}
+
+// Compare returns -1, 0 or 1 as the position is before, the same as or after the other. NoPosition is before every
+// other position.
+func (p Position) Compare(other Position) int {
+	return strings.Compare(string(p), string(other))
+}
+
+func (p Position) Before(other Position) bool {
+	return p.Compare(other) < 0
+}
+
+func (p Position) After(other Position) bool {
+	return p.Compare(other) > 0
+}
+
+// Sequence decodes a sequence position.
+func (p Position) Sequence() (uint64, uint16, error) {
+	var sequence uint64
+	var index uint16
+	if _, err := fmt.Sscanf(string(p), "%d.%d", &sequence, &index); err != nil || SequencePosition(sequence, index) != p {
+		return 0, 0, fmt.Errorf("%w: %q isn't a sequence position", InvalidPosition, p)
+	}
+
+	return sequence, index, nil
+}
+
+// Commit decodes a commit position.
+func (p Position) Commit() (uint64, uint64, error) {
+	var commit, prepare uint64
+	if _, err := fmt.Sscanf(string(p), "%d/%d", &commit, &prepare); err != nil || CommitPosition(commit, prepare) != p {
+		return 0, 0, fmt.Errorf("%w: %q isn't a commit position", InvalidPosition, p)
+	}
+
+	return commit, prepare, nil
+}
+
+// Checkpoint records the position as a checkpoint, to resume after the event.
+func (p Position) Checkpoint() Checkpoint {
+	return Checkpoint(p)
+}
+
+// PositionOf reads the position recorded in a checkpoint.
+func PositionOf(checkpoint Checkpoint) Position {
+	return Position(checkpoint)
+}
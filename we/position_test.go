@@ -0,0 +1,48 @@
+package we
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPositionOrdering(t *testing.T) {
+	assert.True(t, SequencePosition(9, 0).Before(SequencePosition(10, 0)))
+	assert.True(t, SequencePosition(10, 2).After(SequencePosition(10, 1)))
+	assert.True(t, CommitPosition(99, 98).Before(CommitPosition(100, 100)))
+	assert.True(t, NoPosition.Before(SequencePosition(0, 0)))
+	assert.Equal(t, 0, SequencePosition(1, 1).Compare(SequencePosition(1, 1)))
+}
+
+func TestPositionDecoding(t *testing.T) {
+	sequence, index, err := SequencePosition(42, 3).Sequence()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(42), sequence)
+	assert.Equal(t, uint16(3), index)
+
+	commit, prepare, err := CommitPosition(7, 5).Commit()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(7), commit)
+	assert.Equal(t, uint64(5), prepare)
+
+	_, _, err = CommitPosition(7, 5).Sequence()
+	assert.ErrorIs(t, err, InvalidPosition)
+
+	_, _, err = Position("42").Sequence()
+	assert.ErrorIs(t, err, InvalidPosition)
+
+	assert.Equal(t, SequencePosition(42, 3), PositionOf(SequencePosition(42, 3).Checkpoint()))
+}
+
+func TestPositionJSON(t *testing.T) {
+	event := RecordedEvent{EventID: "one", Position: SequencePosition(1, 0)}
+	data, err := json.Marshal(event)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"position":"00000000000000000001.00000"`)
+
+	data, err = json.Marshal(RecordedEvent{EventID: "two"})
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "position", "events without a position omit it")
+}
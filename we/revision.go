@@ -1,29 +1,146 @@
 package we
 
 import (
+	"encoding/binary"
+	"errors"
+	"fmt"
 	"math/rand"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/oklog/ulid/v2"
 )
 
+// Revision identifies the state of an aggregate after an event. Revisions are ULIDs, ordered by the time the event was
+// recorded and then by the store's sequence, so the revisions of an aggregate's events order lexically.
 type Revision string
 
 const InitialRevision = Revision("00000000000000000000000000")
 
+var InvalidRevision = errors.New("invalid-revision")
+
+// ParseRevision validates the revision, returning it in its canonical upper case form.
+func ParseRevision(revision string) (Revision, error) {
+	parsed, err := ulid.ParseStrict(revision)
+	if err != nil {
+		return "", fmt.Errorf("%w: %q: %v", InvalidRevision, revision, err)
+	}
+
+	return Revision(parsed.String()), nil
+}
+
+// SequencedRevision encodes a store sequence number, and the index of the event within what was recorded at that
+// sequence, in a revision recorded at the time.
+func SequencedRevision(t time.Time, sequence uint64, index uint16) (Revision, error) {
+	return sequencedRevision(ulid.Timestamp(t), sequence, index)
+}
+
+func sequencedRevision(ms uint64, sequence uint64, index uint16) (Revision, error) {
+	var r ulid.ULID
+	if err := r.SetTime(ms); err != nil {
+		return "", fmt.Errorf("%w: %v", InvalidRevision, err)
+	}
+
+	entropy := make([]byte, 10)
+	binary.BigEndian.PutUint64(entropy[:8], sequence)
+	binary.BigEndian.PutUint16(entropy[8:], index)
+	if err := r.SetEntropy(entropy); err != nil {
+		return "", fmt.Errorf("%w: %v", InvalidRevision, err)
+	}
+
+	return Revision(r.String()), nil
+}
+
+// Sequence decodes the sequence number and index encoded by SequencedRevision. Revisions made by a RevisionGenerator
+// don't encode a sequence and decode to meaningless values.
+func (revision Revision) Sequence() (uint64, uint16, error) {
+	parsed, err := ulid.ParseStrict(string(revision))
+	if err != nil {
+		return 0, 0, fmt.Errorf("%w: %q: %v", InvalidRevision, revision, err)
+	}
+
+	entropy := parsed.Entropy()
+	return binary.BigEndian.Uint64(entropy[:8]), binary.BigEndian.Uint16(entropy[8:]), nil
+}
+
+// Time returns the time the revision was recorded, to the millisecond.
+func (revision Revision) Time() (time.Time, error) {
+	parsed, err := ulid.ParseStrict(string(revision))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %q: %v", InvalidRevision, revision, err)
+	}
+
+	return ulid.Time(parsed.Time()).UTC(), nil
+}
+
+// Compare returns -1, 0 or 1 as the revision is before, the same as or after the other. The empty revision is before
+// every other revision.
+func (revision Revision) Compare(other Revision) int {
+	return strings.Compare(strings.ToUpper(string(revision)), strings.ToUpper(string(other)))
+}
+
+func (revision Revision) Before(other Revision) bool {
+	return revision.Compare(other) < 0
+}
+
+func (revision Revision) After(other Revision) bool {
+	return revision.Compare(other) > 0
+}
+
+// MarshalText fails for revisions that aren't valid, other than the empty revision.
+func (revision Revision) MarshalText() ([]byte, error) {
+	if revision == "" {
+		return nil, nil
+	}
+
+	if _, err := ParseRevision(string(revision)); err != nil {
+		return nil, err
+	}
+
+	return []byte(revision), nil
+}
+
+func (revision *Revision) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*revision = ""
+		return nil
+	}
+
+	parsed, err := ParseRevision(string(text))
+	if err != nil {
+		return err
+	}
+
+	*revision = parsed
+	return nil
+}
+
 type RevisionGenerator struct {
 	lk      sync.Mutex
+	clock   Clock
 	entropy *ulid.MonotonicEntropy
 }
 
-func NewRevisionGenerator() *RevisionGenerator {
-	t := time.Now()
-	entropy := ulid.Monotonic(rand.New(rand.NewSource(t.UnixNano())), 0)
+type RevisionGeneratorOption func(*RevisionGenerator)
+
+// WithRevisionClock sets the clock that seeds the generator's entropy, so a fake clock makes its revisions repeatable.
+func WithRevisionClock(clock Clock) RevisionGeneratorOption {
+	return func(g *RevisionGenerator) {
+		g.clock = clock
+	}
+}
+
+func NewRevisionGenerator(options ...RevisionGeneratorOption) *RevisionGenerator {
+	g := &RevisionGenerator{clock: SystemClock}
 
-	return &RevisionGenerator{
-		entropy: entropy,
+	for _, option := range options {
+		option(g)
 	}
+
+	g.entropy = ulid.Monotonic(rand.New(rand.NewSource(g.clock.Now().UnixNano())), 0)
+
+	return g
 }
 
 func (g *RevisionGenerator) NewRevision(t time.Time) Revision {
@@ -33,9 +150,45 @@ func (g *RevisionGenerator) NewRevision(t time.Time) Revision {
 	return Revision(ulid.MustNew(ulid.Timestamp(t), g.entropy).String())
 }
 
+// NewRevisionAfter generates a revision at the time that is after the previous revision, even if the clock has gone
+// backwards or another generator made the previous revision in the same millisecond.
+func (g *RevisionGenerator) NewRevisionAfter(t time.Time, previous Revision) Revision {
+	if recorded, err := previous.Time(); err == nil && recorded.After(t) {
+		t = recorded
+	}
+
+	revision := g.NewRevision(t)
+	if revision.After(previous) {
+		return revision
+	}
+
+	// the previous revision was made in the same millisecond with larger entropy, so count on from it
+	parsed, err := ulid.ParseStrict(string(previous))
+	if err != nil {
+		return revision
+	}
+
+	entropy := parsed.Entropy()
+	for i := len(entropy) - 1; i >= 0; i-- {
+		entropy[i]++
+		if entropy[i] != 0 {
+			_ = parsed.SetEntropy(entropy)
+			return Revision(parsed.String())
+		}
+	}
+
+	// the previous revision's millisecond is exhausted
+	return g.NewRevision(ulid.Time(parsed.Time() + 1))
+}
+
+// Timestamp returns the time the revision was recorded, or an empty timestamp if the revision isn't valid.
 func (revision Revision) Timestamp() Timestamp {
-	v := ulid.MustParse(string(revision))
-	return Timestamp(ulid.Time(v.Time()).UTC().Format(RFC3339Milli))
+	recorded, err := revision.Time()
+	if err != nil {
+		return ""
+	}
+
+	return Timestamp(recorded.Format(RFC3339Milli))
 }
 
 func (revision Revision) String() string {
@@ -1,9 +1,13 @@
 package we
 
 import (
+	"encoding/json"
+	"strings"
 	"testing"
+	"testing/quick"
 	"time"
 
+	"github.com/oklog/ulid/v2"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -18,3 +22,124 @@ func TestCovertsToISODatetime(t *testing.T) {
 	timestamp = string(Revision.Timestamp())
 	assert.Equal(t, now.UTC().Format(RFC3339Milli), timestamp)
 }
+
+// revisionAt makes a sequenced revision from generated values, limiting the time to what a revision can hold.
+func revisionAt(ms uint64, sequence uint64, index uint16) Revision {
+	revision, err := SequencedRevision(time.UnixMilli(int64(ms%ulid.MaxTime())), sequence, index)
+	if err != nil {
+		panic(err)
+	}
+
+	return revision
+}
+
+func TestParsesRevisions(t *testing.T) {
+	property := func(ms uint64, sequence uint64, index uint16) bool {
+		revision := revisionAt(ms, sequence, index)
+
+		parsed, err := ParseRevision(strings.ToLower(revision.String()))
+		return err == nil && parsed == revision
+	}
+	assert.Nil(t, quick.Check(property, nil))
+
+	for _, invalid := range []string{"", "not-a-revision", "0000000000000000000000000", "8ZZZZZZZZZZZZZZZZZZZZZZZZZ"} {
+		_, err := ParseRevision(invalid)
+		assert.ErrorIs(t, err, InvalidRevision, invalid)
+	}
+}
+
+func TestEncodesSequences(t *testing.T) {
+	property := func(ms uint64, sequence uint64, index uint16) bool {
+		revision := revisionAt(ms, sequence, index)
+
+		decodedSequence, decodedIndex, err := revision.Sequence()
+		if err != nil || decodedSequence != sequence || decodedIndex != index {
+			return false
+		}
+
+		recorded, err := revision.Time()
+		return err == nil && uint64(recorded.UnixMilli()) == ms%ulid.MaxTime()
+	}
+	assert.Nil(t, quick.Check(property, nil))
+
+	_, _, err := Revision("not-a-revision").Sequence()
+	assert.ErrorIs(t, err, InvalidRevision)
+	assert.Equal(t, Timestamp(""), Revision("not-a-revision").Timestamp())
+}
+
+func TestOrdersRevisionsByTimeThenSequence(t *testing.T) {
+	property := func(ms uint64, sequence uint64, index uint16, laterMs uint64, laterSequence uint64, laterIndex uint16) bool {
+		first := revisionAt(ms, sequence, index)
+		second := revisionAt(laterMs, laterSequence, laterIndex)
+
+		ms, laterMs = ms%ulid.MaxTime(), laterMs%ulid.MaxTime()
+		var expected int
+		switch {
+		case ms != laterMs:
+			expected = compare(ms, laterMs)
+		case sequence != laterSequence:
+			expected = compare(sequence, laterSequence)
+		default:
+			expected = compare(index, laterIndex)
+		}
+
+		return first.Compare(second) == expected &&
+			second.Compare(first) == -expected &&
+			first.Before(second) == (expected < 0) &&
+			first.After(second) == (expected > 0)
+	}
+	assert.Nil(t, quick.Check(property, nil))
+
+	assert.True(t, Revision("").Before(InitialRevision))
+}
+
+func compare[T uint64 | uint16](a T, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func TestGeneratesRevisionsAfterThePrevious(t *testing.T) {
+	generator := NewRevisionGenerator()
+
+	property := func(ms uint64, sequence uint64, index uint16, skew uint16) bool {
+		previous := revisionAt(ms%(ulid.MaxTime()-uint64(skew)), sequence, index)
+		recorded, _ := previous.Time()
+
+		return generator.NewRevisionAfter(recorded.Add(-time.Duration(skew)*time.Millisecond), previous).After(previous)
+	}
+	assert.Nil(t, quick.Check(property, nil))
+}
+
+func TestMarshalsRevisions(t *testing.T) {
+	type document struct {
+		Revision Revision `json:"revision"`
+	}
+
+	property := func(ms uint64, sequence uint64, index uint16) bool {
+		revision := revisionAt(ms, sequence, index)
+
+		encoded, err := json.Marshal(document{Revision: revision})
+		if err != nil {
+			return false
+		}
+
+		var decoded document
+		return json.Unmarshal(encoded, &decoded) == nil && decoded.Revision == revision
+	}
+	assert.Nil(t, quick.Check(property, nil))
+
+	var decoded document
+	assert.Nil(t, json.Unmarshal([]byte(`{"revision":""}`), &decoded))
+	assert.Equal(t, Revision(""), decoded.Revision)
+
+	assert.ErrorIs(t, json.Unmarshal([]byte(`{"revision":"not-a-revision"}`), &decoded), InvalidRevision)
+
+	_, err := json.Marshal(document{Revision: "not-a-revision"})
+	assert.ErrorIs(t, err, InvalidRevision)
+}
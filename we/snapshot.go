@@ -0,0 +1,23 @@
+package we
+
+import (
+	"context"
+	"errors"
+)
+
+// Snapshot records the state of an aggregate at a revision, allowing it to be rebuilt without replaying every event.
+type Snapshot struct {
+	AggregateId AggregateId `json:"aggregate"`
+	Revision    Revision    `json:"revision"`
+	Timestamp   Timestamp   `json:"timestamp"`
+	Data        Data        `json:"data"`
+}
+
+// SnapshotStore persists the latest snapshot of each aggregate. LoadSnapshot returns SnapshotNotFound when there
+// isn't one.
+type SnapshotStore interface {
+	LoadSnapshot(ctx context.Context, id AggregateId) (Snapshot, error)
+	SaveSnapshot(ctx context.Context, snapshot Snapshot) error
+}
+
+var SnapshotNotFound = errors.New("snapshot-not-found")
@@ -0,0 +1,60 @@
+package we
+
+import (
+	"context"
+	"errors"
+)
+
+// Tenant identifies an isolated set of aggregates within a shared store.
+type Tenant string
+
+// NoTenant is used by stores that are not partitioned by tenant.
+const NoTenant = Tenant("")
+
+func (t Tenant) String() string {
+	return string(t)
+}
+
+var MissingTenant = errors.New("missing-tenant")
+
+// TenantResolver determines the tenant for an operation from its context.
+type TenantResolver interface {
+	Tenant(ctx context.Context) (Tenant, error)
+}
+
+type TenantResolverFunc func(ctx context.Context) (Tenant, error)
+
+func (f TenantResolverFunc) Tenant(ctx context.Context) (Tenant, error) {
+	return f(ctx)
+}
+
+type tenantKey struct{}
+
+func WithTenant(ctx context.Context, tenant Tenant) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+func TenantFrom(ctx context.Context) (Tenant, bool) {
+	tenant, ok := ctx.Value(tenantKey{}).(Tenant)
+	return tenant, ok && tenant != NoTenant
+}
+
+// ContextTenantResolver resolves the tenant added to the context with WithTenant, returning MissingTenant if there
+// isn't one.
+func ContextTenantResolver() TenantResolver {
+	return TenantResolverFunc(func(ctx context.Context) (Tenant, error) {
+		tenant, ok := TenantFrom(ctx)
+		if !ok {
+			return NoTenant, MissingTenant
+		}
+
+		return tenant, nil
+	})
+}
+
+// StaticTenantResolver resolves every operation to the same tenant.
+func StaticTenantResolver(tenant Tenant) TenantResolver {
+	return TenantResolverFunc(func(ctx context.Context) (Tenant, error) {
+		return tenant, nil
+	})
+}
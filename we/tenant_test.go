@@ -0,0 +1,22 @@
+package we
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContextTenantResolver(t *testing.T) {
+	resolver := ContextTenantResolver()
+
+	tenant, err := resolver.Tenant(WithTenant(context.Background(), "acme"))
+	assert.Nil(t, err)
+	assert.Equal(t, Tenant("acme"), tenant)
+
+	_, err = resolver.Tenant(context.Background())
+	assert.Equal(t, MissingTenant, err)
+
+	_, err = resolver.Tenant(WithTenant(context.Background(), NoTenant))
+	assert.Equal(t, MissingTenant, err)
+}